@@ -0,0 +1,122 @@
+// Command replay feeds the journal of the service into a fresh matching engine
+// and compares the events it produces with those the service recorded in its
+// event log, to reproduce an incident exactly.
+//
+//	replay -journal /data [-events /data/events.log] [-snapshot] [-out replayed.log]
+//
+// The engine starts empty, so the journal must go back to its first entry,
+// unless -snapshot starts it from the latest snapshot in the journal directory.
+// Only the commands covered by both the replay and the event log are compared.
+// It exits with status 1 when the events differ.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"order-matching/services"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	journalDir := flag.String("journal", "", "directory holding the journal")
+	eventsPath := flag.String("events", "", "recorded event log (default events.log in the journal directory)")
+	fromSnapshot := flag.Bool("snapshot", false, "start from the latest snapshot instead of an empty engine")
+	outPath := flag.String("out", "", "write the replayed events to this file")
+	flag.Parse()
+
+	if *journalDir == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *eventsPath == "" {
+		*eventsPath = filepath.Join(*journalDir, "events.log")
+	}
+
+	entries, err := services.ReadJournal(*journalDir)
+	if err != nil {
+		fail(err)
+	}
+
+	var snapshot *services.Snapshot
+	var from uint64 = 1
+	if *fromSnapshot {
+		snapshot, err = services.LoadSnapshot(*journalDir)
+		if err != nil {
+			fail(err)
+		}
+		if snapshot != nil {
+			from = snapshot.Sequence + 1
+		}
+	}
+
+	replayed, err := services.Replay(snapshot, entries)
+	if err != nil {
+		fail(err)
+	}
+	fmt.Printf("Replayed %d journal entries into %d events\n", len(entries), len(replayed))
+
+	if *outPath != "" {
+		if err := writeEvents(*outPath, replayed); err != nil {
+			fail(err)
+		}
+	}
+
+	recorded, err := services.ReadEventLog(*eventsPath)
+	if err != nil {
+		fail(err)
+	}
+	if len(recorded) == 0 {
+		fmt.Println("The event log is empty, there is nothing to compare")
+		return
+	}
+
+	// the log may have been turned on after the journal, and may not have
+	// caught up with it yet
+	from = max(from, recorded[0].Command)
+	to := recorded[len(recorded)-1].Command
+	recorded = commandRange(recorded, from, to)
+	compared := commandRange(replayed, from, to)
+	fmt.Printf("Comparing the events of journal entries %d to %d\n", from, to)
+
+	if diff := services.DiffEvents(recorded, compared); diff != "" {
+		fmt.Println(diff)
+		os.Exit(1)
+	}
+	fmt.Printf("All %d events match\n", len(recorded))
+}
+
+// commandRange returns the events of the commands from to to, inclusive.
+func commandRange(events []services.Event, from uint64, to uint64) []services.Event {
+	selected := []services.Event{}
+	for _, event := range events {
+		if event.Command >= from && event.Command <= to {
+			selected = append(selected, event)
+		}
+	}
+
+	return selected
+}
+
+func writeEvents(path string, events []services.Event) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(file)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	return file.Close()
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err.Error())
+	os.Exit(2)
+}
@@ -0,0 +1,395 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"order-matching/models"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the settings of the service. Every setting can be overridden
+// with an environment variable and falls back to a sensible default.
+type Config struct {
+	// SessionClose is the time of day, in UTC, at which DAY orders expire
+	// (SESSION_CLOSE, formatted as HH:MM).
+	SessionClose time.Duration
+	// CommandQueueSize is how many commands may wait for the sequencer
+	// (COMMAND_QUEUE_SIZE). Requests beyond it are refused with 503.
+	CommandQueueSize int
+	// ExpirySweepInterval is how often expired GTD and DAY orders are removed
+	// from the book (EXPIRY_SWEEP_INTERVAL, a Go duration such as 1s).
+	ExpirySweepInterval time.Duration
+	// JournalDir is the directory holding the journal every accepted command
+	// is written to before it is applied, and the snapshots of the order books
+	// (JOURNAL_DIR). Both are read back on startup. Nothing is persisted when
+	// it is empty.
+	JournalDir string
+	// JournalSyncInterval is how often the journal is synced to disk
+	// (JOURNAL_SYNC_INTERVAL). With 0 every command is synced before it is
+	// applied; a longer interval trades the last few commands before a crash
+	// for throughput.
+	JournalSyncInterval time.Duration
+	// SnapshotInterval is how often a snapshot is taken (SNAPSHOT_INTERVAL),
+	// and SnapshotEvery after how many journaled commands (SNAPSHOT_EVERY),
+	// whichever comes first. 0 turns either trigger off.
+	SnapshotInterval time.Duration
+	SnapshotEvery uint64
+	// EventLog records the events of every command in events.log in the
+	// journal directory, for the replay tool to compare against (EVENT_LOG,
+	// true or false). It needs JournalDir.
+	EventLog bool
+	// Repository is where instruments, orders and trades are written through
+	// to for reporting (REPOSITORY): "none" or "sqlite".
+	Repository string
+	// SQLitePath is the database file of the sqlite repository (SQLITE_PATH).
+	SQLitePath string
+	// RepositoryQueueSize is how many writes may wait for the repository
+	// (REPOSITORY_QUEUE_SIZE). Writes beyond it are reported and dropped.
+	RepositoryQueueSize int
+	// DefaultMarket is the symbol of the market the routes of the service
+	// from before it listed instruments, such as /api/orders, are served for
+	// (DEFAULT_MARKET). They are not served when it is empty.
+	DefaultMarket string
+	// APIKeys holds the API key of every account, by key (API_KEYS,
+	// comma-separated key:secret:account entries, with :admin appended for
+	// an admin key). Requests to the private endpoints are signed with the
+	// secret of a key.
+	APIKeys map[string]APIKey
+	// AuthWindow is how far the timestamp of a signed request may be from the
+	// time of the service (AUTH_WINDOW). The nonces of requests are
+	// remembered for as long as their timestamp is accepted.
+	AuthWindow time.Duration
+	// RateLimits holds the token bucket of every class of request of every
+	// account tier (RATE_LIMITS, comma-separated tier:class:rate:burst
+	// entries, with the rate in requests per second), on top of the limits
+	// of the default tier. A tier that leaves out a class has the limit of
+	// the default tier for it.
+	RateLimits map[string]map[RateLimitClass]RateLimit
+	// AccountTiers maps accounts to their tier (ACCOUNT_TIERS,
+	// comma-separated account:tier pairs). Other accounts are in the default
+	// tier.
+	AccountTiers map[string]string
+	// TrustedProxies are the addresses and CIDR ranges of the proxies whose
+	// X-Forwarded-For and X-Real-IP headers are believed when rate limiting
+	// per client IP (TRUSTED_PROXIES, comma-separated). With none, the
+	// client IP is the address the request came from.
+	TrustedProxies []string
+	// The pre-trade risk limits every order and amendment is checked against;
+	// a limit left at 0 is not checked. MaxOrderQuantity (MAX_ORDER_QUANTITY)
+	// bounds the amount of an order and MaxOrderNotional (MAX_ORDER_NOTIONAL)
+	// its amount times its price, in the quote asset.
+	MaxOrderQuantity models.Decimal
+	MaxOrderNotional models.Decimal
+	// FatFinger is how far an order may trade through the best opposite
+	// price, as a fraction of it (FAT_FINGER, e.g. 0.05 for 5%).
+	FatFinger models.Decimal
+	// PriceCollar is how far the price of an order may be from the last
+	// trade price, or the mid price before the first trade, as a fraction of
+	// it (PRICE_COLLAR).
+	PriceCollar models.Decimal
+	// MaxOpenOrders is how many open orders an account may have across the
+	// markets (MAX_OPEN_ORDERS).
+	MaxOpenOrders int
+	// MaxPosition is the largest net amount an account may have bought or
+	// sold in a market, counting its open orders as filled (MAX_POSITION).
+	MaxPosition models.Decimal
+	// CircuitBreakerBand halts a market when its last trade is further than
+	// this fraction of the price from any trade of the last
+	// CircuitBreakerWindow (CIRCUIT_BREAKER_BAND and CIRCUIT_BREAKER_WINDOW).
+	// A band of 0 turns the circuit breaker off.
+	CircuitBreakerBand models.Decimal
+	CircuitBreakerWindow time.Duration
+	// CircuitBreakerCooldown is how long a circuit breaker halt lasts
+	// (CIRCUIT_BREAKER_COOLDOWN); with 0 it lasts until an admin resumes the
+	// market.
+	CircuitBreakerCooldown time.Duration
+	// AuctionDuration is how long a halted market queues orders once the
+	// halt is over before matching restarts (AUCTION_DURATION); with 0 it
+	// reopens directly.
+	AuctionDuration time.Duration
+	// HaltMode is what a halted market does with new orders (HALT_MODE):
+	// "reject" them or "queue" them until it reopens.
+	HaltMode string
+}
+
+// APIKey is the secret of an API key and the account it acts for. An admin
+// key may also act on the orders and balances of every other account and
+// list instruments.
+type APIKey struct {
+	Secret string
+	AccountID string
+	Admin bool
+}
+
+// RateLimitClass is a class of requests sharing a rate limit.
+type RateLimitClass string
+
+const RateLimitPlace RateLimitClass = "place" // placing and amending orders, and every other change
+const RateLimitCancel RateLimitClass = "cancel"
+const RateLimitRead RateLimitClass = "read"
+
+// DefaultTier is the tier of accounts without one.
+const DefaultTier = "default"
+
+// ClientIPTier is the tier every client IP is limited by, before the tier of
+// its account. Unless RATE_LIMITS sets its limits, they are those of the
+// default tier.
+const ClientIPTier = "ip"
+
+// RateLimit is a token bucket: it holds up to Burst requests and refills at
+// Rate requests per second.
+type RateLimit struct {
+	Rate float64
+	Burst int
+}
+
+func Default() Config {
+	return Config{
+		SessionClose:        0,
+		CommandQueueSize:    1024,
+		ExpirySweepInterval: time.Second,
+		JournalDir:          "",
+		JournalSyncInterval: 0,
+		SnapshotInterval:    5 * time.Minute,
+		SnapshotEvery:       10_000,
+		EventLog:            false,
+		Repository:          "none",
+		SQLitePath:          "orders.db",
+		RepositoryQueueSize: 100_000,
+		DefaultMarket:       "",
+		APIKeys:             map[string]APIKey{},
+		AuthWindow:          30 * time.Second,
+		RateLimits: map[string]map[RateLimitClass]RateLimit{
+			DefaultTier: {
+				RateLimitPlace:  {Rate: 10, Burst: 20},
+				RateLimitCancel: {Rate: 20, Burst: 40},
+				RateLimitRead:   {Rate: 50, Burst: 100},
+			},
+		},
+		AccountTiers:           map[string]string{},
+		TrustedProxies:         nil,
+		MaxOrderQuantity:       0,
+		MaxOrderNotional:       0,
+		FatFinger:              0,
+		PriceCollar:            0,
+		MaxOpenOrders:          0,
+		MaxPosition:            0,
+		CircuitBreakerBand:     0,
+		CircuitBreakerWindow:   time.Minute,
+		CircuitBreakerCooldown: 5 * time.Minute,
+		AuctionDuration:        0,
+		HaltMode:               "reject",
+	}
+}
+
+// Load reads the configuration from the environment.
+func Load() (Config, error) {
+	cfg := Default()
+
+	if value, exists := os.LookupEnv("SESSION_CLOSE"); exists {
+		sessionClose, err := time.Parse("15:04", value)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid SESSION_CLOSE %q: %w", value, err)
+		}
+		cfg.SessionClose = time.Duration(sessionClose.Hour())*time.Hour + time.Duration(sessionClose.Minute())*time.Minute
+	}
+
+	if value, exists := os.LookupEnv("COMMAND_QUEUE_SIZE"); exists {
+		size, err := strconv.Atoi(value)
+		if err != nil || size <= 0 {
+			return cfg, fmt.Errorf("invalid COMMAND_QUEUE_SIZE %q", value)
+		}
+		cfg.CommandQueueSize = size
+	}
+
+	if value, exists := os.LookupEnv("EXPIRY_SWEEP_INTERVAL"); exists {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			return cfg, fmt.Errorf("invalid EXPIRY_SWEEP_INTERVAL %q", value)
+		}
+		cfg.ExpirySweepInterval = interval
+	}
+
+	if value, exists := os.LookupEnv("JOURNAL_DIR"); exists {
+		cfg.JournalDir = value
+	}
+
+	if value, exists := os.LookupEnv("JOURNAL_SYNC_INTERVAL"); exists {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval < 0 {
+			return cfg, fmt.Errorf("invalid JOURNAL_SYNC_INTERVAL %q", value)
+		}
+		cfg.JournalSyncInterval = interval
+	}
+
+	if value, exists := os.LookupEnv("SNAPSHOT_INTERVAL"); exists {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval < 0 {
+			return cfg, fmt.Errorf("invalid SNAPSHOT_INTERVAL %q", value)
+		}
+		cfg.SnapshotInterval = interval
+	}
+
+	if value, exists := os.LookupEnv("SNAPSHOT_EVERY"); exists {
+		every, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid SNAPSHOT_EVERY %q", value)
+		}
+		cfg.SnapshotEvery = every
+	}
+
+	if value, exists := os.LookupEnv("EVENT_LOG"); exists {
+		eventLog, err := strconv.ParseBool(value)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid EVENT_LOG %q", value)
+		}
+		cfg.EventLog = eventLog
+	}
+	if cfg.EventLog && cfg.JournalDir == "" {
+		return cfg, fmt.Errorf("EVENT_LOG needs JOURNAL_DIR")
+	}
+
+	if value, exists := os.LookupEnv("REPOSITORY"); exists {
+		if value != "none" && value != "sqlite" {
+			return cfg, fmt.Errorf("invalid REPOSITORY %q", value)
+		}
+		cfg.Repository = value
+	}
+
+	if value, exists := os.LookupEnv("SQLITE_PATH"); exists {
+		cfg.SQLitePath = value
+	}
+
+	if value, exists := os.LookupEnv("REPOSITORY_QUEUE_SIZE"); exists {
+		size, err := strconv.Atoi(value)
+		if err != nil || size <= 0 {
+			return cfg, fmt.Errorf("invalid REPOSITORY_QUEUE_SIZE %q", value)
+		}
+		cfg.RepositoryQueueSize = size
+	}
+
+	if value, exists := os.LookupEnv("DEFAULT_MARKET"); exists {
+		cfg.DefaultMarket = value
+	}
+
+	if value, exists := os.LookupEnv("API_KEYS"); exists {
+		for index, entry := range strings.Split(value, ",") {
+			fields := strings.Split(strings.TrimSpace(entry), ":")
+			// the entries are not echoed, as they hold secrets
+			if len(fields) < 3 || len(fields) > 4 || fields[0] == "" || fields[1] == "" || fields[2] == "" || (len(fields) == 4 && fields[3] != "admin") {
+				return cfg, fmt.Errorf("invalid API_KEYS entry %d", index+1)
+			}
+			if _, exists := cfg.APIKeys[fields[0]]; exists {
+				return cfg, fmt.Errorf("API_KEYS entry %d repeats a key", index+1)
+			}
+			cfg.APIKeys[fields[0]] = APIKey{Secret: fields[1], AccountID: fields[2], Admin: len(fields) == 4}
+		}
+	}
+
+	if value, exists := os.LookupEnv("AUTH_WINDOW"); exists {
+		window, err := time.ParseDuration(value)
+		if err != nil || window <= 0 {
+			return cfg, fmt.Errorf("invalid AUTH_WINDOW %q", value)
+		}
+		cfg.AuthWindow = window
+	}
+
+	if value, exists := os.LookupEnv("RATE_LIMITS"); exists {
+		for _, entry := range strings.Split(value, ",") {
+			fields := strings.Split(strings.TrimSpace(entry), ":")
+			if len(fields) != 4 || fields[0] == "" {
+				return cfg, fmt.Errorf("invalid RATE_LIMITS entry %q", entry)
+			}
+			class := RateLimitClass(fields[1])
+			if class != RateLimitPlace && class != RateLimitCancel && class != RateLimitRead {
+				return cfg, fmt.Errorf("invalid RATE_LIMITS class %q", fields[1])
+			}
+			rate, err := strconv.ParseFloat(fields[2], 64)
+			if err != nil || rate <= 0 {
+				return cfg, fmt.Errorf("invalid RATE_LIMITS rate %q", fields[2])
+			}
+			burst, err := strconv.Atoi(fields[3])
+			if err != nil || burst <= 0 {
+				return cfg, fmt.Errorf("invalid RATE_LIMITS burst %q", fields[3])
+			}
+			if cfg.RateLimits[fields[0]] == nil {
+				cfg.RateLimits[fields[0]] = make(map[RateLimitClass]RateLimit)
+			}
+			cfg.RateLimits[fields[0]][class] = RateLimit{Rate: rate, Burst: burst}
+		}
+	}
+
+	if value, exists := os.LookupEnv("ACCOUNT_TIERS"); exists {
+		for _, pair := range strings.Split(value, ",") {
+			accountID, tier, found := strings.Cut(strings.TrimSpace(pair), ":")
+			if !found || accountID == "" {
+				return cfg, fmt.Errorf("invalid ACCOUNT_TIERS entry %q", pair)
+			}
+			if _, exists := cfg.RateLimits[tier]; !exists {
+				return cfg, fmt.Errorf("ACCOUNT_TIERS tier %q has no RATE_LIMITS", tier)
+			}
+			cfg.AccountTiers[accountID] = tier
+		}
+	}
+
+	if value, exists := os.LookupEnv("TRUSTED_PROXIES"); exists && value != "" {
+		for _, proxy := range strings.Split(value, ",") {
+			proxy = strings.TrimSpace(proxy)
+			if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+				return cfg, fmt.Errorf("invalid TRUSTED_PROXIES entry %q", proxy)
+			}
+			cfg.TrustedProxies = append(cfg.TrustedProxies, proxy)
+		}
+	}
+
+	for name, limit := range map[string]*models.Decimal{
+		"MAX_ORDER_QUANTITY": &cfg.MaxOrderQuantity,
+		"MAX_ORDER_NOTIONAL": &cfg.MaxOrderNotional,
+		"FAT_FINGER": &cfg.FatFinger,
+		"PRICE_COLLAR": &cfg.PriceCollar,
+		"MAX_POSITION": &cfg.MaxPosition,
+		"CIRCUIT_BREAKER_BAND": &cfg.CircuitBreakerBand,
+	} {
+		if value, exists := os.LookupEnv(name); exists {
+			decimal, err := models.ParseDecimal(value)
+			if err != nil || decimal < 0 {
+				return cfg, fmt.Errorf("invalid %s %q", name, value)
+			}
+			*limit = decimal
+		}
+	}
+
+	if value, exists := os.LookupEnv("MAX_OPEN_ORDERS"); exists {
+		maxOpenOrders, err := strconv.Atoi(value)
+		if err != nil || maxOpenOrders < 0 {
+			return cfg, fmt.Errorf("invalid MAX_OPEN_ORDERS %q", value)
+		}
+		cfg.MaxOpenOrders = maxOpenOrders
+	}
+
+	for name, duration := range map[string]*time.Duration{
+		"CIRCUIT_BREAKER_WINDOW": &cfg.CircuitBreakerWindow,
+		"CIRCUIT_BREAKER_COOLDOWN": &cfg.CircuitBreakerCooldown,
+		"AUCTION_DURATION": &cfg.AuctionDuration,
+	} {
+		if value, exists := os.LookupEnv(name); exists {
+			parsed, err := time.ParseDuration(value)
+			if err != nil || parsed < 0 {
+				return cfg, fmt.Errorf("invalid %s %q", name, value)
+			}
+			*duration = parsed
+		}
+	}
+
+	if value, exists := os.LookupEnv("HALT_MODE"); exists {
+		if value != "reject" && value != "queue" {
+			return cfg, fmt.Errorf("invalid HALT_MODE %q", value)
+		}
+		cfg.HaltMode = value
+	}
+
+	return cfg, nil
+}
@@ -15,69 +15,142 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/orderbook": {
+        "/accounts/{account}/balances": {
             "get": {
-                "description": "Returns a list of buy and sell orders with their price and liquidity.",
+                "security": [
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Returns the balance of every asset of an account, ordered by asset: the available funds, and the funds held by its open orders. An unknown account has no balances.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Orders"
+                    "Accounts"
                 ],
-                "summary": "Get order book",
+                "summary": "Get the balances of an account",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Number of orders to retrieve (default is 10)",
-                        "name": "limit",
-                        "in": "query"
+                        "type": "string",
+                        "description": "Account ID",
+                        "name": "account",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successfully retrieved order book",
+                        "description": "Successfully retrieved the balances",
                         "schema": {
-                            "$ref": "#/definitions/handlers.OrderBookResponse"
+                            "$ref": "#/definitions/handlers.BalancesResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid signature",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Another account, without an admin key",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
                         }
                     }
                 }
             }
         },
-        "/orders": {
-            "get": {
-                "description": "Returns a paginated list of all orders placed in the order book.",
+        "/accounts/{account}/deposits": {
+            "post": {
+                "security": [
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Adds funds of an asset to the available balance of an account, opening the account if needed. Needs an admin key: a deposit records funds the exchange received from outside, which an account cannot vouch for itself. Returns the new balance of the asset.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Orders"
+                    "Accounts"
                 ],
-                "summary": "Get list of orders",
+                "summary": "Deposit funds",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Page number (default is 1)",
-                        "name": "page",
-                        "in": "query"
+                        "type": "string",
+                        "description": "Account ID",
+                        "name": "account",
+                        "in": "path",
+                        "required": true
                     },
                     {
-                        "type": "integer",
-                        "description": "Number of orders per page (default is 10)",
-                        "name": "page_size",
-                        "in": "query"
+                        "description": "Asset and amount",
+                        "name": "transfer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Transfer"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successfully retrieved list of orders",
+                        "description": "Funds successfully deposited",
                         "schema": {
-                            "$ref": "#/definitions/handlers.Response"
+                            "$ref": "#/definitions/handlers.BalanceResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid signature",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin key",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid request payload",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BalanceResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Too many commands are waiting",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BalanceResponse"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/accounts/{account}/withdrawals": {
             "post": {
-                "description": "Places a buy or sell order in the order book and returns matched orders if available.",
+                "security": [
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Takes funds of an asset out of the available balance of an account. Funds held by open orders cannot be withdrawn. Returns the new balance of the asset.",
                 "consumes": [
                     "application/json"
                 ],
@@ -85,117 +158,1506 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Orders"
+                    "Accounts"
                 ],
-                "summary": "Create a new order",
+                "summary": "Withdraw funds",
                 "parameters": [
                     {
-                        "description": "Order details",
-                        "name": "order",
+                        "type": "string",
+                        "description": "Account ID",
+                        "name": "account",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Asset and amount",
+                        "name": "transfer",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Order"
+                            "$ref": "#/definitions/models.Transfer"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Order successfully placed",
+                        "description": "Funds successfully withdrawn",
                         "schema": {
-                            "$ref": "#/definitions/handlers.Response"
+                            "$ref": "#/definitions/handlers.BalanceResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid signature",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Another account, without an admin key",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
                         }
                     },
                     "409": {
-                        "description": "Duplicate order detected",
+                        "description": "Insufficient funds",
                         "schema": {
-                            "$ref": "#/definitions/handlers.Response"
+                            "$ref": "#/definitions/handlers.BalanceResponse"
                         }
                     },
                     "422": {
                         "description": "Invalid request payload",
                         "schema": {
-                            "$ref": "#/definitions/handlers.Response"
+                            "$ref": "#/definitions/handlers.BalanceResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Too many commands are waiting",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BalanceResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "handlers.OrderBookResponse": {
-            "type": "object",
-            "properties": {
-                "data": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.OrderBookEntry"
+        },
+        "/executions": {
+            "get": {
+                "security": [
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Streams the execution reports of the orders of the authenticated account as server-sent events: NEW, TRIGGERED, FILL, AMENDED, CANCELED, EXPIRED and REJECTED. Every order of the account is reported, whichever of its API keys placed it. Every event is named after the report type and carries the account's next sequence number as its id. A client that reconnects with the last id it saw, in the Last-Event-ID header or the after parameter, first receives every report it missed; without one it receives new reports only. A heartbeat event without an id is sent every 30 seconds. A client that falls too far behind is disconnected and can resume.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Executions"
+                ],
+                "summary": "Stream execution reports",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Resume after the report with this sequence number",
+                        "name": "after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Resume after the report with this sequence number",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Stream of execution reports",
+                        "schema": {
+                            "$ref": "#/definitions/services.ExecutionReport"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid signature",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "410": {
+                        "description": "The reports to resume from are no longer kept",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid sequence number",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
                     }
                 }
             }
         },
-        "handlers.Response": {
-            "type": "object",
-            "properties": {
-                "data": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.Order"
+        "/markets": {
+            "get": {
+                "description": "Returns every listed instrument, ordered by symbol.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Markets"
+                ],
+                "summary": "Get list of instruments",
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved list of instruments",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.InstrumentsResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Lists a new instrument and opens an empty order book for it under /markets/{symbol}. Prices and amounts are fixed-point decimals written as strings; the tick size and lot size decide how many decimal places the prices and amounts of its orders have.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Markets"
+                ],
+                "summary": "Create an instrument",
+                "parameters": [
+                    {
+                        "description": "Instrument details",
+                        "name": "instrument",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Instrument"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Instrument successfully created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.InstrumentResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid signature",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin key",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Instrument already exists",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.InstrumentResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid request payload",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.InstrumentResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Too many commands are waiting",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.InstrumentResponse"
+                        }
                     }
-                },
-                "message": {
-                    "type": "string"
                 }
             }
         },
-        "models.Order": {
-            "type": "object",
-            "required": [
-                "action",
-                "amount",
-                "price",
-                "uuid"
-            ],
-            "properties": {
-                "action": {
-                    "enum": [
-                        "BUY",
-                        "SELL"
-                    ],
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/models.OrderType"
+        "/markets/{symbol}": {
+            "get": {
+                "description": "Returns a listed instrument with its trading rules and status.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Markets"
+                ],
+                "summary": "Get an instrument",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Instrument symbol",
+                        "name": "symbol",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved the instrument",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.InstrumentResponse"
                         }
-                    ]
-                },
-                "amount": {
-                    "type": "number",
-                    "example": 10
-                },
-                "price": {
-                    "type": "number",
-                    "example": 100
-                },
-                "uuid": {
-                    "type": "string",
-                    "example": "550e8400-e29b-41d4-a716-646655440000"
+                    },
+                    "404": {
+                        "description": "Market not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.InstrumentResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    }
                 }
             }
         },
-        "models.OrderBookEntry": {
-            "type": "object",
-            "properties": {
-                "liquidity": {
-                    "type": "number"
+        "/markets/{symbol}/close": {
+            "post": {
+                "security": [
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Refuses every new order until the market is resumed. Resting orders stay in the book and can be canceled.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Markets"
+                ],
+                "summary": "Close a market",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Instrument symbol",
+                        "name": "symbol",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Market closed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MarketStateResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid signature",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin key",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Market not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MarketStateResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Too many commands are waiting",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MarketStateResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/markets/{symbol}/feed": {
+            "get": {
+                "description": "Opens a WebSocket that streams the market data of a market as JSON messages. The first message is a SNAPSHOT of the whole depth; it is followed by a TRADE for every trade executed and a LEVEL for every price level whose aggregate amount changed, with an amount of 0 once the level is gone. Every message carries the next sequence number, so a client that sees a gap has missed updates and should open the feed again. A client that falls behind is disconnected with close code 1013.",
+                "tags": [
+                    "Market Data"
+                ],
+                "summary": "Stream market data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Instrument symbol",
+                        "name": "symbol",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "Switching to the WebSocket protocol",
+                        "schema": {
+                            "$ref": "#/definitions/services.FeedUpdate"
+                        }
+                    },
+                    "404": {
+                        "description": "Market not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrderBookResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/markets/{symbol}/halt": {
+            "post": {
+                "security": [
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Stops matching in the market until it is resumed. Resting orders stay in the book and can be canceled; new orders are refused or queued, as configured.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Markets"
+                ],
+                "summary": "Halt a market",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Instrument symbol",
+                        "name": "symbol",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Market halted",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MarketStateResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid signature",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin key",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Market not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MarketStateResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Too many commands are waiting",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MarketStateResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/markets/{symbol}/orderbook": {
+            "get": {
+                "description": "Returns a list of buy and sell orders with their price and liquidity.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Get order book",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Instrument symbol",
+                        "name": "symbol",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of orders to retrieve (default is 10)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved order book",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrderBookResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Market not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrderBookResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/markets/{symbol}/orders": {
+            "get": {
+                "security": [
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Returns a paginated list of the orders of the account of the API key ever accepted in a market, in the order they were accepted, with their current status. An admin key lists the orders of every account.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Get list of orders",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Instrument symbol",
+                        "name": "symbol",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number (default is 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of orders per page (default is 10)",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved list of orders",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid signature",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Market not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Places a buy or sell order in the order book of a market. Prices must be a multiple of the instrument's tick size and amounts a multiple of its lot size, within its quantity limits. The order is matched against the opposite side with price-time priority and may be partially filled. Depending on its time in force the unfilled remainder rests in the book (GTC, GTD until expire_at, DAY until the session close) or is canceled (IOC); a FOK order is filled completely or not at all. STOP and STOP_LIMIT orders wait until the last trade price reaches their stop_price and then trade as a MARKET or LIMIT order. The order belongs to the account of the API key and holds the funds of that account until it trades or closes: the amount of the base asset to sell, or the quote asset to buy at its price. A MARKET buy holds what it would cost to fill right now; a STOP buy, which has no price to hold funds for, is refused. An order that fails the pre-trade risk checks is recorded as REJECTED and returned with 422, with the code of the check in reason and in the reject_reason of the order: MAX_QUANTITY, MAX_NOTIONAL, FAT_FINGER, PRICE_COLLAR, MAX_OPEN_ORDERS or MAX_POSITION. A CLOSED market refuses every order with 409. While the market is HALTED or in its AUCTION, an order that cannot rest in the book (MARKET, STOP, IOC or FOK) is refused with 409 and any other order is accepted but waits, without trading, until the market reopens, when the waiting orders that cross trade at a single uncross price; unless the service queues orders during halts, a HALTED market refuses them all with 409. Returns the accepted order with its status and the trades executed, one per resting order traded against.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Create a new order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Instrument symbol",
+                        "name": "symbol",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Order details",
+                        "name": "order",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Order"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Order successfully placed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrderDetailsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid signature",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Market not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrderDetailsResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Duplicate order detected, market closed or halted, or insufficient funds",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrderDetailsResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid request payload, or refused by the risk checks",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrderDetailsResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Too many orders are waiting",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrderDetailsResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/markets/{symbol}/orders/{uuid}": {
+            "get": {
+                "security": [
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Returns any order ever accepted, including orders no longer in the book, with its status, filled and remaining amount and the trades it took part in. Orders of other accounts are not found, unless the key is an admin key.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Get an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Instrument symbol",
+                        "name": "symbol",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Order UUID",
+                        "name": "uuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved the order",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrderDetailsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid signature",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Market or order not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrderDetailsResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Removes a resting order from the order book, a stop order still waiting for its stop price, or an order waiting for a halted market to reopen, and returns it with status CANCELED. Orders of other accounts are not found, unless the key is an admin key.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Cancel an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Instrument symbol",
+                        "name": "symbol",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Order UUID",
+                        "name": "uuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Order successfully canceled",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrderResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid signature",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Market or order not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrderResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Order is no longer open",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrderResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Too many orders are waiting",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrderResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Changes the price and/or remaining amount of a resting order. Reducing the amount keeps the order's queue priority; changing the price or increasing the amount sends it to the back of the queue, where it may trade. An amendment that needs more funds than the order holds takes them from the available balance of its account. An amendment that takes the order's filled plus remaining amount outside the instrument's quantity limits is refused with 422, and one that fails the pre-trade risk checks with 422 and the code of the check in reason, and the order is left as it was. Amendments are refused with 409 while the market is not OPEN. Returns the amended order and the trades executed by the amendment. Orders of other accounts are not found, unless the key is an admin key.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Amend an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Instrument symbol",
+                        "name": "symbol",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Order UUID",
+                        "name": "uuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "amendment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.OrderAmendment"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Order successfully amended",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrderDetailsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid signature",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Market or order not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrderDetailsResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Order is no longer open, is waiting for its stop price or needs more funds than available, or market not open",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrderDetailsResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid request payload, outside the quantity limits, or refused by the risk checks",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrderDetailsResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Too many orders are waiting",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrderDetailsResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/markets/{symbol}/resume": {
+            "post": {
+                "security": [
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Reopens a HALTED or CLOSED market, through an AUCTION if one is configured, and sends the orders queued in the meantime through matching, in the order they were placed, once it is OPEN. Their trades can trip the circuit breaker again. Returns the state of the market afterwards.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Markets"
+                ],
+                "summary": "Resume a market",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Instrument symbol",
+                        "name": "symbol",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Market resumed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MarketStateResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid signature",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin key",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Market not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MarketStateResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Too many commands are waiting",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MarketStateResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/markets/{symbol}/state": {
+            "get": {
+                "description": "Returns whether the market is OPEN, HALTED, CLOSED or in its AUCTION, since when, and, for a halt or auction that ends on its own, until when. reason says why the market left OPEN: CIRCUIT_BREAKER when its last trade moved too far within the configured window, or MANUAL.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Markets"
+                ],
+                "summary": "Get the trading state of a market",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Instrument symbol",
+                        "name": "symbol",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved the trading state",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MarketStateResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Market not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MarketStateResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/markets/{symbol}/trades": {
+            "get": {
+                "description": "Returns a paginated list of the trades executed in a market, oldest first, optionally restricted to a time range.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Trades"
+                ],
+                "summary": "Get list of trades",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Instrument symbol",
+                        "name": "symbol",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number (default is 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of trades per page (default is 10)",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only trades executed at or after this time (RFC 3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only trades executed before this time (RFC 3339)",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved list of trades",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TradesResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Market not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TradesResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid time filter",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TradesResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "handlers.BalanceResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.Balance"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.BalancesResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Balance"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.InstrumentResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.Instrument"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.InstrumentsResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Instrument"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.MarketStateResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.MarketState"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.MessageResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.OrderBookResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.OrderBookEntry"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.OrderDetails": {
+            "type": "object",
+            "properties": {
+                "fills": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Trade"
+                    }
+                },
+                "order": {
+                    "$ref": "#/definitions/models.Order"
+                }
+            }
+        },
+        "handlers.OrderDetailsResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/handlers.OrderDetails"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "reason": {
+                    "description": "the risk check an order or amendment failed",
+                    "type": "string",
+                    "example": "MAX_NOTIONAL"
+                }
+            }
+        },
+        "handlers.OrderResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.Order"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.Response": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Order"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.TradesResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Trade"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Balance": {
+            "type": "object",
+            "properties": {
+                "asset": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "available": {
+                    "type": "string"
+                },
+                "held": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Instrument": {
+            "type": "object",
+            "required": [
+                "base_asset",
+                "lot_size",
+                "quote_asset",
+                "symbol",
+                "tick_size"
+            ],
+            "properties": {
+                "base_asset": {
+                    "type": "string",
+                    "example": "BTC"
+                },
+                "lot_size": {
+                    "description": "amounts are multiples of it",
+                    "type": "string",
+                    "example": "0.001"
+                },
+                "max_quantity": {
+                    "description": "No upper limit when left out",
+                    "type": "string",
+                    "example": "100.0"
+                },
+                "min_quantity": {
+                    "type": "string",
+                    "example": "0.001"
+                },
+                "quote_asset": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "status": {
+                    "description": "TRADING when left out",
+                    "enum": [
+                        "TRADING",
+                        "SUSPENDED"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.InstrumentStatus"
+                        }
+                    ],
+                    "example": "TRADING"
+                },
+                "symbol": {
+                    "type": "string",
+                    "maxLength": 32,
+                    "example": "BTC-USD"
+                },
+                "tick_size": {
+                    "description": "prices are multiples of it",
+                    "type": "string",
+                    "example": "0.01"
+                }
+            }
+        },
+        "models.InstrumentStatus": {
+            "type": "string",
+            "enum": [
+                "TRADING",
+                "SUSPENDED"
+            ],
+            "x-enum-comments": {
+                "InstrumentSuspended": "listed CLOSED, until an admin resumes it",
+                "InstrumentTrading": "listed OPEN"
+            },
+            "x-enum-varnames": [
+                "InstrumentTrading",
+                "InstrumentSuspended"
+            ]
+        },
+        "models.MarketState": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "description": "Why the market left OPEN: CIRCUIT_BREAKER, or MANUAL for an admin.",
+                    "type": "string",
+                    "example": "CIRCUIT_BREAKER"
+                },
+                "since": {
+                    "type": "string"
+                },
+                "state": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.TradingState"
+                        }
+                    ],
+                    "example": "HALTED"
+                },
+                "until": {
+                    "description": "When a halt or an auction ends on its own; zero while it lasts until\nthe market is resumed.",
+                    "type": "string"
+                }
+            }
+        },
+        "models.Order": {
+            "type": "object",
+            "required": [
+                "action",
+                "amount",
+                "uuid"
+            ],
+            "properties": {
+                "account_id": {
+                    "description": "The account the order holds its funds from, taken from the API key.\nOrders of the same account never trade against each other.",
+                    "type": "string",
+                    "example": "account-1"
+                },
+                "action": {
+                    "enum": [
+                        "BUY",
+                        "SELL"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.OrderType"
+                        }
+                    ]
+                },
+                "amount": {
+                    "type": "string",
+                    "example": "10.0"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "display_amount": {
+                    "description": "Makes the order an iceberg: only this much of it is shown in the order book\nat a time, the rest is held in reserve.",
+                    "type": "string",
+                    "example": "1.0"
+                },
+                "expire_at": {
+                    "description": "Required for GTD orders; set by the order book for DAY orders.",
+                    "type": "string",
+                    "example": "2025-03-01T16:00:00Z"
+                },
+                "filled_amount": {
+                    "type": "string"
+                },
+                "post_only": {
+                    "description": "Set for orders that must not trade on arrival.",
+                    "enum": [
+                        "REJECT",
+                        "REPRICE"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.PostOnly"
+                        }
+                    ],
+                    "example": "REJECT"
+                },
+                "price": {
+                    "description": "Required for LIMIT and STOP_LIMIT orders, not allowed for MARKET and STOP orders.",
+                    "type": "string",
+                    "example": "100.0"
+                },
+                "protection_band": {
+                    "description": "Optional for MARKET orders: the furthest the order may trade from the best\nopposite price at the time it arrives.",
+                    "type": "string",
+                    "example": "0.5"
+                },
+                "reject_reason": {
+                    "description": "Why a REJECTED order was refused: POST_ONLY, or the code of the risk\ncheck it failed, such as MAX_NOTIONAL.",
+                    "type": "string",
+                    "example": "PRICE_COLLAR"
+                },
+                "remaining_amount": {
+                    "type": "string"
+                },
+                "sequence": {
+                    "description": "maintained by the order book once the order is accepted",
+                    "type": "integer"
+                },
+                "status": {
+                    "$ref": "#/definitions/models.OrderStatus"
+                },
+                "stop_price": {
+                    "description": "Required for STOP and STOP_LIMIT orders: a buy stop triggers once the last\ntrade price is at or above it, a sell stop once it is at or below it.",
+                    "type": "string",
+                    "example": "105.0"
+                },
+                "stp_mode": {
+                    "description": "Applied when the order would trade against a resting order of the same\naccount; CANCEL_NEWEST when left out.",
+                    "enum": [
+                        "CANCEL_NEWEST",
+                        "CANCEL_OLDEST",
+                        "CANCEL_BOTH",
+                        "DECREMENT"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.SelfTradePrevention"
+                        }
+                    ],
+                    "example": "CANCEL_NEWEST"
+                },
+                "symbol": {
+                    "description": "taken from the request path",
+                    "type": "string",
+                    "example": "BTC-USD"
+                },
+                "time_in_force": {
+                    "description": "GTC for LIMIT and IOC for MARKET orders when left out",
+                    "enum": [
+                        "GTC",
+                        "IOC",
+                        "FOK",
+                        "GTD",
+                        "DAY"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.TimeInForce"
+                        }
+                    ],
+                    "example": "GTC"
+                },
+                "triggered_at": {
+                    "description": "when a STOP or STOP_LIMIT order left the trigger book",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "LIMIT when left out",
+                    "enum": [
+                        "LIMIT",
+                        "MARKET",
+                        "STOP",
+                        "STOP_LIMIT"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.OrderKind"
+                        }
+                    ],
+                    "example": "LIMIT"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "uuid": {
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-646655440000"
+                },
+                "visible_amount": {
+                    "description": "the part of the remaining amount shown in the order book",
+                    "type": "string"
+                }
+            }
+        },
+        "models.OrderAmendment": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "5.0"
+                },
+                "price": {
+                    "type": "string",
+                    "example": "101.0"
+                }
+            }
+        },
+        "models.OrderBookEntry": {
+            "type": "object",
+            "properties": {
+                "liquidity": {
+                    "type": "string"
                 },
                 "price": {
-                    "type": "number"
+                    "type": "string"
                 },
                 "type": {
                     "$ref": "#/definitions/models.OrderType"
                 }
             }
         },
+        "models.OrderKind": {
+            "type": "string",
+            "enum": [
+                "LIMIT",
+                "MARKET",
+                "STOP",
+                "STOP_LIMIT"
+            ],
+            "x-enum-varnames": [
+                "Limit",
+                "Market",
+                "Stop",
+                "StopLimit"
+            ]
+        },
+        "models.OrderStatus": {
+            "type": "string",
+            "enum": [
+                "NEW",
+                "PARTIALLY_FILLED",
+                "FILLED",
+                "CANCELED",
+                "REJECTED",
+                "EXPIRED"
+            ],
+            "x-enum-varnames": [
+                "StatusNew",
+                "StatusPartiallyFilled",
+                "StatusFilled",
+                "StatusCanceled",
+                "StatusRejected",
+                "StatusExpired"
+            ]
+        },
         "models.OrderType": {
             "type": "string",
             "enum": [
@@ -206,6 +1668,264 @@ const docTemplate = `{
                 "Buy",
                 "Sell"
             ]
+        },
+        "models.PostOnly": {
+            "type": "string",
+            "enum": [
+                "REJECT",
+                "REPRICE"
+            ],
+            "x-enum-comments": {
+                "PostOnlyReject": "the order is rejected",
+                "PostOnlyReprice": "the order rests one tick behind the best opposite price"
+            },
+            "x-enum-varnames": [
+                "PostOnlyReject",
+                "PostOnlyReprice"
+            ]
+        },
+        "models.SelfTradePrevention": {
+            "type": "string",
+            "enum": [
+                "CANCEL_NEWEST",
+                "CANCEL_OLDEST",
+                "CANCEL_BOTH",
+                "DECREMENT"
+            ],
+            "x-enum-comments": {
+                "CancelBoth": "both orders are canceled",
+                "CancelNewest": "the incoming order is canceled",
+                "CancelOldest": "the resting order is canceled",
+                "Decrement": "both orders are reduced by the smaller remaining amount"
+            },
+            "x-enum-varnames": [
+                "CancelNewest",
+                "CancelOldest",
+                "CancelBoth",
+                "Decrement"
+            ]
+        },
+        "models.TimeInForce": {
+            "type": "string",
+            "enum": [
+                "GTC",
+                "IOC",
+                "FOK",
+                "GTD",
+                "DAY"
+            ],
+            "x-enum-comments": {
+                "Day": "rests until the end of the trading session",
+                "FillOrKill": "fills completely on arrival or not at all",
+                "GoodTillCanceled": "rests until filled or canceled",
+                "GoodTillDate": "rests until its expire_at",
+                "ImmediateOrCancel": "fills what crosses on arrival, the rest is canceled"
+            },
+            "x-enum-varnames": [
+                "GoodTillCanceled",
+                "ImmediateOrCancel",
+                "FillOrKill",
+                "GoodTillDate",
+                "Day"
+            ]
+        },
+        "models.Trade": {
+            "type": "object",
+            "properties": {
+                "aggressor_side": {
+                    "$ref": "#/definitions/models.OrderType"
+                },
+                "amount": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "maker_order_uuid": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "string"
+                },
+                "sequence": {
+                    "description": "sequence number of the order placement that produced the trade",
+                    "type": "integer"
+                },
+                "symbol": {
+                    "type": "string"
+                },
+                "taker_order_uuid": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TradingState": {
+            "type": "string",
+            "enum": [
+                "OPEN",
+                "HALTED",
+                "CLOSED",
+                "AUCTION"
+            ],
+            "x-enum-comments": {
+                "StateAuction": "new orders are queued until the market reopens",
+                "StateClosed": "new orders are refused",
+                "StateHalted": "new orders are refused or queued until the market reopens"
+            },
+            "x-enum-varnames": [
+                "StateOpen",
+                "StateHalted",
+                "StateClosed",
+                "StateAuction"
+            ]
+        },
+        "models.Transfer": {
+            "type": "object",
+            "required": [
+                "amount",
+                "asset"
+            ],
+            "properties": {
+                "account_id": {
+                    "description": "taken from the request path",
+                    "type": "string"
+                },
+                "amount": {
+                    "type": "string",
+                    "example": "1000.0"
+                },
+                "asset": {
+                    "type": "string",
+                    "example": "USD"
+                }
+            }
+        },
+        "services.BookDepth": {
+            "type": "object",
+            "properties": {
+                "asks": {
+                    "description": "best, lowest, price first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.OrderBookEntry"
+                    }
+                },
+                "bids": {
+                    "description": "best, highest, price first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.OrderBookEntry"
+                    }
+                }
+            }
+        },
+        "services.ExecutionReport": {
+            "type": "object",
+            "properties": {
+                "account_id": {
+                    "type": "string"
+                },
+                "order": {
+                    "description": "The order as the report left it; for a FILL, as the command left it.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Order"
+                        }
+                    ]
+                },
+                "order_uuid": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "sequence": {
+                    "type": "integer"
+                },
+                "symbol": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "trade": {
+                    "$ref": "#/definitions/models.Trade"
+                },
+                "type": {
+                    "$ref": "#/definitions/services.ExecutionType"
+                }
+            }
+        },
+        "services.ExecutionType": {
+            "type": "string",
+            "enum": [
+                "NEW",
+                "TRIGGERED",
+                "FILL",
+                "AMENDED",
+                "CANCELED",
+                "EXPIRED",
+                "REJECTED"
+            ],
+            "x-enum-comments": {
+                "ExecutionRejected": "the order, or a cancel or amendment of it, was refused"
+            },
+            "x-enum-varnames": [
+                "ExecutionNew",
+                "ExecutionTriggered",
+                "ExecutionFill",
+                "ExecutionAmended",
+                "ExecutionCanceled",
+                "ExecutionExpired",
+                "ExecutionRejected"
+            ]
+        },
+        "services.FeedUpdate": {
+            "type": "object",
+            "properties": {
+                "depth": {
+                    "$ref": "#/definitions/services.BookDepth"
+                },
+                "level": {
+                    "$ref": "#/definitions/models.OrderBookEntry"
+                },
+                "sequence": {
+                    "type": "integer"
+                },
+                "symbol": {
+                    "type": "string"
+                },
+                "trade": {
+                    "$ref": "#/definitions/models.Trade"
+                },
+                "type": {
+                    "$ref": "#/definitions/services.FeedUpdateType"
+                }
+            }
+        },
+        "services.FeedUpdateType": {
+            "type": "string",
+            "enum": [
+                "SNAPSHOT",
+                "LEVEL",
+                "TRADE"
+            ],
+            "x-enum-varnames": [
+                "FeedSnapshot",
+                "FeedLevel",
+                "FeedTrade"
+            ]
+        }
+    },
+    "securityDefinitions": {
+        "APIKey": {
+            "description": "The API key of an account. Every request must also carry X-API-Timestamp (milliseconds since the Unix epoch), X-API-Nonce (unique per key, up to 64 characters) and X-API-Signature: the hex encoded HMAC-SHA256, keyed with the secret of the key, of the timestamp, nonce, method and path with query, each followed by a newline, then the body.",
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
         }
     }
 }`
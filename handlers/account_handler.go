@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"order-matching/models"
+	"order-matching/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BalanceResponse struct {
+	Message string `json:"message"`
+	Data models.Balance `json:"data"`
+}
+
+type BalancesResponse struct {
+	Message string `json:"message"`
+	Data []models.Balance `json:"data"`
+}
+
+// Deposit adds funds to an account
+//	@Summary		Deposit funds
+//	@Description	Adds funds of an asset to the available balance of an account, opening the account if needed. Needs an admin key: a deposit records funds the exchange received from outside, which an account cannot vouch for itself. Returns the new balance of the asset.
+//	@Tags			Accounts
+//	@Accept			json
+//	@Produce		json
+//	@Security		APIKey
+//	@Param			account		path		string			true	"Account ID"
+//	@Param			transfer	body		models.Transfer	true	"Asset and amount"	Example({ "asset": "USD", "amount": "1000" })
+//	@Success		200			{object}	BalanceResponse	"Funds successfully deposited"
+//	@Failure		422			{object}	BalanceResponse	"Invalid request payload"
+//	@Failure		503			{object}	BalanceResponse	"Too many commands are waiting"
+//	@Failure		401			{object}	MessageResponse	"Missing or invalid signature"
+//	@Failure		403			{object}	MessageResponse	"Not an admin key"
+//	@Failure		429			{object}	MessageResponse	"Too many requests"
+//	@Router			/accounts/{account}/deposits [post]
+func Deposit(sequencer *services.Sequencer) gin.HandlerFunc {
+	return transfer(sequencer.Deposit)
+}
+
+// Withdraw takes funds out of an account
+//	@Summary		Withdraw funds
+//	@Description	Takes funds of an asset out of the available balance of an account. Funds held by open orders cannot be withdrawn. Returns the new balance of the asset.
+//	@Tags			Accounts
+//	@Accept			json
+//	@Produce		json
+//	@Security		APIKey
+//	@Param			account		path		string			true	"Account ID"
+//	@Param			transfer	body		models.Transfer	true	"Asset and amount"	Example({ "asset": "USD", "amount": "100" })
+//	@Success		200			{object}	BalanceResponse	"Funds successfully withdrawn"
+//	@Failure		422			{object}	BalanceResponse	"Invalid request payload"
+//	@Failure		409			{object}	BalanceResponse	"Insufficient funds"
+//	@Failure		503			{object}	BalanceResponse	"Too many commands are waiting"
+//	@Failure		401			{object}	MessageResponse	"Missing or invalid signature"
+//	@Failure		403			{object}	MessageResponse	"Another account, without an admin key"
+//	@Failure		429			{object}	MessageResponse	"Too many requests"
+//	@Router			/accounts/{account}/withdrawals [post]
+func Withdraw(sequencer *services.Sequencer) gin.HandlerFunc {
+	return transfer(sequencer.Withdraw)
+}
+
+func transfer(apply func(models.Transfer) (models.Balance, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var transfer models.Transfer
+		if err := c.ShouldBindJSON(&transfer); err != nil {
+			fmt.Println(err.Error())
+			c.JSON(http.StatusUnprocessableEntity, BalanceResponse{
+				Message: "Invalid request",
+			})
+			return
+		}
+		transfer.AccountID = c.Param("account")
+
+		balance, err := apply(transfer)
+		if err != nil {
+			status, message := orderErrorResponse(err)
+			c.JSON(status, BalanceResponse{
+				Message: message,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, BalanceResponse{
+			Message: "success",
+			Data: balance,
+		})
+	}
+}
+
+// GetBalances retrieves the balances of an account
+//	@Summary		Get the balances of an account
+//	@Description	Returns the balance of every asset of an account, ordered by asset: the available funds, and the funds held by its open orders. An unknown account has no balances.
+//	@Tags			Accounts
+//	@Produce		json
+//	@Security		APIKey
+//	@Param			account	path		string				true	"Account ID"
+//	@Success		200		{object}	BalancesResponse	"Successfully retrieved the balances"
+//	@Failure		401		{object}	MessageResponse		"Missing or invalid signature"
+//	@Failure		403		{object}	MessageResponse		"Another account, without an admin key"
+//	@Failure		429		{object}	MessageResponse		"Too many requests"
+//	@Router			/accounts/{account}/balances [get]
+func GetBalances(markets *services.BookManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, BalancesResponse{
+			Message: "success",
+			Data: markets.Accounts().Balances(c.Param("account")),
+		})
+	}
+}
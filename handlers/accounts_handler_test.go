@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"order-matching/models"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccounts(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+
+	newEngine := func(t *testing.T, admin bool) *gin.Engine {
+		sequencer := newMarkets(t)
+		engine := gin.New()
+		engine.Use(asAccount("account-1", admin))
+		account := engine.Group("/api/accounts/:account", RequireAccount())
+		account.POST("/deposits", RequireAdmin(), Deposit(sequencer))
+		account.POST("/withdrawals", Withdraw(sequencer))
+		account.GET("/balances", GetBalances(sequencer.Markets()))
+		engine.POST("/api/markets/:symbol/orders", CreateOrder(sequencer))
+		return engine
+	}
+
+	post := func(engine *gin.Engine, path string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("It returns 200 with the new balance for a deposit", func(t *testing.T) {
+		t.Parallel()
+		engine := newEngine(t, true)
+
+		recorder := post(engine, "/api/accounts/account-1/deposits", `{"asset": "USD", "amount": "250.5"}`)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		response := new(BalanceResponse)
+		json.Unmarshal(recorder.Body.Bytes(), response)
+		assert.Equal(t, models.Balance{Asset: "USD", Available: decimal("1000250.5")}, response.Data)
+	})
+
+	t.Run("It returns 403 error for a deposit without an admin key", func(t *testing.T) {
+		t.Parallel()
+		engine := newEngine(t, false)
+
+		recorder := post(engine, "/api/accounts/account-1/deposits", `{"asset": "USD", "amount": "250.5"}`)
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+	})
+
+	t.Run("It returns 403 error for another account without an admin key", func(t *testing.T) {
+		t.Parallel()
+		engine := newEngine(t, false)
+
+		recorder := post(engine, "/api/accounts/account-2/withdrawals", `{"asset": "USD", "amount": "250.5"}`)
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+	})
+
+	t.Run("It returns 422 error for a deposit without an amount", func(t *testing.T) {
+		t.Parallel()
+		engine := newEngine(t, true)
+
+		recorder := post(engine, "/api/accounts/account-1/deposits", `{"asset": "USD"}`)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	})
+
+	t.Run("It returns 409 error for a withdrawal of held funds", func(t *testing.T) {
+		t.Parallel()
+		engine := newEngine(t, false)
+		post(engine, "/api/markets/BTC-USD/orders", `{"uuid": "550e8400-e29b-41d4-a716-446655440000", "action": "SELL", "price": "100", "amount": "600"}`)
+
+		recorder := post(engine, "/api/accounts/account-1/withdrawals", `{"asset": "BTC", "amount": "500"}`)
+
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+		response := new(BalanceResponse)
+		json.Unmarshal(recorder.Body.Bytes(), response)
+		assert.Equal(t, "Insufficient funds", response.Message)
+	})
+
+	t.Run("It returns 409 error for an order the account cannot fund", func(t *testing.T) {
+		t.Parallel()
+		engine := newEngine(t, false)
+
+		recorder := post(engine, "/api/markets/BTC-USD/orders", `{"uuid": "550e8400-e29b-41d4-a716-446655440000", "action": "BUY", "price": "100", "amount": "20000"}`)
+
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+		response := new(OrderDetailsResponse)
+		json.Unmarshal(recorder.Body.Bytes(), response)
+		assert.Equal(t, "Insufficient funds", response.Message)
+	})
+
+	t.Run("It returns the available and held balances of an account", func(t *testing.T) {
+		t.Parallel()
+		engine := newEngine(t, false)
+		post(engine, "/api/markets/BTC-USD/orders", `{"uuid": "550e8400-e29b-41d4-a716-446655440000", "action": "BUY", "price": "100", "amount": "2"}`)
+
+		req, _ := http.NewRequest(http.MethodGet, "/api/accounts/account-1/balances", nil)
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		response := new(BalancesResponse)
+		json.Unmarshal(recorder.Body.Bytes(), response)
+		assert.Equal(t, []models.Balance{
+			{Asset: "BTC", Available: decimal("1000.0")},
+			{Asset: "USD", Available: decimal("999800.0"), Held: decimal("200.0")},
+		}, response.Data)
+	})
+}
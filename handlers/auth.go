@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"order-matching/config"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The headers of a signed request.
+const (
+	apiKeyHeader = "X-API-Key"
+	timestampHeader = "X-API-Timestamp" // milliseconds since the Unix epoch
+	nonceHeader = "X-API-Nonce"
+	signatureHeader = "X-API-Signature"
+)
+
+// maxSignedBody bounds the body read to check the signature of a request.
+const maxSignedBody = 1 << 20
+
+// maxNonceLength bounds the nonces kept to refuse replayed requests.
+const maxNonceLength = 64
+
+// The gin context keys holding the authenticated key.
+const (
+	accountIDKey = "account_id"
+	adminKey = "admin"
+)
+
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// Authenticate lets through only requests signed with the secret of an API
+// key, and stores the account of the key, and whether it is an admin key, for
+// the handlers. The signature is the hex encoded HMAC-SHA256, keyed with the
+// secret, of the timestamp, the nonce, the method, the path with its query
+// and the body of the request, each followed by a newline but the body.
+//
+// A request is refused when its timestamp is more than window away from now,
+// or when its key used its nonce before within the window, so that a request
+// that was seen cannot be sent again. The same middleware has to guard every
+// route for the nonces to be remembered across them.
+func Authenticate(keys map[string]config.APIKey, window time.Duration) gin.HandlerFunc {
+	nonces := newNonceCache(window)
+
+	return func(c *gin.Context) {
+		key, exists := keys[c.GetHeader(apiKeyHeader)]
+		if !exists {
+			unauthorized(c, "A valid API key is required")
+			return
+		}
+
+		now := time.Now()
+		milliseconds, err := strconv.ParseInt(c.GetHeader(timestampHeader), 10, 64)
+		if err != nil {
+			unauthorized(c, "A timestamp in milliseconds is required")
+			return
+		}
+		if timestamp := time.UnixMilli(milliseconds); timestamp.Before(now.Add(-window)) || timestamp.After(now.Add(window)) {
+			unauthorized(c, "The timestamp is too far from the time of the service")
+			return
+		}
+
+		nonce := c.GetHeader(nonceHeader)
+		if nonce == "" || len(nonce) > maxNonceLength {
+			unauthorized(c, "A nonce of up to 64 characters is required")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxSignedBody))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, MessageResponse{
+				Message: "The request body is too large",
+			})
+			return
+		}
+		if err != nil {
+			unauthorized(c, "The request body cannot be read")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		signature, err := hex.DecodeString(c.GetHeader(signatureHeader))
+		if err != nil || !hmac.Equal(signature, Sign(key.Secret, c.GetHeader(timestampHeader), nonce, c.Request.Method, c.Request.URL.RequestURI(), body)) {
+			unauthorized(c, "The signature does not match the request")
+			return
+		}
+
+		// only signed requests are remembered, so that guesses cannot fill
+		// the cache
+		if !nonces.add(c.GetHeader(apiKeyHeader)+"\n"+nonce, now) {
+			unauthorized(c, "The nonce was used before")
+			return
+		}
+
+		c.Set(accountIDKey, key.AccountID)
+		c.Set(adminKey, key.Admin)
+		c.Next()
+	}
+}
+
+// Sign returns the signature of a request, as Authenticate expects it.
+func Sign(secret string, timestamp string, nonce string, method string, uri string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + nonce + "\n" + method + "\n" + uri + "\n"))
+	mac.Write(body)
+
+	return mac.Sum(nil)
+}
+
+func unauthorized(c *gin.Context, message string) {
+	c.AbortWithStatusJSON(http.StatusUnauthorized, MessageResponse{
+		Message: message,
+	})
+}
+
+// RequireAdmin lets through only requests authenticated with an admin key.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isAdmin(c) {
+			c.AbortWithStatusJSON(http.StatusForbidden, MessageResponse{
+				Message: "This requires an admin key",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireAccount lets through only requests that may act for the account in
+// the path: their own, or any with an admin key.
+func RequireAccount() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !authorizedFor(c, c.Param("account")) {
+			c.AbortWithStatusJSON(http.StatusForbidden, MessageResponse{
+				Message: "This key cannot act for the account",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// authenticatedAccount returns the ID of the account Authenticate let through.
+func authenticatedAccount(c *gin.Context) string {
+	return c.GetString(accountIDKey)
+}
+
+// isAdmin reports whether the request was authenticated with an admin key.
+func isAdmin(c *gin.Context) bool {
+	return c.GetBool(adminKey)
+}
+
+// authorizedFor reports whether the request may act for an account: its own,
+// or any with an admin key.
+func authorizedFor(c *gin.Context, accountID string) bool {
+	return isAdmin(c) || authenticatedAccount(c) == accountID
+}
+
+// nonceCache remembers the nonces of the signed requests of the last two
+// windows: a request may be up to a window early or late, so its nonce has to
+// be known for as long as its timestamp is accepted. It is safe for
+// concurrent use.
+type nonceCache struct {
+	mutex sync.Mutex
+	window time.Duration
+	seen map[string]time.Time // when every nonce may be forgotten, by key and nonce
+	nextPrune time.Time
+}
+
+func newNonceCache(window time.Duration) *nonceCache {
+	return &nonceCache{
+		window: window,
+		seen: make(map[string]time.Time),
+	}
+}
+
+// add remembers a nonce, or reports false if it is still remembered.
+func (nc *nonceCache) add(nonce string, now time.Time) bool {
+	nc.mutex.Lock()
+	defer nc.mutex.Unlock()
+
+	if now.After(nc.nextPrune) {
+		for seen, forgetAt := range nc.seen {
+			if now.After(forgetAt) {
+				delete(nc.seen, seen)
+			}
+		}
+		nc.nextPrune = now.Add(nc.window)
+	}
+
+	if forgetAt, exists := nc.seen[nonce]; exists && !now.After(forgetAt) {
+		return false
+	}
+	nc.seen[nonce] = now.Add(2 * nc.window)
+
+	return true
+}
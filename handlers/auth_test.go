@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"order-matching/config"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthenticate(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+
+	keys := map[string]config.APIKey{
+		"key-1": {Secret: "secret-1", AccountID: "account-1"},
+		"key-admin": {Secret: "secret-admin", AccountID: "operator", Admin: true},
+	}
+
+	newEngine := func() *gin.Engine {
+		engine := gin.New()
+		authenticate := Authenticate(keys, time.Minute)
+		whoAmI := func(c *gin.Context) {
+			c.String(http.StatusOK, authenticatedAccount(c))
+		}
+		engine.POST("/api/echo", authenticate, whoAmI)
+		engine.POST("/api/admin", authenticate, RequireAdmin(), whoAmI)
+		return engine
+	}
+
+	// signed builds a request signed with the secret of key
+	signed := func(key string, secret string, timestamp time.Time, nonce string, path string, body string) *http.Request {
+		req, _ := http.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		milliseconds := strconv.FormatInt(timestamp.UnixMilli(), 10)
+		req.Header.Set("X-API-Key", key)
+		req.Header.Set("X-API-Timestamp", milliseconds)
+		req.Header.Set("X-API-Nonce", nonce)
+		req.Header.Set("X-API-Signature", hex.EncodeToString(Sign(secret, milliseconds, nonce, http.MethodPost, path, []byte(body))))
+		return req
+	}
+
+	serve := func(engine *gin.Engine, req *http.Request) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("It lets a signed request act for the account of its key", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := serve(newEngine(), signed("key-1", "secret-1", time.Now(), "nonce-1", "/api/echo?page=2", `{"amount": "1"}`))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "account-1", recorder.Body.String())
+	})
+
+	t.Run("It returns 401 error for a tampered body", func(t *testing.T) {
+		t.Parallel()
+		req := signed("key-1", "secret-1", time.Now(), "nonce-1", "/api/echo", `{"amount": "1"}`)
+		req.Body = http.NoBody
+		req.ContentLength = 0
+
+		assert.Equal(t, http.StatusUnauthorized, serve(newEngine(), req).Code)
+	})
+
+	t.Run("It returns 401 error for the secret of another key", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := serve(newEngine(), signed("key-admin", "secret-1", time.Now(), "nonce-1", "/api/echo", ""))
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+
+	t.Run("It returns 401 error for a stale timestamp", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := serve(newEngine(), signed("key-1", "secret-1", time.Now().Add(-2*time.Minute), "nonce-1", "/api/echo", ""))
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+
+	t.Run("It returns 401 error for a replayed request", func(t *testing.T) {
+		t.Parallel()
+		engine := newEngine()
+		now := time.Now()
+
+		assert.Equal(t, http.StatusOK, serve(engine, signed("key-1", "secret-1", now, "nonce-1", "/api/echo", "")).Code)
+		assert.Equal(t, http.StatusUnauthorized, serve(engine, signed("key-1", "secret-1", now, "nonce-1", "/api/echo", "")).Code)
+		assert.Equal(t, http.StatusOK, serve(engine, signed("key-admin", "secret-admin", now, "nonce-1", "/api/echo", "")).Code, "nonces are per key")
+	})
+
+	t.Run("It returns 403 error for an admin route without an admin key", func(t *testing.T) {
+		t.Parallel()
+		engine := newEngine()
+
+		assert.Equal(t, http.StatusForbidden, serve(engine, signed("key-1", "secret-1", time.Now(), "nonce-1", "/api/admin", "")).Code)
+		assert.Equal(t, http.StatusOK, serve(engine, signed("key-admin", "secret-admin", time.Now(), "nonce-1", "/api/admin", "")).Code)
+	})
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"order-matching/services"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	executionsBufferSize = 1024 // reports held for a client before it is dropped
+	executionsHeartbeatInterval = 30 * time.Second
+)
+
+// StreamExecutions streams the execution reports of the authenticated account
+// as server-sent events.
+//
+//	@Summary		Stream execution reports
+//	@Description	Streams the execution reports of the orders of the authenticated account as server-sent events: NEW, TRIGGERED, FILL, AMENDED, CANCELED, EXPIRED and REJECTED. Every order of the account is reported, whichever of its API keys placed it. Every event is named after the report type and carries the account's next sequence number as its id. A client that reconnects with the last id it saw, in the Last-Event-ID header or the after parameter, first receives every report it missed; without one it receives new reports only. A heartbeat event without an id is sent every 30 seconds. A client that falls too far behind is disconnected and can resume.
+//	@Tags			Executions
+//	@Produce		text/event-stream
+//	@Security		APIKey
+//	@Param			after			query		int		false	"Resume after the report with this sequence number"
+//	@Param			Last-Event-ID	header		int		false	"Resume after the report with this sequence number"
+//	@Success		200				{object}	services.ExecutionReport	"Stream of execution reports"
+//	@Failure		401				{object}	MessageResponse	"Missing or invalid signature"
+//	@Failure		410				{object}	MessageResponse	"The reports to resume from are no longer kept"
+//	@Failure		422				{object}	MessageResponse	"Invalid sequence number"
+//	@Failure		429				{object}	MessageResponse	"Too many requests"
+//	@Router			/executions [get]
+func StreamExecutions(markets *services.BookManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		executions := markets.Executions()
+		accountID := authenticatedAccount(c)
+
+		after := c.Query("after")
+		if after == "" {
+			after = c.GetHeader("Last-Event-ID")
+		}
+
+		var missed []services.ExecutionReport
+		var subscription *services.ExecutionSubscription
+		if after == "" {
+			subscription = executions.Subscribe(accountID, executionsBufferSize)
+		} else {
+			sequence, err := strconv.ParseUint(after, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusUnprocessableEntity, MessageResponse{
+					Message: "Invalid sequence number",
+				})
+				return
+			}
+			missed, subscription, err = executions.Resume(accountID, sequence, executionsBufferSize)
+			if errors.Is(err, services.ErrExecutionsGone) {
+				c.JSON(http.StatusGone, MessageResponse{
+					Message: "The reports after this sequence number are no longer kept, fetch the orders instead",
+				})
+				return
+			}
+		}
+		defer executions.Unsubscribe(subscription)
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Status(http.StatusOK)
+		for _, report := range missed {
+			renderExecution(c, report)
+		}
+		c.Writer.Flush()
+
+		heartbeat := time.NewTicker(executionsHeartbeatInterval)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case report, open := <-subscription.Reports:
+				if !open {
+					// too far behind; the client resumes from its last report
+					return
+				}
+				renderExecution(c, report)
+			case <-heartbeat.C:
+				c.Render(-1, sse.Event{Event: "heartbeat", Data: ""})
+			case <-c.Request.Context().Done():
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+func renderExecution(c *gin.Context, report services.ExecutionReport) {
+	c.Render(-1, sse.Event{
+		Id: strconv.FormatUint(report.Sequence, 10),
+		Event: string(report.Type),
+		Data: report,
+	})
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"order-matching/config"
+	"order-matching/models"
+	"order-matching/services"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamExecutions(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+
+	newServer := func(t *testing.T) (*services.Sequencer, *httptest.Server) {
+		sequencer := newMarkets(t)
+		engine := gin.New()
+		engine.GET("/api/executions", asAccount("account-1", false), StreamExecutions(sequencer.Markets()))
+		server := httptest.NewServer(engine)
+		t.Cleanup(server.Close)
+		return sequencer, server
+	}
+
+	open := func(server *httptest.Server, lastEventID string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/executions", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		response, _ := http.DefaultClient.Do(req)
+		return response
+	}
+
+	// next reads the id, name and report of the next event of the stream
+	next := func(reader *bufio.Reader) (string, string, services.ExecutionReport) {
+		var id, name string
+		report := services.ExecutionReport{}
+		for {
+			line, _ := reader.ReadString('\n')
+			line = strings.TrimRight(line, "\n")
+			switch {
+			case line == "":
+				return id, name, report
+			case strings.HasPrefix(line, "id:"):
+				id = line[len("id:"):]
+			case strings.HasPrefix(line, "event:"):
+				name = line[len("event:"):]
+			case strings.HasPrefix(line, "data:"):
+				json.Unmarshal([]byte(line[len("data:"):]), &report)
+			}
+		}
+	}
+
+	place := func(sequencer *services.Sequencer, id string) {
+		market, _ := sequencer.Markets().Market("BTC-USD")
+		sequencer.PlaceOrder(market, &models.Order{ID: id, Symbol: "BTC-USD", AccountID: "account-1", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("1.0")})
+	}
+
+	t.Run("It streams the reports of the account", func(t *testing.T) {
+		t.Parallel()
+		sequencer, server := newServer(t)
+		response := open(server, "")
+		defer response.Body.Close()
+
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+		place(sequencer, "550e8400-e29b-41d4-a716-446655440000")
+
+		id, name, report := next(bufio.NewReader(response.Body))
+		assert.Equal(t, "1", id)
+		assert.Equal(t, "NEW", name)
+		assert.Equal(t, "account-1", report.AccountID)
+		assert.Equal(t, "550e8400-e29b-41d4-a716-446655440000", report.OrderID)
+	})
+
+	t.Run("It resumes from the last event ID", func(t *testing.T) {
+		t.Parallel()
+		sequencer, server := newServer(t)
+		place(sequencer, "550e8400-e29b-41d4-a716-446655440000")
+		place(sequencer, "550e8400-e29b-41d4-a716-446655440001")
+
+		response := open(server, "1")
+		defer response.Body.Close()
+
+		id, _, report := next(bufio.NewReader(response.Body))
+		assert.Equal(t, "2", id)
+		assert.Equal(t, "550e8400-e29b-41d4-a716-446655440001", report.OrderID)
+	})
+
+	t.Run("It returns 410 error when the reports are no longer kept", func(t *testing.T) {
+		t.Parallel()
+		_, server := newServer(t)
+
+		response := open(server, "5")
+		response.Body.Close()
+
+		assert.Equal(t, http.StatusGone, response.StatusCode)
+	})
+
+	t.Run("It returns 401 error without a signature", func(t *testing.T) {
+		t.Parallel()
+		engine := gin.New()
+		engine.GET("/api/executions", Authenticate(map[string]config.APIKey{"key-1": {Secret: "secret", AccountID: "account-1"}}, time.Minute), StreamExecutions(newMarkets(t).Markets()))
+
+		req, _ := http.NewRequest(http.MethodGet, "/api/executions", nil)
+		req.Header.Set("X-API-Key", "key-1")
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"order-matching/models"
+	"order-matching/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InstrumentResponse struct {
+	Message string `json:"message"`
+	Data models.Instrument `json:"data"`
+}
+
+type InstrumentsResponse struct {
+	Message string `json:"message"`
+	Data []models.Instrument `json:"data"`
+}
+
+// CreateInstrument lists a new instrument
+//	@Summary		Create an instrument
+//	@Description	Lists a new instrument and opens an empty order book for it under /markets/{symbol}. Prices and amounts are fixed-point decimals written as strings; the tick size and lot size decide how many decimal places the prices and amounts of its orders have.
+//	@Tags			Markets
+//	@Accept			json
+//	@Produce		json
+//	@Security		APIKey
+//	@Param			instrument	body		models.Instrument	true	"Instrument details"	Example({ "symbol": "BTC-USD", "base_asset": "BTC", "quote_asset": "USD", "tick_size": "0.01", "lot_size": "0.001", "min_quantity": "0.001", "max_quantity": "100" })
+//	@Success		200			{object}	InstrumentResponse	"Instrument successfully created"
+//	@Failure		422			{object}	InstrumentResponse	"Invalid request payload"
+//	@Failure		409			{object}	InstrumentResponse	"Instrument already exists"
+//	@Failure		503			{object}	InstrumentResponse	"Too many commands are waiting"
+//	@Failure		401			{object}	MessageResponse	"Missing or invalid signature"
+//	@Failure		403			{object}	MessageResponse	"Not an admin key"
+//	@Failure		429			{object}	MessageResponse	"Too many requests"
+//	@Router			/markets [post]
+func CreateInstrument(sequencer *services.Sequencer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var instrument models.Instrument
+		if err := c.ShouldBindJSON(&instrument); err != nil {
+			fmt.Println(err.Error())
+			c.JSON(http.StatusUnprocessableEntity, InstrumentResponse{
+				Message: "Invalid request",
+			})
+			return
+		}
+
+		instrument, err := sequencer.CreateInstrument(instrument)
+		if errors.Is(err, services.ErrInstrumentExists) {
+			c.JSON(http.StatusConflict, InstrumentResponse{
+				Message: "Instrument already exists",
+			})
+			return
+		}
+		if errors.Is(err, services.ErrSequencerBusy) || errors.Is(err, services.ErrSequencerStopped) || errors.Is(err, services.ErrJournalFailed) {
+			status, message := orderErrorResponse(err)
+			c.JSON(status, InstrumentResponse{
+				Message: message,
+			})
+			return
+		}
+		if err != nil {
+			fmt.Println(err.Error())
+			c.JSON(http.StatusUnprocessableEntity, InstrumentResponse{
+				Message: "Invalid request",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, InstrumentResponse{
+			Message: "success",
+			Data: instrument,
+		})
+	}
+}
+
+// GetInstrument retrieves an instrument by its symbol
+//	@Summary		Get an instrument
+//	@Description	Returns a listed instrument with its trading rules and status.
+//	@Tags			Markets
+//	@Produce		json
+//	@Param			symbol	path		string				true	"Instrument symbol"
+//	@Success		200		{object}	InstrumentResponse	"Successfully retrieved the instrument"
+//	@Failure		404		{object}	InstrumentResponse	"Market not found"
+//	@Failure		429		{object}	MessageResponse	"Too many requests"
+//	@Router			/markets/{symbol} [get]
+func GetInstrument(markets *services.BookManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		instrument, err := markets.GetInstrument(c.Param("symbol"))
+		if err != nil {
+			status, message := orderErrorResponse(err)
+			c.JSON(status, InstrumentResponse{
+				Message: message,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, InstrumentResponse{
+			Message: "success",
+			Data: instrument,
+		})
+	}
+}
+
+// GetInstrumentsList retrieves every listed instrument
+//	@Summary		Get list of instruments
+//	@Description	Returns every listed instrument, ordered by symbol.
+//	@Tags			Markets
+//	@Produce		json
+//	@Success		200	{object}	InstrumentsResponse	"Successfully retrieved list of instruments"
+//	@Failure		429	{object}	MessageResponse	"Too many requests"
+//	@Router			/markets [get]
+func GetInstrumentsList(markets *services.BookManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, InstrumentsResponse{
+			Message: "success",
+			Data: markets.GetInstrumentList(),
+		})
+	}
+}
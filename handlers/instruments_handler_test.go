@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"order-matching/models"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateInstrument(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+
+	t.Run("It returns 200 and lists the instrument", func(t *testing.T) {
+		t.Parallel()
+		body := `{
+			"symbol": "ETH-USD",
+			"base_asset": "ETH",
+			"quote_asset": "USD",
+			"tick_size": 0.01,
+			"lot_size": 0.001,
+			"min_quantity": 0.01,
+			"max_quantity": 1000
+		}`
+
+		markets := newMarkets(t)
+		engine := gin.New()
+		engine.POST("/api/markets", CreateInstrument(markets))
+
+		req, _ := http.NewRequest(http.MethodPost, "/api/markets", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		response := new(InstrumentResponse)
+		json.Unmarshal(recorder.Body.Bytes(), response)
+		assert.Equal(t, models.InstrumentTrading, response.Data.Status)
+		assert.Equal(t, 2, len(markets.Markets().GetInstrumentList()))
+	})
+
+	t.Run("It returns 409 error for a symbol that is already listed", func(t *testing.T) {
+		t.Parallel()
+		body := `{
+			"symbol": "BTC-USD",
+			"base_asset": "BTC",
+			"quote_asset": "USD",
+			"tick_size": 0.01,
+			"lot_size": 0.01
+		}`
+
+		engine := gin.New()
+		engine.POST("/api/markets", CreateInstrument(newMarkets(t)))
+
+		req, _ := http.NewRequest(http.MethodPost, "/api/markets", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+	})
+
+	t.Run("It returns 422 error for an instrument without a tick size", func(t *testing.T) {
+		t.Parallel()
+		body := `{
+			"symbol": "ETH-USD",
+			"base_asset": "ETH",
+			"quote_asset": "USD",
+			"lot_size": 0.01
+		}`
+
+		engine := gin.New()
+		engine.POST("/api/markets", CreateInstrument(newMarkets(t)))
+
+		req, _ := http.NewRequest(http.MethodPost, "/api/markets", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	})
+}
+
+func TestGetInstrument(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+
+	t.Run("It returns a listed instrument", func(t *testing.T) {
+		t.Parallel()
+		engine := gin.New()
+		engine.GET("/api/markets/:symbol", GetInstrument(newMarkets(t).Markets()))
+
+		req, _ := http.NewRequest(http.MethodGet, "/api/markets/BTC-USD", nil)
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		response := new(InstrumentResponse)
+		json.Unmarshal(recorder.Body.Bytes(), response)
+		assert.Equal(t, "BTC", response.Data.BaseAsset)
+	})
+
+	t.Run("It returns 404 error for an unknown symbol", func(t *testing.T) {
+		t.Parallel()
+		engine := gin.New()
+		engine.GET("/api/markets/:symbol", GetInstrument(newMarkets(t).Markets()))
+
+		req, _ := http.NewRequest(http.MethodGet, "/api/markets/DOGE-USD", nil)
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
+
+func TestInstrumentsList(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.GET("/api/markets", GetInstrumentsList(newMarkets(t).Markets()))
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/markets", nil)
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	response := new(InstrumentsResponse)
+	json.Unmarshal(recorder.Body.Bytes(), response)
+	assert.Equal(t, 1, len(response.Data))
+	assert.Equal(t, "BTC-USD", response.Data[0].Symbol)
+}
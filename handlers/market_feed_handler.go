@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"net/http"
+	"order-matching/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	feedBufferSize = 1024 // updates held for a client before it is dropped
+	feedWriteTimeout = 10 * time.Second
+	feedPingInterval = 30 * time.Second
+)
+
+// The market data is public, so the feed can be opened from any origin.
+var feedUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// StreamMarketData streams the market data of a market over a WebSocket.
+//
+//	@Summary		Stream market data
+//	@Description	Opens a WebSocket that streams the market data of a market as JSON messages. The first message is a SNAPSHOT of the whole depth; it is followed by a TRADE for every trade executed and a LEVEL for every price level whose aggregate amount changed, with an amount of 0 once the level is gone. Every message carries the next sequence number, so a client that sees a gap has missed updates and should open the feed again. A client that falls behind is disconnected with close code 1013.
+//	@Tags			Market Data
+//	@Param			symbol	path	string	true	"Instrument symbol"
+//	@Success		101		{object}	services.FeedUpdate	"Switching to the WebSocket protocol"
+//	@Failure		404		{object}	OrderBookResponse	"Market not found"
+//	@Failure		429		{object}	MessageResponse	"Too many requests"
+//	@Router			/markets/{symbol}/feed [get]
+func StreamMarketData(markets *services.BookManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		market, err := markets.Market(c.Param("symbol"))
+		if err != nil {
+			status, message := orderErrorResponse(err)
+			c.JSON(status, OrderBookResponse{
+				Message: message,
+			})
+			return
+		}
+
+		conn, err := feedUpgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			// the upgrader has answered the request already
+			return
+		}
+		defer conn.Close()
+
+		snapshot, subscription := market.Feed.Subscribe(feedBufferSize)
+		defer market.Feed.Unsubscribe(subscription)
+
+		// the client sends nothing; reading notices when it goes away
+		gone := make(chan struct{})
+		go func() {
+			defer close(gone)
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		conn.SetWriteDeadline(time.Now().Add(feedWriteTimeout))
+		if err := conn.WriteJSON(snapshot); err != nil {
+			return
+		}
+
+		ping := time.NewTicker(feedPingInterval)
+		defer ping.Stop()
+		for {
+			select {
+			case update, open := <-subscription.Updates:
+				if !open {
+					message := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too far behind, subscribe again")
+					conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(feedWriteTimeout))
+					return
+				}
+				conn.SetWriteDeadline(time.Now().Add(feedWriteTimeout))
+				if err := conn.WriteJSON(update); err != nil {
+					return
+				}
+			case <-ping.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(feedWriteTimeout)); err != nil {
+					return
+				}
+			case <-gone:
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"order-matching/models"
+	"order-matching/services"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamMarketData(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+
+	t.Run("It streams a snapshot followed by the updates", func(t *testing.T) {
+		t.Parallel()
+		sequencer := initMarkets(t)
+		market, _ := sequencer.Markets().Market("BTC-USD")
+		engine := gin.New()
+		engine.GET("/api/markets/:symbol/feed", StreamMarketData(sequencer.Markets()))
+		server := httptest.NewServer(engine)
+		defer server.Close()
+
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/api/markets/BTC-USD/feed", nil)
+		assert.Nil(t, err)
+		defer conn.Close()
+
+		snapshot := new(services.FeedUpdate)
+		assert.Nil(t, conn.ReadJSON(snapshot))
+		assert.Equal(t, services.FeedSnapshot, snapshot.Type)
+		assert.Equal(t, 2, len(snapshot.Depth.Asks))
+		assert.Equal(t, 2, len(snapshot.Depth.Bids))
+
+		sequencer.CancelOrder(market, "550e8400-e29b-41d4-a716-666655443000")
+
+		update := new(services.FeedUpdate)
+		assert.Nil(t, conn.ReadJSON(update))
+		assert.Equal(t, snapshot.Sequence+1, update.Sequence)
+		assert.Equal(t, services.FeedLevel, update.Type)
+		assert.Equal(t, &models.OrderBookEntry{Price: decimal("80.0"), Liquidity: 0, Type: models.Buy}, update.Level)
+	})
+
+	t.Run("It returns 404 error for an unknown market", func(t *testing.T) {
+		t.Parallel()
+		engine := gin.New()
+		engine.GET("/api/markets/:symbol/feed", StreamMarketData(newMarkets(t).Markets()))
+
+		req, _ := http.NewRequest(http.MethodGet, "/api/markets/DOGE-USD/feed", nil)
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
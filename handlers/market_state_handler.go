@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"net/http"
+	"order-matching/models"
+	"order-matching/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MarketStateResponse struct {
+	Message string `json:"message"`
+	Data models.MarketState `json:"data"`
+}
+
+// GetMarketState retrieves the trading state of a market
+//	@Summary		Get the trading state of a market
+//	@Description	Returns whether the market is OPEN, HALTED, CLOSED or in its AUCTION, since when, and, for a halt or auction that ends on its own, until when. reason says why the market left OPEN: CIRCUIT_BREAKER when its last trade moved too far within the configured window, or MANUAL.
+//	@Tags			Markets
+//	@Produce		json
+//	@Param			symbol	path		string				true	"Instrument symbol"
+//	@Success		200		{object}	MarketStateResponse	"Successfully retrieved the trading state"
+//	@Failure		404		{object}	MarketStateResponse	"Market not found"
+//	@Failure		429		{object}	MessageResponse	"Too many requests"
+//	@Router			/markets/{symbol}/state [get]
+func GetMarketState(markets *services.BookManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		market, err := markets.Market(c.Param("symbol"))
+		if err != nil {
+			status, message := orderErrorResponse(err)
+			c.JSON(status, MarketStateResponse{
+				Message: message,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, MarketStateResponse{
+			Message: "success",
+			Data: market.State(),
+		})
+	}
+}
+
+// HaltMarket halts matching in a market
+//	@Summary		Halt a market
+//	@Description	Stops matching in the market until it is resumed. Resting orders stay in the book and can be canceled; new orders are refused or queued, as configured.
+//	@Tags			Markets
+//	@Produce		json
+//	@Security		APIKey
+//	@Param			symbol	path		string				true	"Instrument symbol"
+//	@Success		200		{object}	MarketStateResponse	"Market halted"
+//	@Failure		404		{object}	MarketStateResponse	"Market not found"
+//	@Failure		503		{object}	MarketStateResponse	"Too many commands are waiting"
+//	@Failure		401		{object}	MessageResponse	"Missing or invalid signature"
+//	@Failure		403		{object}	MessageResponse	"Not an admin key"
+//	@Failure		429		{object}	MessageResponse	"Too many requests"
+//	@Router			/markets/{symbol}/halt [post]
+func HaltMarket(sequencer *services.Sequencer) gin.HandlerFunc {
+	return changeMarketState(sequencer, sequencer.Halt)
+}
+
+// ResumeMarket reopens a halted or closed market
+//	@Summary		Resume a market
+//	@Description	Reopens a HALTED or CLOSED market, through an AUCTION if one is configured, and sends the orders queued in the meantime through matching, in the order they were placed, once it is OPEN. Their trades can trip the circuit breaker again. Returns the state of the market afterwards.
+//	@Tags			Markets
+//	@Produce		json
+//	@Security		APIKey
+//	@Param			symbol	path		string				true	"Instrument symbol"
+//	@Success		200		{object}	MarketStateResponse	"Market resumed"
+//	@Failure		404		{object}	MarketStateResponse	"Market not found"
+//	@Failure		503		{object}	MarketStateResponse	"Too many commands are waiting"
+//	@Failure		401		{object}	MessageResponse	"Missing or invalid signature"
+//	@Failure		403		{object}	MessageResponse	"Not an admin key"
+//	@Failure		429		{object}	MessageResponse	"Too many requests"
+//	@Router			/markets/{symbol}/resume [post]
+func ResumeMarket(sequencer *services.Sequencer) gin.HandlerFunc {
+	return changeMarketState(sequencer, sequencer.Resume)
+}
+
+// CloseMarket closes a market
+//	@Summary		Close a market
+//	@Description	Refuses every new order until the market is resumed. Resting orders stay in the book and can be canceled.
+//	@Tags			Markets
+//	@Produce		json
+//	@Security		APIKey
+//	@Param			symbol	path		string				true	"Instrument symbol"
+//	@Success		200		{object}	MarketStateResponse	"Market closed"
+//	@Failure		404		{object}	MarketStateResponse	"Market not found"
+//	@Failure		503		{object}	MarketStateResponse	"Too many commands are waiting"
+//	@Failure		401		{object}	MessageResponse	"Missing or invalid signature"
+//	@Failure		403		{object}	MessageResponse	"Not an admin key"
+//	@Failure		429		{object}	MessageResponse	"Too many requests"
+//	@Router			/markets/{symbol}/close [post]
+func CloseMarket(sequencer *services.Sequencer) gin.HandlerFunc {
+	return changeMarketState(sequencer, sequencer.Close)
+}
+
+func changeMarketState(sequencer *services.Sequencer, change func(*services.Market) (models.MarketState, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		market, err := sequencer.Markets().Market(c.Param("symbol"))
+		if err != nil {
+			status, message := orderErrorResponse(err)
+			c.JSON(status, MarketStateResponse{
+				Message: message,
+			})
+			return
+		}
+
+		state, err := change(market)
+		if err != nil {
+			status, message := orderErrorResponse(err)
+			c.JSON(status, MarketStateResponse{
+				Message: message,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, MarketStateResponse{
+			Message: "success",
+			Data: state,
+		})
+	}
+}
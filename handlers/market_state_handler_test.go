@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"order-matching/models"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarketState(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+
+	newEngine := func(t *testing.T) *gin.Engine {
+		sequencer := newMarkets(t)
+		engine := gin.New()
+		engine.GET("/api/markets/:symbol/state", GetMarketState(sequencer.Markets()))
+		engine.POST("/api/markets/:symbol/halt", HaltMarket(sequencer))
+		engine.POST("/api/markets/:symbol/resume", ResumeMarket(sequencer))
+		engine.POST("/api/markets/:symbol/close", CloseMarket(sequencer))
+		engine.POST("/api/markets/:symbol/orders", asAccount("account-1", false), CreateOrder(sequencer))
+		return engine
+	}
+	send := func(engine *gin.Engine, method string, path string, body string) (*httptest.ResponseRecorder, *MarketStateResponse) {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		response := new(MarketStateResponse)
+		json.Unmarshal(recorder.Body.Bytes(), response)
+		return recorder, response
+	}
+	order := `{
+		"uuid": "550e8400-e29b-41d4-a716-446655440900",
+		"action": "BUY",
+		"price": 100.0,
+		"amount": 1.0
+	}`
+
+	t.Run("It returns an open market", func(t *testing.T) {
+		t.Parallel()
+
+		recorder, response := send(newEngine(t), http.MethodGet, "/api/markets/BTC-USD/state", "")
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, models.StateOpen, response.Data.State)
+	})
+
+	t.Run("It halts the market and refuses orders with 409 until it is resumed", func(t *testing.T) {
+		t.Parallel()
+		engine := newEngine(t)
+
+		recorder, response := send(engine, http.MethodPost, "/api/markets/BTC-USD/halt", "")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, models.StateHalted, response.Data.State)
+		assert.Equal(t, "MANUAL", response.Data.Reason)
+
+		_, response = send(engine, http.MethodGet, "/api/markets/BTC-USD/state", "")
+		assert.Equal(t, models.StateHalted, response.Data.State)
+
+		recorder, response = send(engine, http.MethodPost, "/api/markets/BTC-USD/orders", order)
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+		assert.Equal(t, "Market is halted", response.Message)
+
+		recorder, response = send(engine, http.MethodPost, "/api/markets/BTC-USD/resume", "")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, models.StateOpen, response.Data.State)
+
+		recorder, _ = send(engine, http.MethodPost, "/api/markets/BTC-USD/orders", order)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+
+	t.Run("It closes the market and refuses orders with 409", func(t *testing.T) {
+		t.Parallel()
+		engine := newEngine(t)
+
+		recorder, response := send(engine, http.MethodPost, "/api/markets/BTC-USD/close", "")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, models.StateClosed, response.Data.State)
+
+		recorder, response = send(engine, http.MethodPost, "/api/markets/BTC-USD/orders", order)
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+		assert.Equal(t, "Market is closed", response.Message)
+	})
+
+	t.Run("It returns 404 error for an unknown symbol", func(t *testing.T) {
+		t.Parallel()
+
+		recorder, _ := send(newEngine(t), http.MethodPost, "/api/markets/DOGE-USD/halt", "")
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
@@ -1,147 +1,394 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"order-matching/models"
 	"order-matching/services"
 	"strconv"
-	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-var (
-	existingUUIDs  = make(map[string]struct{})
-	mutex  sync.Mutex
-)
-
 type Response struct {
 	Message string `json:"message"`
 	Data []models.Order `json:"data"`
 }
 
+type OrderResponse struct {
+	Message string `json:"message"`
+	Data models.Order `json:"data"`
+}
+
+// OrderDetails is an order together with trades it took part in.
+type OrderDetails struct {
+	Order models.Order `json:"order"`
+	Fills []models.Trade `json:"fills"`
+}
+
+type OrderDetailsResponse struct {
+	Message string `json:"message"`
+	Reason string `json:"reason,omitempty" example:"MAX_NOTIONAL"` // the risk check an order or amendment failed
+	Data OrderDetails `json:"data"`
+}
+
 type OrderBookResponse struct {
+	Message string `json:"message"`
 	Data []models.OrderBookEntry `json:"data"`
 }
 
-// CreateOrder places a new order in the order book
+// CreateOrder places a new order in the order book of a market
 //	@Summary		Create a new order
-//	@Description	Places a buy or sell order in the order book and returns matched orders if available.
+//	@Description	Places a buy or sell order in the order book of a market. Prices must be a multiple of the instrument's tick size and amounts a multiple of its lot size, within its quantity limits. The order is matched against the opposite side with price-time priority and may be partially filled. Depending on its time in force the unfilled remainder rests in the book (GTC, GTD until expire_at, DAY until the session close) or is canceled (IOC); a FOK order is filled completely or not at all. STOP and STOP_LIMIT orders wait until the last trade price reaches their stop_price and then trade as a MARKET or LIMIT order. The order belongs to the account of the API key and holds the funds of that account until it trades or closes: the amount of the base asset to sell, or the quote asset to buy at its price. A MARKET buy holds what it would cost to fill right now; a STOP buy, which has no price to hold funds for, is refused. An order that fails the pre-trade risk checks is recorded as REJECTED and returned with 422, with the code of the check in reason and in the reject_reason of the order: MAX_QUANTITY, MAX_NOTIONAL, FAT_FINGER, PRICE_COLLAR, MAX_OPEN_ORDERS or MAX_POSITION. A CLOSED market refuses every order with 409. While the market is HALTED or in its AUCTION, an order that cannot rest in the book (MARKET, STOP, IOC or FOK) is refused with 409 and any other order is accepted but waits, without trading, until the market reopens, when the waiting orders that cross trade at a single uncross price; unless the service queues orders during halts, a HALTED market refuses them all with 409. Returns the accepted order with its status and the trades executed, one per resting order traded against.
 //	@Tags			Orders
 //	@Accept			json
 //	@Produce		json
-//	@Param			order	body		models.Order	true	"Order details"	Example({ "uuid": "550e8400-e29b-41d4-a716-446655440000", "action": "BUY", "price": 100.5, "amount": 2 })
-//	@Success		200		{object}	Response		"Order successfully placed"
-//	@Failure		422		{object}	Response		"Invalid request payload"
-//	@Failure		409		{object}	Response		"Duplicate order detected"
-//	@Router			/orders [post]
-func CreateOrder(orderBook *services.OrderBook) gin.HandlerFunc {
+//	@Security		APIKey
+//	@Param			symbol	path		string			true	"Instrument symbol"
+//	@Param			order	body		models.Order	true	"Order details"	Example({ "uuid": "550e8400-e29b-41d4-a716-446655440000", "action": "BUY", "price": "100.5", "amount": "2", "time_in_force": "GTC" })
+//	@Success		200		{object}	OrderDetailsResponse	"Order successfully placed"
+//	@Failure		422		{object}	OrderDetailsResponse	"Invalid request payload, or refused by the risk checks"
+//	@Failure		404		{object}	OrderDetailsResponse	"Market not found"
+//	@Failure		409		{object}	OrderDetailsResponse	"Duplicate order detected, market closed or halted, or insufficient funds"
+//	@Failure		503		{object}	OrderDetailsResponse	"Too many orders are waiting"
+//	@Failure		401		{object}	MessageResponse	"Missing or invalid signature"
+//	@Failure		429		{object}	MessageResponse	"Too many requests"
+//	@Router			/markets/{symbol}/orders [post]
+func CreateOrder(sequencer *services.Sequencer) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		market, err := sequencer.Markets().Market(c.Param("symbol"))
+		if err != nil {
+			status, message := orderErrorResponse(err)
+			c.JSON(status, OrderDetailsResponse{
+				Message: message,
+			})
+			return
+		}
+
 		var order models.Order
-		if err := c.ShouldBindJSON(&order); err != nil {
+		err = c.ShouldBindJSON(&order)
+		if err == nil {
+			err = order.Validate(time.Now().UTC())
+		}
+		if err == nil {
+			err = market.Instrument.Validate(&order)
+		}
+		if err != nil {
 			fmt.Println(err.Error())
-			c.JSON(http.StatusUnprocessableEntity, Response{
+			c.JSON(http.StatusUnprocessableEntity, OrderDetailsResponse{
 				Message: "Invalid request",
-				Data: []models.Order{},
 			})
 			return
 		}
+		order.Symbol = market.Instrument.Symbol
+		order.AccountID = authenticatedAccount(c)
 
-		mutex.Lock()
-		defer mutex.Unlock()
+		trades, err := sequencer.PlaceOrder(market, &order)
+		if reason := riskReason(err); reason != "" {
+			// the order was recorded as rejected
+			status, message := orderErrorResponse(err)
+			c.JSON(status, OrderDetailsResponse{
+				Message: message,
+				Reason: reason,
+				Data: OrderDetails{
+					Order: order,
+					Fills: []models.Trade{},
+				},
+			})
+			return
+		}
+		if err != nil {
+			status, message := orderErrorResponse(err)
+			c.JSON(status, OrderDetailsResponse{
+				Message: message,
+			})
+			return
+		}
+		if trades == nil {
+			trades = []models.Trade{}
+		}
 
-		if _, exists := existingUUIDs[order.ID]; exists {
-			c.JSON(http.StatusConflict, Response{
-				Message: "This order has been processed already.",
-				Data: []models.Order{},
+		c.JSON(http.StatusOK, OrderDetailsResponse{
+			Message: "success",
+			Data: OrderDetails{
+				Order: order,
+				Fills: trades,
+			},
+		})
+	}
+}
+
+// GetOrder retrieves an order by its UUID
+//	@Summary		Get an order
+//	@Description	Returns any order ever accepted, including orders no longer in the book, with its status, filled and remaining amount and the trades it took part in. Orders of other accounts are not found, unless the key is an admin key.
+//	@Tags			Orders
+//	@Produce		json
+//	@Security		APIKey
+//	@Param			symbol	path		string					true	"Instrument symbol"
+//	@Param			uuid	path		string					true	"Order UUID"
+//	@Success		200		{object}	OrderDetailsResponse	"Successfully retrieved the order"
+//	@Failure		404		{object}	OrderDetailsResponse	"Market or order not found"
+//	@Failure		401		{object}	MessageResponse	"Missing or invalid signature"
+//	@Failure		429		{object}	MessageResponse	"Too many requests"
+//	@Router			/markets/{symbol}/orders/{uuid} [get]
+func GetOrder(markets *services.BookManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		market, err := markets.Market(c.Param("symbol"))
+		if err != nil {
+			status, message := orderErrorResponse(err)
+			c.JSON(status, OrderDetailsResponse{
+				Message: message,
 			})
 			return
 		}
 
-		existingUUIDs[order.ID] = struct{}{}
+		order, exists := market.OrderHistory.GetOrder(c.Param("uuid"))
+		if !exists || !authorizedFor(c, order.AccountID) {
+			c.JSON(http.StatusNotFound, OrderDetailsResponse{
+				Message: "Order not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, OrderDetailsResponse{
+			Message: "success",
+			Data: OrderDetails{
+				Order: order,
+				Fills: market.TradeHistory.GetOrderTrades(order.ID),
+			},
+		})
+	}
+}
+
+// CancelOrder removes a resting order from the order book
+//	@Summary		Cancel an order
+//	@Description	Removes a resting order from the order book, a stop order still waiting for its stop price, or an order waiting for a halted market to reopen, and returns it with status CANCELED. Orders of other accounts are not found, unless the key is an admin key.
+//	@Tags			Orders
+//	@Produce		json
+//	@Security		APIKey
+//	@Param			symbol	path		string			true	"Instrument symbol"
+//	@Param			uuid	path		string			true	"Order UUID"
+//	@Success		200		{object}	OrderResponse	"Order successfully canceled"
+//	@Failure		404		{object}	OrderResponse	"Market or order not found"
+//	@Failure		409		{object}	OrderResponse	"Order is no longer open"
+//	@Failure		503		{object}	OrderResponse	"Too many orders are waiting"
+//	@Failure		401		{object}	MessageResponse	"Missing or invalid signature"
+//	@Failure		429		{object}	MessageResponse	"Too many requests"
+//	@Router			/markets/{symbol}/orders/{uuid} [delete]
+func CancelOrder(sequencer *services.Sequencer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		market, err := sequencer.Markets().Market(c.Param("symbol"))
+		if err != nil {
+			status, message := orderErrorResponse(err)
+			c.JSON(status, OrderResponse{
+				Message: message,
+			})
+			return
+		}
 
-		matchedOrders := orderBook.PlaceOrder(&order)
-		if matchedOrders == nil {
-			matchedOrders = []models.Order{}
+		if !ownsOrder(c, market, c.Param("uuid")) {
+			c.JSON(http.StatusNotFound, OrderResponse{
+				Message: "Order not found",
+			})
+			return
 		}
 
-		c.JSON(http.StatusOK, Response{
+		order, err := sequencer.CancelOrder(market, c.Param("uuid"))
+		if err != nil {
+			status, message := orderErrorResponse(err)
+			c.JSON(status, OrderResponse{
+				Message: message,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, OrderResponse{
+			Message: "success",
+			Data: order,
+		})
+	}
+}
+
+// AmendOrder changes the price and/or amount of a resting order
+//	@Summary		Amend an order
+//	@Description	Changes the price and/or remaining amount of a resting order. Reducing the amount keeps the order's queue priority; changing the price or increasing the amount sends it to the back of the queue, where it may trade. An amendment that needs more funds than the order holds takes them from the available balance of its account. An amendment that takes the order's filled plus remaining amount outside the instrument's quantity limits is refused with 422, and one that fails the pre-trade risk checks with 422 and the code of the check in reason, and the order is left as it was. Amendments are refused with 409 while the market is not OPEN. Returns the amended order and the trades executed by the amendment. Orders of other accounts are not found, unless the key is an admin key.
+//	@Tags			Orders
+//	@Accept			json
+//	@Produce		json
+//	@Security		APIKey
+//	@Param			symbol		path		string					true	"Instrument symbol"
+//	@Param			uuid		path		string					true	"Order UUID"
+//	@Param			amendment	body		models.OrderAmendment	true	"Fields to change"	Example({ "price": "101.0", "amount": "1.5" })
+//	@Success		200			{object}	OrderDetailsResponse	"Order successfully amended"
+//	@Failure		422			{object}	OrderDetailsResponse	"Invalid request payload, outside the quantity limits, or refused by the risk checks"
+//	@Failure		404			{object}	OrderDetailsResponse	"Market or order not found"
+//	@Failure		409			{object}	OrderDetailsResponse	"Order is no longer open, is waiting for its stop price or needs more funds than available, or market not open"
+//	@Failure		503			{object}	OrderDetailsResponse	"Too many orders are waiting"
+//	@Failure		401			{object}	MessageResponse	"Missing or invalid signature"
+//	@Failure		429			{object}	MessageResponse	"Too many requests"
+//	@Router			/markets/{symbol}/orders/{uuid} [patch]
+func AmendOrder(sequencer *services.Sequencer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		market, err := sequencer.Markets().Market(c.Param("symbol"))
+		if err != nil {
+			status, message := orderErrorResponse(err)
+			c.JSON(status, OrderDetailsResponse{
+				Message: message,
+			})
+			return
+		}
+
+		if !ownsOrder(c, market, c.Param("uuid")) {
+			c.JSON(http.StatusNotFound, OrderDetailsResponse{
+				Message: "Order not found",
+			})
+			return
+		}
+
+		var amendment models.OrderAmendment
+		if err := c.ShouldBindJSON(&amendment); err != nil || (amendment.Price == nil && amendment.Amount == nil) || market.Instrument.ValidateAmendment(amendment) != nil {
+			c.JSON(http.StatusUnprocessableEntity, OrderDetailsResponse{
+				Message: "Invalid request",
+			})
+			return
+		}
+
+		order, trades, err := sequencer.AmendOrder(market, c.Param("uuid"), amendment)
+		if err != nil {
+			status, message := orderErrorResponse(err)
+			c.JSON(status, OrderDetailsResponse{
+				Message: message,
+				Reason: riskReason(err),
+			})
+			return
+		}
+		if trades == nil {
+			trades = []models.Trade{}
+		}
+
+		c.JSON(http.StatusOK, OrderDetailsResponse{
 			Message: "success",
-			Data: matchedOrders,
+			Data: OrderDetails{
+				Order: order,
+				Fills: trades,
+			},
 		})
 	}
 }
 
-// GetOrderBook retrieves the current state of the order book.
+// GetOrderBook retrieves the current state of the order book of a market.
 //
 //	@Summary		Get order book
 //	@Description	Returns a list of buy and sell orders with their price and liquidity.
 //	@Tags			Orders
 //	@Produce		json
-//	@Param			limit	query		int	false	"Number of orders to retrieve (default is 10)"
+//	@Param			symbol	path		string	true	"Instrument symbol"
+//	@Param			limit	query		int		false	"Number of orders to retrieve (default is 10)"
 //	@Success		200		{object}	OrderBookResponse	"Successfully retrieved order book"
-//	@Router			/orderbook [get]
+//	@Failure		404		{object}	OrderBookResponse	"Market not found"
+//	@Failure		429		{object}	MessageResponse	"Too many requests"
+//	@Router			/markets/{symbol}/orderbook [get]
 //	@Example		{json} Success-Response
 //	{
+//	  "message": "success",
 //	  "data": [
 //	    {
-//	      "price": 100.0,
-//	      "liquidity": 5.0,
+//	      "price": "100",
+//	      "liquidity": "5",
 //	      "type": "BUY"
 //	    },
 //	    {
-//	      "price": 99.5,
-//	      "liquidity": 3.0,
+//	      "price": "99.5",
+//	      "liquidity": "3",
 //	      "type": "SELL"
 //	    }
 //	  ]
 //	}
-func GetOrderBook(orderBook *services.OrderBook) gin.HandlerFunc {
+func GetOrderBook(markets *services.BookManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		market, err := markets.Market(c.Param("symbol"))
+		if err != nil {
+			status, message := orderErrorResponse(err)
+			c.JSON(status, OrderBookResponse{
+				Message: message,
+				Data: []models.OrderBookEntry{},
+			})
+			return
+		}
+
 		limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
 		if err != nil || limit <= 0 {
 			limit = 10
 		}
 
-		result := orderBook.GetOrderBook(int(limit))
+		result := market.GetOrderBook(int(limit))
 
 		c.JSON(http.StatusOK, OrderBookResponse{
+			Message: "success",
 			Data: result,
 		})
 	}
 }
 
-// GetOrdersList retrieves a paginated list of all orders.
+// GetOrdersList retrieves a paginated list of all orders of a market.
 //
 //	@Summary		Get list of orders
-//	@Description	Returns a paginated list of all orders placed in the order book.
+//	@Description	Returns a paginated list of the orders of the account of the API key ever accepted in a market, in the order they were accepted, with their current status. An admin key lists the orders of every account.
 //	@Tags			Orders
 //	@Produce		json
-//	@Param			page		query	int	false	"Page number (default is 1)"
-//	@Param			page_size	query	int	false	"Number of orders per page (default is 10)"
+//	@Security		APIKey
+//	@Param			symbol		path	string	true	"Instrument symbol"
+//	@Param			page		query	int		false	"Page number (default is 1)"
+//	@Param			page_size	query	int		false	"Number of orders per page (default is 10)"
 //	@Success		200			{object}	Response	"Successfully retrieved list of orders"
-//	@Router			/orders [get]
+//	@Failure		404			{object}	Response	"Market not found"
+//	@Failure		401			{object}	MessageResponse	"Missing or invalid signature"
+//	@Failure		429			{object}	MessageResponse	"Too many requests"
+//	@Router			/markets/{symbol}/orders [get]
 //	@Example		{json} Success-Response
 //	{
 //	  "message": "success",
 //	  "data": [
 //	    {
 //	      "uuid": "550e8400-e29b-41d4-a716-446655440000",
+//	      "symbol": "BTC-USD",
 //	      "action": "BUY",
-//	      "price": 100.0,
-//	      "amount": 2.5
+//	      "price": "100",
+//	      "amount": "2.5",
+//	      "status": "PARTIALLY_FILLED",
+//	      "filled_amount": "1",
+//	      "remaining_amount": "1.5",
+//	      "created_at": "2025-03-01T10:00:00Z",
+//	      "updated_at": "2025-03-01T10:05:00Z"
 //	    },
 //	    {
 //	      "uuid": "550e8400-e29b-41d4-a716-446655440001",
+//	      "symbol": "BTC-USD",
 //	      "action": "SELL",
-//	      "price": 99.5,
-//	      "amount": 1.0
+//	      "price": "99.5",
+//	      "amount": "1",
+//	      "status": "FILLED",
+//	      "filled_amount": "1",
+//	      "remaining_amount": "0",
+//	      "created_at": "2025-03-01T10:05:00Z",
+//	      "updated_at": "2025-03-01T10:05:00Z"
 //	    }
 //	  ]
 //	}
-func GetOrdersList(orderBook *services.OrderBook) gin.HandlerFunc {
+func GetOrdersList(markets *services.BookManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		market, err := markets.Market(c.Param("symbol"))
+		if err != nil {
+			status, message := orderErrorResponse(err)
+			c.JSON(status, Response{
+				Message: message,
+				Data: []models.Order{},
+			})
+			return
+		}
+
 		page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
 		if err != nil || page <= 0 {
 			page = 1
@@ -152,12 +399,75 @@ func GetOrdersList(orderBook *services.OrderBook) gin.HandlerFunc {
 			pageSize = 10
 		}
 
-		orders := orderBook.GetOrderList(page, pageSize)
-		fmt.Println("orders", orders)
+		var orders []models.Order
+		if isAdmin(c) {
+			orders = market.OrderHistory.GetOrderList(page, pageSize)
+		} else {
+			orders = market.OrderHistory.GetAccountOrderList(authenticatedAccount(c), page, pageSize)
+		}
 
 		c.JSON(http.StatusOK, Response{
 			Message: "success",
 			Data: orders,
 		})
 	}
-}
\ No newline at end of file
+}
+
+// ownsOrder reports whether the request may act on an order of the market:
+// one of its own account, or any with an admin key.
+func ownsOrder(c *gin.Context, market *services.Market, id string) bool {
+	order, exists := market.OrderHistory.GetOrder(id)
+	return exists && authorizedFor(c, order.AccountID)
+}
+
+// riskReason returns the code of the risk check an error reports, or an empty
+// string for any other error.
+func riskReason(err error) string {
+	var refused *services.RiskError
+	if errors.As(err, &refused) {
+		return refused.Reason
+	}
+
+	return ""
+}
+
+// orderErrorResponse maps an order book error to the HTTP status and message
+// returned to the client.
+func orderErrorResponse(err error) (int, string) {
+	switch {
+	case errors.Is(err, services.ErrInstrumentNotFound):
+		return http.StatusNotFound, "Market not found"
+	case errors.Is(err, services.ErrDuplicateOrder):
+		return http.StatusConflict, "This order has been processed already."
+	case errors.Is(err, services.ErrOrderNotFound):
+		return http.StatusNotFound, "Order not found"
+	case errors.Is(err, services.ErrOrderNotOpen):
+		return http.StatusConflict, "Order is no longer open"
+	case errors.Is(err, services.ErrOrderPendingTrigger):
+		return http.StatusConflict, "Order is waiting for its stop price"
+	case errors.Is(err, services.ErrOrderQueued):
+		return http.StatusConflict, "Order is waiting for the market to reopen"
+	case errors.Is(err, services.ErrMarketClosed):
+		return http.StatusConflict, "Market is closed"
+	case errors.Is(err, services.ErrMarketHalted):
+		return http.StatusConflict, "Market is halted"
+	case errors.Is(err, services.ErrInsufficientFunds):
+		return http.StatusConflict, "Insufficient funds"
+	case errors.Is(err, services.ErrUnboundedCost):
+		return http.StatusUnprocessableEntity, "A STOP buy order cannot be funded, place a STOP_LIMIT order instead"
+	case riskReason(err) != "":
+		return http.StatusUnprocessableEntity, "Order refused by the risk checks"
+	case errors.Is(err, models.ErrDecimalOverflow):
+		return http.StatusUnprocessableEntity, "Amount is too large"
+	case errors.Is(err, models.ErrQuantityLimits):
+		return http.StatusUnprocessableEntity, "Amount is outside the instrument's quantity limits"
+	case errors.Is(err, services.ErrSequencerBusy):
+		return http.StatusServiceUnavailable, "Too many orders are waiting, please try again"
+	case errors.Is(err, services.ErrJournalFailed):
+		return http.StatusServiceUnavailable, "Commands cannot be journaled, the service needs a restart"
+	case errors.Is(err, services.ErrSequencerStopped):
+		return http.StatusServiceUnavailable, "The service is shutting down"
+	default:
+		return http.StatusInternalServerError, err.Error()
+	}
+}
@@ -2,12 +2,14 @@ package handlers
 
 import (
 	"bytes"
-	"container/heap"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"order-matching/models"
 	"order-matching/services"
+	"sync"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -29,9 +31,9 @@ func TestCreateOrder(t *testing.T) {
 		}`
 
 		engine := gin.New()
-		engine.POST("/api/orders", CreateOrder(services.NewOrderBook()))
+		engine.POST("/api/markets/:symbol/orders", asAccount("account-1", false), CreateOrder(newMarkets(t)))
 
-		req, _ := http.NewRequest(http.MethodPost, "/api/orders", bytes.NewBufferString(body))
+		req, _ := http.NewRequest(http.MethodPost, "/api/markets/BTC-USD/orders", bytes.NewBufferString(body))
 		req.Header.Set("Content-Type", "application/json")
 
 		w := httptest.NewRecorder()
@@ -39,7 +41,7 @@ func TestCreateOrder(t *testing.T) {
 
 		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 
-		response := new(Response)
+		response := new(OrderDetailsResponse)
 		json.Unmarshal(w.Body.Bytes(), response)
 		assert.Equal(t, "Invalid request", response.Message)
 	})
@@ -50,9 +52,9 @@ func TestCreateOrder(t *testing.T) {
 		}`
 
 		engine := gin.New()
-		engine.POST("/api/orders", CreateOrder(services.NewOrderBook()))
+		engine.POST("/api/markets/:symbol/orders", asAccount("account-1", false), CreateOrder(newMarkets(t)))
 
-		req, _ := http.NewRequest(http.MethodPost, "/api/orders", bytes.NewBufferString(body))
+		req, _ := http.NewRequest(http.MethodPost, "/api/markets/BTC-USD/orders", bytes.NewBufferString(body))
 		req.Header.Set("Content-Type", "application/json")
 
 		w := httptest.NewRecorder()
@@ -60,7 +62,7 @@ func TestCreateOrder(t *testing.T) {
 
 		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 		
-		response := new(Response)
+		response := new(OrderDetailsResponse)
 		json.Unmarshal(w.Body.Bytes(), response)
 		assert.Equal(t, "Invalid request", response.Message)
 	})
@@ -75,9 +77,9 @@ func TestCreateOrder(t *testing.T) {
 		}`
 
 		engine := gin.New()
-		engine.POST("/api/orders", CreateOrder(services.NewOrderBook()))
+		engine.POST("/api/markets/:symbol/orders", asAccount("account-1", false), CreateOrder(newMarkets(t)))
 
-		req, _ := http.NewRequest(http.MethodPost, "/api/orders", bytes.NewBufferString(body))
+		req, _ := http.NewRequest(http.MethodPost, "/api/markets/BTC-USD/orders", bytes.NewBufferString(body))
 		req.Header.Set("Content-Type", "application/json")
 
 		w := httptest.NewRecorder()
@@ -85,7 +87,7 @@ func TestCreateOrder(t *testing.T) {
 
 		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 		
-		response := new(Response)
+		response := new(OrderDetailsResponse)
 		json.Unmarshal(w.Body.Bytes(), response)
 		assert.Equal(t, "Invalid request", response.Message)
 	})
@@ -100,16 +102,16 @@ func TestCreateOrder(t *testing.T) {
 		}`
 
 		engine := gin.New()
-		engine.POST("/api/orders", CreateOrder(services.NewOrderBook()))
+		engine.POST("/api/markets/:symbol/orders", asAccount("account-1", false), CreateOrder(newMarkets(t)))
 
-		req, _ := http.NewRequest(http.MethodPost, "/api/orders", bytes.NewBufferString(body))
+		req, _ := http.NewRequest(http.MethodPost, "/api/markets/BTC-USD/orders", bytes.NewBufferString(body))
 		req.Header.Set("Content-Type", "application/json")
 
 		recorder := httptest.NewRecorder()
 		engine.ServeHTTP(recorder, req)
 
 		//send the same request again
-		newReq, _ := http.NewRequest(http.MethodPost, "/api/orders", bytes.NewBufferString(body))
+		newReq, _ := http.NewRequest(http.MethodPost, "/api/markets/BTC-USD/orders", bytes.NewBufferString(body))
 		newReq.Header.Set("Content-Type", "application/json")
 
 		newRecorder := httptest.NewRecorder()
@@ -117,7 +119,7 @@ func TestCreateOrder(t *testing.T) {
 
 		assert.Equal(t, http.StatusConflict, newRecorder.Code)
 		
-		response := new(Response)
+		response := new(OrderDetailsResponse)
 		json.Unmarshal(newRecorder.Body.Bytes(), response)
 		assert.Equal(t, "This order has been processed already.", response.Message)
 	})
@@ -132,9 +134,9 @@ func TestCreateOrder(t *testing.T) {
 		}`
 
 		engine := gin.New()
-		engine.POST("/api/orders", CreateOrder(services.NewOrderBook()))
+		engine.POST("/api/markets/:symbol/orders", asAccount("account-1", false), CreateOrder(newMarkets(t)))
 
-		req, _ := http.NewRequest(http.MethodPost, "/api/orders", bytes.NewBufferString(body))
+		req, _ := http.NewRequest(http.MethodPost, "/api/markets/BTC-USD/orders", bytes.NewBufferString(body))
 		req.Header.Set("Content-Type", "application/json")
 
 		recorder := httptest.NewRecorder()
@@ -142,11 +144,12 @@ func TestCreateOrder(t *testing.T) {
 
 		assert.Equal(t, http.StatusOK, recorder.Code)
 
-		response := new(Response)
+		response := new(OrderDetailsResponse)
 		err := json.Unmarshal(recorder.Body.Bytes(), response)
 		assert.Nil(t, err)
 		assert.Equal(t, "success", response.Message)
-		assert.Equal(t, []models.Order{}, response.Data)
+		assert.Equal(t, []models.Trade{}, response.Data.Fills)
+		assert.Equal(t, models.StatusNew, response.Data.Order.Status)
 	})
 
 	t.Run("It returns 200 for sell order submission when there are no buy orders", func(t *testing.T) {
@@ -159,9 +162,9 @@ func TestCreateOrder(t *testing.T) {
 		}`
 
 		engine := gin.New()
-		engine.POST("/api/orders", CreateOrder(services.NewOrderBook()))
+		engine.POST("/api/markets/:symbol/orders", asAccount("account-1", false), CreateOrder(newMarkets(t)))
 
-		req, _ := http.NewRequest(http.MethodPost, "/api/orders", bytes.NewBufferString(body))
+		req, _ := http.NewRequest(http.MethodPost, "/api/markets/BTC-USD/orders", bytes.NewBufferString(body))
 		req.Header.Set("Content-Type", "application/json")
 
 		recorder := httptest.NewRecorder()
@@ -169,24 +172,501 @@ func TestCreateOrder(t *testing.T) {
 
 		assert.Equal(t, http.StatusOK, recorder.Code)
 
-		response := new(Response)
+		response := new(OrderDetailsResponse)
+		err := json.Unmarshal(recorder.Body.Bytes(), response)
+		assert.Nil(t, err)
+		assert.Equal(t, "success", response.Message)
+		assert.Equal(t, []models.Trade{}, response.Data.Fills)
+		assert.Equal(t, models.StatusNew, response.Data.Order.Status)
+	})
+
+	t.Run("It returns 200 with the order status and fills when the order crosses the book", func(t *testing.T) {
+		t.Parallel()
+		body := `{
+			"uuid": "550e8400-e29b-41d4-a716-646655440400",
+			"action": "BUY",
+			"price": 100.0,
+			"amount": 5.0
+		}`
+
+		engine := gin.New()
+		engine.POST("/api/markets/:symbol/orders", asAccount("account-1", false), CreateOrder(initMarkets(t)))
+
+		req, _ := http.NewRequest(http.MethodPost, "/api/markets/BTC-USD/orders", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		response := new(OrderDetailsResponse)
 		err := json.Unmarshal(recorder.Body.Bytes(), response)
 		assert.Nil(t, err)
 		assert.Equal(t, "success", response.Message)
-		assert.Equal(t, []models.Order{}, response.Data)
+		assert.Equal(t, models.StatusFilled, response.Data.Order.Status)
+		assert.Equal(t, 3, len(response.Data.Fills))
+		makers := []string{}
+		amounts := []models.Decimal{}
+		for _, trade := range response.Data.Fills {
+			makers = append(makers, trade.MakerOrderID)
+			amounts = append(amounts, trade.Amount)
+			assert.Equal(t, "550e8400-e29b-41d4-a716-646655440400", trade.TakerOrderID)
+			assert.Equal(t, models.Buy, trade.AggressorSide)
+			assert.Equal(t, decimal("100.0"), trade.Price)
+		}
+		assert.Equal(t, []string{
+			"550e8400-e29b-41d4-a716-77755442000",
+			"550e8400-e29b-41d4-a716-77755442001",
+			"550e8400-e29b-41d4-a716-77755442002",
+		}, makers)
+		assert.Equal(t, []models.Decimal{decimal("2.0"), decimal("2.0"), decimal("1.0")}, amounts)
+	})
+}
+
+func TestCreateOrder_Instrument(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+
+	send := func(sequencer *services.Sequencer, symbol string, body string) *httptest.ResponseRecorder {
+		engine := gin.New()
+		engine.POST("/api/markets/:symbol/orders", asAccount("account-1", false), CreateOrder(sequencer))
+
+		req, _ := http.NewRequest(http.MethodPost, "/api/markets/"+symbol+"/orders", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		return recorder
+	}
+
+	t.Run("It places the order in the market of the path", func(t *testing.T) {
+		t.Parallel()
+		recorder := send(initMarkets(t), "BTC-USD", `{
+			"uuid": "550e8400-e29b-41d4-a716-646655440800",
+			"action": "BUY",
+			"price": 100.0,
+			"amount": 1.0
+		}`)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		response := new(OrderDetailsResponse)
+		json.Unmarshal(recorder.Body.Bytes(), response)
+		assert.Equal(t, "BTC-USD", response.Data.Order.Symbol)
+		assert.Equal(t, "BTC-USD", response.Data.Fills[0].Symbol)
+	})
+
+	t.Run("It returns 404 error for an unknown market", func(t *testing.T) {
+		t.Parallel()
+		recorder := send(newMarkets(t), "DOGE-USD", `{
+			"uuid": "550e8400-e29b-41d4-a716-646655440801",
+			"action": "BUY",
+			"price": 100.0,
+			"amount": 1.0
+		}`)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("It returns 422 error for a price off the tick size", func(t *testing.T) {
+		t.Parallel()
+		recorder := send(newMarkets(t), "BTC-USD", `{
+			"uuid": "550e8400-e29b-41d4-a716-646655440802",
+			"action": "BUY",
+			"price": 100.005,
+			"amount": 1.0
+		}`)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	})
+
+	t.Run("It returns 422 error for an amount off the lot size", func(t *testing.T) {
+		t.Parallel()
+		recorder := send(newMarkets(t), "BTC-USD", `{
+			"uuid": "550e8400-e29b-41d4-a716-646655440803",
+			"action": "BUY",
+			"price": 100.0,
+			"amount": 1.005
+		}`)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	})
+
+	t.Run("It returns 422 error for an order whose notional overflows", func(t *testing.T) {
+		t.Parallel()
+		recorder := send(newMarkets(t), "BTC-USD", `{
+			"uuid": "550e8400-e29b-41d4-a716-646655440806",
+			"action": "BUY",
+			"price": "90000000000.0",
+			"amount": "2.0"
+		}`)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	})
+
+	t.Run("It returns 409 error when the market is suspended", func(t *testing.T) {
+		t.Parallel()
+		markets := services.NewBookManager(0)
+		markets.CreateInstrument(models.Instrument{Symbol: "ETH-USD", BaseAsset: "ETH", QuoteAsset: "USD", TickSize: decimal("0.01"), LotSize: decimal("0.01"), Status: models.InstrumentSuspended})
+
+		recorder := send(runSequencer(t, markets), "ETH-USD", `{
+			"uuid": "550e8400-e29b-41d4-a716-646655440804",
+			"action": "BUY",
+			"price": 100.0,
+			"amount": 1.0
+		}`)
+
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+	})
+
+	t.Run("It returns 422 error with the reason for an order refused by the risk checks", func(t *testing.T) {
+		t.Parallel()
+		markets := services.NewBookManager(0)
+		markets.CreateInstrument(models.Instrument{Symbol: "BTC-USD", BaseAsset: "BTC", QuoteAsset: "USD", TickSize: decimal("0.01"), LotSize: decimal("0.01")})
+		markets.Deposit(models.Transfer{AccountID: "account-1", Asset: "USD", Amount: decimal("1000000.0")})
+		markets.SetRiskLimits(services.RiskLimits{MaxOrderNotional: decimal("1000.0")})
+
+		recorder := send(runSequencer(t, markets), "BTC-USD", `{
+			"uuid": "550e8400-e29b-41d4-a716-646655440805",
+			"action": "BUY",
+			"price": 100.0,
+			"amount": 11.0
+		}`)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+		response := new(OrderDetailsResponse)
+		json.Unmarshal(recorder.Body.Bytes(), response)
+		assert.Equal(t, services.ReasonMaxNotional, response.Reason)
+		assert.Equal(t, models.StatusRejected, response.Data.Order.Status)
+		assert.Equal(t, services.ReasonMaxNotional, response.Data.Order.RejectReason)
 	})
 }
 
+func TestCreateMarketOrder(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+
+	t.Run("It returns 200 for a market order without a price", func(t *testing.T) {
+		t.Parallel()
+		body := `{
+			"uuid": "550e8400-e29b-41d4-a716-646655440500",
+			"action": "BUY",
+			"type": "MARKET",
+			"amount": 3.0
+		}`
+
+		engine := gin.New()
+		engine.POST("/api/markets/:symbol/orders", asAccount("account-1", false), CreateOrder(initMarkets(t)))
+
+		req, _ := http.NewRequest(http.MethodPost, "/api/markets/BTC-USD/orders", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		response := new(OrderDetailsResponse)
+		json.Unmarshal(recorder.Body.Bytes(), response)
+		assert.Equal(t, models.Market, response.Data.Order.Kind)
+		assert.Equal(t, models.StatusFilled, response.Data.Order.Status)
+		assert.Equal(t, 2, len(response.Data.Fills))
+	})
+
+	t.Run("It returns 422 error for a market order with a price", func(t *testing.T) {
+		t.Parallel()
+		body := `{
+			"uuid": "550e8400-e29b-41d4-a716-646655440501",
+			"action": "BUY",
+			"type": "MARKET",
+			"price": 100.0,
+			"amount": 3.0
+		}`
+
+		engine := gin.New()
+		engine.POST("/api/markets/:symbol/orders", asAccount("account-1", false), CreateOrder(initMarkets(t)))
+
+		req, _ := http.NewRequest(http.MethodPost, "/api/markets/BTC-USD/orders", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	})
+
+	t.Run("It returns 422 error for a limit order without a price", func(t *testing.T) {
+		t.Parallel()
+		body := `{
+			"uuid": "550e8400-e29b-41d4-a716-646655440502",
+			"action": "BUY",
+			"type": "LIMIT",
+			"amount": 3.0
+		}`
+
+		engine := gin.New()
+		engine.POST("/api/markets/:symbol/orders", asAccount("account-1", false), CreateOrder(initMarkets(t)))
+
+		req, _ := http.NewRequest(http.MethodPost, "/api/markets/BTC-USD/orders", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	})
+}
+
+func TestCreateStopOrder(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+
+	t.Run("It returns 200 for a stop limit order waiting for its stop price", func(t *testing.T) {
+		t.Parallel()
+		body := `{
+			"uuid": "550e8400-e29b-41d4-a716-646655440700",
+			"action": "SELL",
+			"type": "STOP_LIMIT",
+			"stop_price": 90.0,
+			"price": 89.0,
+			"amount": 1.0
+		}`
+
+		engine := gin.New()
+		engine.POST("/api/markets/:symbol/orders", asAccount("account-1", false), CreateOrder(initMarkets(t)))
+
+		req, _ := http.NewRequest(http.MethodPost, "/api/markets/BTC-USD/orders", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		response := new(OrderDetailsResponse)
+		json.Unmarshal(recorder.Body.Bytes(), response)
+		assert.Equal(t, models.StopLimit, response.Data.Order.Kind)
+		assert.Equal(t, models.StatusNew, response.Data.Order.Status)
+		assert.Equal(t, 0, len(response.Data.Fills))
+	})
+
+	invalidBodies := map[string]string{
+		"It returns 422 error for a stop order without a stop price": `{
+			"uuid": "550e8400-e29b-41d4-a716-646655440701",
+			"action": "BUY",
+			"type": "STOP",
+			"amount": 1.0
+		}`,
+		"It returns 422 error for a stop order with a price": `{
+			"uuid": "550e8400-e29b-41d4-a716-646655440702",
+			"action": "BUY",
+			"type": "STOP",
+			"stop_price": 110.0,
+			"price": 110.0,
+			"amount": 1.0
+		}`,
+		"It returns 422 error for a stop limit order without a price": `{
+			"uuid": "550e8400-e29b-41d4-a716-646655440703",
+			"action": "BUY",
+			"type": "STOP_LIMIT",
+			"stop_price": 110.0,
+			"amount": 1.0
+		}`,
+		"It returns 422 error for a limit order with a stop price": `{
+			"uuid": "550e8400-e29b-41d4-a716-646655440704",
+			"action": "BUY",
+			"price": 110.0,
+			"stop_price": 110.0,
+			"amount": 1.0
+		}`,
+	}
+
+	for name, body := range invalidBodies {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			engine := gin.New()
+			engine.POST("/api/markets/:symbol/orders", asAccount("account-1", false), CreateOrder(newMarkets(t)))
+
+			req, _ := http.NewRequest(http.MethodPost, "/api/markets/BTC-USD/orders", bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+
+			recorder := httptest.NewRecorder()
+			engine.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+		})
+	}
+}
+
+func TestCreateOrder_TimeInForce(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+
+	invalidBodies := map[string]string{
+		"It returns 422 error for a GTD order without expire_at": `{
+			"uuid": "550e8400-e29b-41d4-a716-646655440600",
+			"action": "BUY",
+			"price": 10.0,
+			"amount": 1.0,
+			"time_in_force": "GTD"
+		}`,
+		"It returns 422 error for a GTD order expiring in the past": `{
+			"uuid": "550e8400-e29b-41d4-a716-646655440601",
+			"action": "BUY",
+			"price": 10.0,
+			"amount": 1.0,
+			"time_in_force": "GTD",
+			"expire_at": "2020-01-01T00:00:00Z"
+		}`,
+		"It returns 422 error for expire_at on a GTC order": `{
+			"uuid": "550e8400-e29b-41d4-a716-646655440602",
+			"action": "BUY",
+			"price": 10.0,
+			"amount": 1.0,
+			"expire_at": "2999-01-01T00:00:00Z"
+		}`,
+		"It returns 422 error for an iceberg order showing its whole amount": `{
+			"uuid": "550e8400-e29b-41d4-a716-646655440605",
+			"action": "BUY",
+			"price": 10.0,
+			"amount": 1.0,
+			"display_amount": 1.0
+		}`,
+		"It returns 422 error for an IOC iceberg order": `{
+			"uuid": "550e8400-e29b-41d4-a716-646655440606",
+			"action": "BUY",
+			"price": 10.0,
+			"amount": 2.0,
+			"display_amount": 1.0,
+			"time_in_force": "IOC"
+		}`,
+		"It returns 422 error for a post-only market order": `{
+			"uuid": "550e8400-e29b-41d4-a716-646655440607",
+			"action": "BUY",
+			"type": "MARKET",
+			"amount": 1.0,
+			"post_only": "REJECT"
+		}`,
+		"It returns 422 error for an unknown self-trade prevention mode": `{
+			"uuid": "550e8400-e29b-41d4-a716-646655440608",
+			"action": "BUY",
+			"price": 10.0,
+			"amount": 1.0,
+			"account_id": "account-1",
+			"stp_mode": "CANCEL_ALL"
+		}`,
+		"It returns 422 error for a market order that would rest": `{
+			"uuid": "550e8400-e29b-41d4-a716-646655440603",
+			"action": "BUY",
+			"type": "MARKET",
+			"amount": 1.0,
+			"time_in_force": "GTC"
+		}`,
+	}
+
+	for name, body := range invalidBodies {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			engine := gin.New()
+			engine.POST("/api/markets/:symbol/orders", asAccount("account-1", false), CreateOrder(newMarkets(t)))
+
+			req, _ := http.NewRequest(http.MethodPost, "/api/markets/BTC-USD/orders", bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+
+			recorder := httptest.NewRecorder()
+			engine.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+		})
+	}
+
+	t.Run("It returns 200 for a GTD order expiring in the future", func(t *testing.T) {
+		t.Parallel()
+		body := `{
+			"uuid": "550e8400-e29b-41d4-a716-646655440604",
+			"action": "BUY",
+			"price": 10.0,
+			"amount": 1.0,
+			"time_in_force": "GTD",
+			"expire_at": "2999-01-01T00:00:00Z"
+		}`
+
+		engine := gin.New()
+		engine.POST("/api/markets/:symbol/orders", asAccount("account-1", false), CreateOrder(newMarkets(t)))
+
+		req, _ := http.NewRequest(http.MethodPost, "/api/markets/BTC-USD/orders", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		response := new(OrderDetailsResponse)
+		json.Unmarshal(recorder.Body.Bytes(), response)
+		assert.Equal(t, models.GoodTillDate, response.Data.Order.TimeInForce)
+		assert.Equal(t, models.StatusNew, response.Data.Order.Status)
+	})
+}
+
+func TestConcurrentRequests(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+
+	sequencer := initMarkets(t)
+	engine := gin.New()
+	engine.POST("/api/markets/:symbol/orders", asAccount("account-1", false), CreateOrder(sequencer))
+	engine.GET("/api/markets/:symbol/orderbook", GetOrderBook(sequencer.Markets()))
+	engine.GET("/api/markets/:symbol/orders", asAccount("admin", true), GetOrdersList(sequencer.Markets()))
+
+	var requests sync.WaitGroup
+	for client := 0; client < 8; client++ {
+		requests.Add(1)
+		go func() {
+			defer requests.Done()
+			for index := 0; index < 25; index++ {
+				body := fmt.Sprintf(`{"uuid": "00000000-0000-4000-8000-%04d%08d", "action": "%s", "price": 100.0, "amount": 1.0}`, client, index, []string{"BUY", "SELL"}[index%2])
+				req, _ := http.NewRequest(http.MethodPost, "/api/markets/BTC-USD/orders", bytes.NewBufferString(body))
+				req.Header.Set("Content-Type", "application/json")
+				recorder := httptest.NewRecorder()
+				engine.ServeHTTP(recorder, req)
+				assert.Equal(t, http.StatusOK, recorder.Code)
+
+				for _, path := range []string{"/api/markets/BTC-USD/orderbook", "/api/markets/BTC-USD/orders"} {
+					req, _ := http.NewRequest(http.MethodGet, path, nil)
+					recorder := httptest.NewRecorder()
+					engine.ServeHTTP(recorder, req)
+					assert.Equal(t, http.StatusOK, recorder.Code)
+				}
+			}
+		}()
+	}
+	requests.Wait()
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/markets/BTC-USD/orders?page_size=500", nil)
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, req)
+
+	response := new(Response)
+	json.Unmarshal(recorder.Body.Bytes(), response)
+	assert.Equal(t, 206, len(response.Data))
+}
+
 func TestOrderBook(t *testing.T) {
 	t.Parallel()
 	t.Run("It returns orderbook correctly", func(t *testing.T) {
 		t.Parallel()
-		orderBook := initOrderBook()
-
 		engine := gin.New()
-		engine.GET("/api/orderbook", GetOrderBook(orderBook))
+		engine.GET("/api/markets/:symbol/orderbook", GetOrderBook(initMarkets(t).Markets()))
 
-		req, _ := http.NewRequest(http.MethodGet, "/api/orderbook", nil)
+		req, _ := http.NewRequest(http.MethodGet, "/api/markets/BTC-USD/orderbook", nil)
 		req.Header.Set("Content-Type", "application/json")
 
 		recorder := httptest.NewRecorder()
@@ -204,12 +684,10 @@ func TestOrderList(t *testing.T) {
 	t.Parallel()
 	t.Run("It returns order list correctly", func(t *testing.T) {
 		t.Parallel()
-		orderBook := initOrderBook()
-
 		engine := gin.New()
-		engine.GET("/api/orders", GetOrdersList(orderBook))
+		engine.GET("/api/markets/:symbol/orders", asAccount("admin", true), GetOrdersList(initMarkets(t).Markets()))
 
-		req, _ := http.NewRequest(http.MethodGet, "/api/orders", nil)
+		req, _ := http.NewRequest(http.MethodGet, "/api/markets/BTC-USD/orders", nil)
 		req.Header.Set("Content-Type", "application/json")
 
 		recorder := httptest.NewRecorder()
@@ -221,65 +699,224 @@ func TestOrderList(t *testing.T) {
 		json.Unmarshal(recorder.Body.Bytes(), response)
 		assert.Equal(t, 6, len(response.Data))
 	})
+
+	t.Run("It returns only the orders of the account without an admin key", func(t *testing.T) {
+		t.Parallel()
+		sequencer := initMarkets(t)
+		market, _ := sequencer.Markets().Market("BTC-USD")
+		sequencer.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-646655440900", Symbol: "BTC-USD", AccountID: "account-1", Action: models.Buy, Price: decimal("70.0"), Amount: decimal("1.0")})
+
+		engine := gin.New()
+		engine.GET("/api/markets/:symbol/orders", asAccount("account-1", false), GetOrdersList(sequencer.Markets()))
+
+		req, _ := http.NewRequest(http.MethodGet, "/api/markets/BTC-USD/orders", nil)
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		response := new(Response)
+		json.Unmarshal(recorder.Body.Bytes(), response)
+		assert.Equal(t, 1, len(response.Data))
+		assert.Equal(t, "550e8400-e29b-41d4-a716-646655440900", response.Data[0].ID)
+	})
+}
+
+func TestGetOrder(t *testing.T) {
+	t.Parallel()
+	t.Run("It returns an order with its status and fills", func(t *testing.T) {
+		t.Parallel()
+		sequencer := initMarkets(t)
+		market, _ := sequencer.Markets().Market("BTC-USD")
+		sequencer.PlaceOrder(market, &models.Order{
+			ID:     "550e8400-e29b-41d4-a716-646655440400",
+			Symbol: "BTC-USD",
+			Action: models.Buy,
+			Price:  decimal("100.0"),
+			Amount: decimal("1.0"),
+		})
+
+		engine := gin.New()
+		engine.GET("/api/markets/:symbol/orders/:uuid", asAccount("admin", true), GetOrder(sequencer.Markets()))
+
+		req, _ := http.NewRequest(http.MethodGet, "/api/markets/BTC-USD/orders/550e8400-e29b-41d4-a716-77755442000", nil)
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		response := new(OrderDetailsResponse)
+		json.Unmarshal(recorder.Body.Bytes(), response)
+		assert.Equal(t, models.StatusPartiallyFilled, response.Data.Order.Status)
+		assert.Equal(t, decimal("1.0"), response.Data.Order.RemainingAmount)
+		assert.Equal(t, 1, len(response.Data.Fills))
+		assert.Equal(t, "550e8400-e29b-41d4-a716-646655440400", response.Data.Fills[0].TakerOrderID)
+	})
+
+	t.Run("It returns 404 error for an unknown order", func(t *testing.T) {
+		t.Parallel()
+		engine := gin.New()
+		engine.GET("/api/markets/:symbol/orders/:uuid", asAccount("admin", true), GetOrder(initMarkets(t).Markets()))
+
+		req, _ := http.NewRequest(http.MethodGet, "/api/markets/BTC-USD/orders/550e8400-e29b-41d4-a716-999955442000", nil)
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
+
+func TestCancelOrder(t *testing.T) {
+	t.Parallel()
+	t.Run("It cancels a resting order", func(t *testing.T) {
+		t.Parallel()
+		engine := gin.New()
+		engine.DELETE("/api/markets/:symbol/orders/:uuid", asAccount("admin", true), CancelOrder(initMarkets(t)))
+
+		req, _ := http.NewRequest(http.MethodDelete, "/api/markets/BTC-USD/orders/550e8400-e29b-41d4-a716-666655442000", nil)
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		response := new(OrderResponse)
+		json.Unmarshal(recorder.Body.Bytes(), response)
+		assert.Equal(t, "success", response.Message)
+		assert.Equal(t, "550e8400-e29b-41d4-a716-666655442000", response.Data.ID)
+	})
+
+	t.Run("It returns 404 error for an order of another account", func(t *testing.T) {
+		t.Parallel()
+		engine := gin.New()
+		engine.DELETE("/api/markets/:symbol/orders/:uuid", asAccount("account-1", false), CancelOrder(initMarkets(t)))
+
+		req, _ := http.NewRequest(http.MethodDelete, "/api/markets/BTC-USD/orders/550e8400-e29b-41d4-a716-666655442000", nil)
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("It returns 404 error for an unknown order", func(t *testing.T) {
+		t.Parallel()
+		engine := gin.New()
+		engine.DELETE("/api/markets/:symbol/orders/:uuid", asAccount("admin", true), CancelOrder(initMarkets(t)))
+
+		req, _ := http.NewRequest(http.MethodDelete, "/api/markets/BTC-USD/orders/550e8400-e29b-41d4-a716-999955442000", nil)
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
 }
 
-func initOrderBook() *services.OrderBook {
-	orderBook := services.NewOrderBook()
-	orderBook.SellOrders = map[float64][]models.Order{
-		120.0: {
-			{
-				ID:     "550e8400-e29b-41d4-a716-666655442000",
-				Action: models.Sell,
-				Price:  120.0,
-				Amount: 2.0,
-			},
-		},
-		100.0: {
-			{
-				ID:     "550e8400-e29b-41d4-a716-77755442000",
-				Action: models.Sell,
-				Price:  100.0,
-				Amount: 2.0,
-			},
-			{
-				ID:     "550e8400-e29b-41d4-a716-77755442001",
-				Action: models.Sell,
-				Price:  100.0,
-				Amount: 2.0,
-			},
-			{
-				ID:     "550e8400-e29b-41d4-a716-77755442002",
-				Action: models.Sell,
-				Price:  100.0,
-				Amount: 3.0,
-			},
-		},
+func TestAmendOrder(t *testing.T) {
+	t.Parallel()
+	t.Run("It amends a resting order", func(t *testing.T) {
+		t.Parallel()
+		sequencer := initMarkets(t)
+		market, _ := sequencer.Markets().Market("BTC-USD")
+
+		engine := gin.New()
+		engine.PATCH("/api/markets/:symbol/orders/:uuid", asAccount("admin", true), AmendOrder(sequencer))
+
+		req, _ := http.NewRequest(http.MethodPatch, "/api/markets/BTC-USD/orders/550e8400-e29b-41d4-a716-77755442002", bytes.NewBufferString(`{"amount": 1.0}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		order, _ := market.OrderHistory.GetOrder("550e8400-e29b-41d4-a716-77755442002")
+		assert.Equal(t, decimal("1.0"), order.RemainingAmount)
+	})
+
+	t.Run("It returns 422 error for an empty amendment", func(t *testing.T) {
+		t.Parallel()
+		engine := gin.New()
+		engine.PATCH("/api/markets/:symbol/orders/:uuid", asAccount("admin", true), AmendOrder(initMarkets(t)))
+
+		req, _ := http.NewRequest(http.MethodPatch, "/api/markets/BTC-USD/orders/550e8400-e29b-41d4-a716-77755442002", bytes.NewBufferString(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	})
+
+	t.Run("It returns 404 error for an unknown order", func(t *testing.T) {
+		t.Parallel()
+		engine := gin.New()
+		engine.PATCH("/api/markets/:symbol/orders/:uuid", asAccount("admin", true), AmendOrder(initMarkets(t)))
+
+		req, _ := http.NewRequest(http.MethodPatch, "/api/markets/BTC-USD/orders/550e8400-e29b-41d4-a716-999955442000", bytes.NewBufferString(`{"price": 101.0}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
+
+// runSequencer runs a sequencer for the markets until the test ends.
+func runSequencer(t *testing.T, markets *services.BookManager) *services.Sequencer {
+	ctx, cancel := context.WithCancel(context.Background())
+	sequencer := services.NewSequencer(markets, 1024)
+	go sequencer.Run(ctx)
+	t.Cleanup(func() {
+		cancel()
+		<-sequencer.Done()
+	})
+
+	return sequencer
+}
+
+// newMarkets lists BTC-USD and funds account-1 and market-maker, the account
+// of the orders of initMarkets.
+func newMarkets(t *testing.T) *services.Sequencer {
+	markets := services.NewBookManager(0)
+	markets.CreateInstrument(models.Instrument{Symbol: "BTC-USD", BaseAsset: "BTC", QuoteAsset: "USD", TickSize: decimal("0.01"), LotSize: decimal("0.01")})
+	for _, accountID := range []string{"account-1", "market-maker"} {
+		markets.Deposit(models.Transfer{AccountID: accountID, Asset: "BTC", Amount: decimal("1000.0")})
+		markets.Deposit(models.Transfer{AccountID: accountID, Asset: "USD", Amount: decimal("1000000.0")})
 	}
 
-	heap.Push(&orderBook.SellPricesHeap, 120.0)
-	heap.Push(&orderBook.SellPricesHeap, 100.0)
-
-	orderBook.BuyOrders = map[float64][]models.Order{
-		80.0: {
-			{
-				ID:     "550e8400-e29b-41d4-a716-666655442000",
-				Action: models.Buy,
-				Price:  80.0,
-				Amount: 2.0,
-			},
-		},
-		100.0: {
-			{
-				ID:     "550e8400-e29b-41d4-a716-77755442000",
-				Action: models.Buy,
-				Price:  100.0,
-				Amount: 2.0,
-			},
-		},
+	return runSequencer(t, markets)
+}
+
+func initMarkets(t *testing.T) *services.Sequencer {
+	sequencer := newMarkets(t)
+	market, _ := sequencer.Markets().Market("BTC-USD")
+
+	place := func(id string, action models.OrderType, price string, amount string) {
+		sequencer.PlaceOrder(market, &models.Order{ID: id, Symbol: "BTC-USD", AccountID: "market-maker", Action: action, Price: decimal(price), Amount: decimal(amount)})
 	}
+	place("550e8400-e29b-41d4-a716-666655442000", models.Sell, "120.0", "2.0")
+	place("550e8400-e29b-41d4-a716-77755442000", models.Sell, "100.0", "2.0")
+	place("550e8400-e29b-41d4-a716-77755442001", models.Sell, "100.0", "2.0")
+	place("550e8400-e29b-41d4-a716-77755442002", models.Sell, "100.0", "3.0")
+
+	place("550e8400-e29b-41d4-a716-666655443000", models.Buy, "80.0", "2.0")
+	place("550e8400-e29b-41d4-a716-77755443000", models.Buy, "90.0", "2.0")
 
-	heap.Push(&orderBook.BuyPricesHeap, 80.0)
-	heap.Push(&orderBook.BuyPricesHeap, 100.0)
+	return sequencer
+}
 
-	return orderBook
-}
\ No newline at end of file
+// asAccount stands in for Authenticate: the requests act for accountID, with
+// an admin key if admin is set.
+func asAccount(accountID string, admin bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(accountIDKey, accountID)
+		c.Set(adminKey, admin)
+	}
+}
+
+// decimal keeps the test fixtures readable.
+func decimal(value string) models.Decimal {
+	return models.MustParseDecimal(value)
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"expvar"
+	"math"
+	"net/http"
+	"order-matching/config"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitRejects counts the requests refused by RateLimit, by class and by
+// class and tier, e.g. "place" and "place.default". It is served with the
+// other expvar metrics.
+var rateLimitRejects = expvar.NewMap("rate_limit_rejects")
+
+// RateLimiter keeps a token bucket per account and class of request, and per
+// client IP and class. Every request takes a token from its bucket, which
+// refills at the rate of the tier of the account, or of the client IP tier,
+// up to its burst. Buckets that have refilled completely are forgotten. It is
+// safe for concurrent use.
+type RateLimiter struct {
+	mutex sync.Mutex
+	limits map[string]map[config.RateLimitClass]config.RateLimit
+	tiers map[string]string // tier by account ID
+	buckets map[bucketKey]*bucket
+	nextPrune time.Time
+	now func() time.Time
+}
+
+type bucketKey struct {
+	client string // account ID, or IP address with an "ip:" prefix
+	class config.RateLimitClass
+}
+
+type bucket struct {
+	tokens float64
+	updated time.Time
+	limit config.RateLimit
+}
+
+// rateLimitPruneInterval is how often buckets that are full again are
+// forgotten.
+const rateLimitPruneInterval = time.Minute
+
+func NewRateLimiter(limits map[string]map[config.RateLimitClass]config.RateLimit, tiers map[string]string) *RateLimiter {
+	return &RateLimiter{
+		limits: limits,
+		tiers: tiers,
+		buckets: make(map[bucketKey]*bucket),
+		now: time.Now,
+	}
+}
+
+// tier returns the tier of an account.
+func (rl *RateLimiter) tier(accountID string) string {
+	if tier, exists := rl.tiers[accountID]; exists {
+		return tier
+	}
+
+	return config.DefaultTier
+}
+
+// limit returns the limit of a tier for a class, which is that of the default
+// tier if the tier leaves the class out.
+func (rl *RateLimiter) limit(tier string, class config.RateLimitClass) config.RateLimit {
+	if limit, exists := rl.limits[tier][class]; exists {
+		return limit
+	}
+
+	return rl.limits[config.DefaultTier][class]
+}
+
+// take takes a token from the bucket of a client for a class. It returns
+// whether there was one, the limit, how many tokens are left, and how long it
+// takes for the next token to arrive.
+func (rl *RateLimiter) take(key bucketKey, limit config.RateLimit) (bool, int, time.Duration) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	now := rl.now()
+	if now.After(rl.nextPrune) {
+		for key, bucket := range rl.buckets {
+			if bucket.refill(now) >= float64(bucket.limit.Burst) {
+				delete(rl.buckets, key)
+			}
+		}
+		rl.nextPrune = now.Add(rateLimitPruneInterval)
+	}
+
+	current, exists := rl.buckets[key]
+	if !exists {
+		current = &bucket{tokens: float64(limit.Burst), updated: now, limit: limit}
+		rl.buckets[key] = current
+	}
+	current.tokens = current.refill(now)
+	current.updated = now
+
+	if current.tokens < 1 {
+		return false, 0, time.Duration((1 - current.tokens) / limit.Rate * float64(time.Second))
+	}
+	current.tokens--
+
+	return true, int(current.tokens), time.Duration(max(1-current.tokens, 0) / limit.Rate * float64(time.Second))
+}
+
+// refill returns the tokens the bucket holds at now.
+func (b *bucket) refill(now time.Time) float64 {
+	return min(b.tokens+now.Sub(b.updated).Seconds()*b.limit.Rate, float64(b.limit.Burst))
+}
+
+// RateLimit limits requests per account, so it goes after Authenticate. It
+// lets a request through only if the bucket of its account for the class has
+// a token left, and refuses it with 429 otherwise.
+func RateLimit(limiter *RateLimiter, class config.RateLimitClass) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountID := authenticatedAccount(c)
+		limitRequest(c, limiter, accountID, limiter.tier(accountID), class)
+	}
+}
+
+// RateLimitIP limits requests per client IP, with the limits of the client IP
+// tier. It goes before Authenticate on signed routes, so that a flood of
+// requests is refused before their signatures are checked. The client IP is
+// only taken from forwarding headers set by a trusted proxy.
+func RateLimitIP(limiter *RateLimiter, class config.RateLimitClass) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limitRequest(c, limiter, "ip:"+c.ClientIP(), config.ClientIPTier, class)
+	}
+}
+
+// limitRequest takes a token from the bucket of the client for the class, or
+// refuses the request with 429. Every response carries the limit and the
+// tokens left, and a refusal how many seconds to wait; when a request passes
+// more than one limit, the headers are those of the last.
+func limitRequest(c *gin.Context, limiter *RateLimiter, client string, tier string, class config.RateLimitClass) {
+	limit := limiter.limit(tier, class)
+	allowed, remaining, wait := limiter.take(bucketKey{client: client, class: class}, limit)
+
+	c.Header("X-RateLimit-Limit", strconv.Itoa(limit.Burst))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	c.Header("X-RateLimit-Reset", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	if !allowed {
+		rateLimitRejects.Add(string(class), 1)
+		rateLimitRejects.Add(string(class)+"."+tier, 1)
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, MessageResponse{
+			Message: "Too many requests, please slow down",
+		})
+		return
+	}
+
+	c.Next()
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"order-matching/config"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+
+	limits := map[string]map[config.RateLimitClass]config.RateLimit{
+		config.DefaultTier: {
+			config.RateLimitPlace: {Rate: 1, Burst: 2},
+			config.RateLimitCancel: {Rate: 1, Burst: 3},
+		},
+		"market-maker": {
+			config.RateLimitPlace: {Rate: 10, Burst: 5},
+		},
+		config.ClientIPTier: {
+			config.RateLimitPlace: {Rate: 1, Burst: 3},
+		},
+	}
+	tiers := map[string]string{"account-2": "market-maker"}
+
+	// newEngine serves /place and /cancel, limited per account, as the
+	// account in the X-Test-Account header; /public, limited per client IP;
+	// and /signed, limited per client IP before it counts an authentication
+	// and per account after
+	newEngine := func(now *time.Time) (*gin.Engine, *int) {
+		limiter := NewRateLimiter(limits, tiers)
+		limiter.now = func() time.Time { return *now }
+		engine := gin.New()
+		authentications := 0
+		authenticate := func(c *gin.Context) {
+			authentications++
+			c.Set(accountIDKey, c.GetHeader("X-Test-Account"))
+		}
+		ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+		engine.POST("/place", authenticate, RateLimit(limiter, config.RateLimitPlace), ok)
+		engine.DELETE("/cancel", authenticate, RateLimit(limiter, config.RateLimitCancel), ok)
+		engine.POST("/public", RateLimitIP(limiter, config.RateLimitPlace), ok)
+		engine.POST("/signed", RateLimitIP(limiter, config.RateLimitPlace), authenticate, RateLimit(limiter, config.RateLimitPlace), ok)
+		return engine, &authentications
+	}
+
+	serve := func(engine *gin.Engine, method string, path string, accountID string, ip string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("X-Test-Account", accountID)
+		req.RemoteAddr = ip + ":1234"
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("It returns 429 error with the time to wait once the burst is spent", func(t *testing.T) {
+		t.Parallel()
+		now := time.Now()
+		engine, _ := newEngine(&now)
+
+		first := serve(engine, http.MethodPost, "/place", "account-1", "10.0.0.1")
+		assert.Equal(t, http.StatusOK, first.Code)
+		assert.Equal(t, "2", first.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, "1", first.Header().Get("X-RateLimit-Remaining"))
+		assert.Equal(t, http.StatusOK, serve(engine, http.MethodPost, "/place", "account-1", "10.0.0.1").Code)
+
+		recorder := serve(engine, http.MethodPost, "/place", "account-1", "10.0.0.1")
+
+		assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+		assert.Equal(t, "0", recorder.Header().Get("X-RateLimit-Remaining"))
+		assert.Equal(t, "1", recorder.Header().Get("Retry-After"))
+	})
+
+	t.Run("It lets requests through again as the bucket refills", func(t *testing.T) {
+		t.Parallel()
+		now := time.Now()
+		engine, _ := newEngine(&now)
+		serve(engine, http.MethodPost, "/place", "account-1", "10.0.0.1")
+		serve(engine, http.MethodPost, "/place", "account-1", "10.0.0.1")
+
+		now = now.Add(500 * time.Millisecond)
+		assert.Equal(t, http.StatusTooManyRequests, serve(engine, http.MethodPost, "/place", "account-1", "10.0.0.1").Code)
+		now = now.Add(500 * time.Millisecond)
+		assert.Equal(t, http.StatusOK, serve(engine, http.MethodPost, "/place", "account-1", "10.0.0.1").Code)
+	})
+
+	t.Run("It keeps separate buckets per account, per client IP and per class", func(t *testing.T) {
+		t.Parallel()
+		now := time.Now()
+		engine, _ := newEngine(&now)
+		serve(engine, http.MethodPost, "/place", "account-1", "10.0.0.1")
+		serve(engine, http.MethodPost, "/place", "account-1", "10.0.0.1")
+
+		assert.Equal(t, http.StatusOK, serve(engine, http.MethodPost, "/place", "account-3", "10.0.0.1").Code)
+		assert.Equal(t, http.StatusOK, serve(engine, http.MethodDelete, "/cancel", "account-1", "10.0.0.1").Code)
+		for range 3 {
+			assert.Equal(t, http.StatusOK, serve(engine, http.MethodPost, "/public", "", "10.0.0.1").Code)
+		}
+		assert.Equal(t, http.StatusTooManyRequests, serve(engine, http.MethodPost, "/public", "", "10.0.0.1").Code)
+		assert.Equal(t, http.StatusOK, serve(engine, http.MethodPost, "/public", "", "10.0.0.2").Code)
+	})
+
+	t.Run("It limits a client IP by its own tier before checking the signature", func(t *testing.T) {
+		t.Parallel()
+		now := time.Now()
+		engine, authentications := newEngine(&now)
+
+		for _, accountID := range []string{"account-1", "account-3", "account-4"} {
+			recorder := serve(engine, http.MethodPost, "/signed", accountID, "10.0.0.1")
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, "2", recorder.Header().Get("X-RateLimit-Limit"), "the headers are those of the account limit")
+		}
+		recorder := serve(engine, http.MethodPost, "/signed", "account-5", "10.0.0.1")
+
+		assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+		assert.Equal(t, "3", recorder.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, 3, *authentications, "the refused request is not authenticated")
+	})
+
+	t.Run("It only takes the client IP from a trusted proxy", func(t *testing.T) {
+		t.Parallel()
+		now := time.Now()
+		engine, _ := newEngine(&now)
+		engine.SetTrustedProxies([]string{"10.0.0.9"})
+		forwarded := func(remoteIP string, clientIP string) int {
+			req, _ := http.NewRequest(http.MethodPost, "/public", nil)
+			req.Header.Set("X-Forwarded-For", clientIP)
+			req.RemoteAddr = remoteIP + ":1234"
+			recorder := httptest.NewRecorder()
+			engine.ServeHTTP(recorder, req)
+			return recorder.Code
+		}
+
+		for index := range 4 {
+			forwarded("10.0.0.1", fmt.Sprintf("192.168.0.%d", index))
+		}
+		assert.Equal(t, http.StatusTooManyRequests, forwarded("10.0.0.1", "192.168.0.9"), "an untrusted peer cannot pick its client IP")
+		assert.Equal(t, http.StatusOK, forwarded("10.0.0.9", "192.168.0.1"))
+	})
+
+	t.Run("It applies the limits of the tier of the account", func(t *testing.T) {
+		t.Parallel()
+		now := time.Now()
+		engine, _ := newEngine(&now)
+
+		place := serve(engine, http.MethodPost, "/place", "account-2", "10.0.0.1")
+		cancel := serve(engine, http.MethodDelete, "/cancel", "account-2", "10.0.0.1")
+
+		assert.Equal(t, "5", place.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, "3", cancel.Header().Get("X-RateLimit-Limit"), "a class the tier leaves out has the default limit")
+	})
+
+	t.Run("It counts the refused requests", func(t *testing.T) {
+		t.Parallel()
+		now := time.Now()
+		engine, _ := newEngine(&now)
+		before := rateLimitRejects.Get("cancel.market-maker")
+
+		for range 4 {
+			serve(engine, http.MethodDelete, "/cancel", "account-2", "10.0.0.1")
+		}
+
+		assert.Nil(t, before)
+		assert.Equal(t, "1", rateLimitRejects.Get("cancel.market-maker").String())
+	})
+}
@@ -1,17 +1,96 @@
 package handlers
 
 import (
+	"context"
+	"expvar"
+	"fmt"
+	"order-matching/config"
 	"order-matching/services"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterRoutes(engine *gin.Engine) {
-	orderBook := services.NewOrderBook()
+// RegisterRoutes serves the markets of the sequencer under /api. Every change
+// goes through the sequencer; reads are served from the published state of
+// the markets. Market data is public; orders, balances and execution reports
+// need a signed request, and listing an instrument, depositing funds or
+// halting, resuming and closing a market an admin key. Requests are rate
+// limited per client IP, signed requests before their signature is checked,
+// and signed requests again per account. The counters of the service are
+// served to admin keys at /debug/vars. journal is nil when nothing is
+// persisted; otherwise the markets are snapshotted next to it. The routes of
+// the service from before it listed instruments are kept for the default
+// market, if there is one.
+func RegisterRoutes(engine *gin.Engine, sequencer *services.Sequencer, journal *services.Journal, cfg config.Config) {
+	markets := sequencer.Markets()
+	go services.NewExpirySweeper(sequencer, cfg.ExpirySweepInterval).Run(context.Background())
+	if journal != nil {
+		go services.NewSnapshotter(sequencer, journal, cfg.JournalDir, cfg.SnapshotInterval, cfg.SnapshotEvery).Run(context.Background())
+	}
+
+	authenticate := Authenticate(cfg.APIKeys, cfg.AuthWindow)
+	limiter := NewRateLimiter(cfg.RateLimits, cfg.AccountTiers)
+	placeByIP := RateLimitIP(limiter, config.RateLimitPlace)
+	cancelByIP := RateLimitIP(limiter, config.RateLimitCancel)
+	readByIP := RateLimitIP(limiter, config.RateLimitRead)
+	place := RateLimit(limiter, config.RateLimitPlace)
+	cancel := RateLimit(limiter, config.RateLimitCancel)
+	read := RateLimit(limiter, config.RateLimitRead)
+	engine.GET("/debug/vars", readByIP, authenticate, read, RequireAdmin(), gin.WrapH(expvar.Handler()))
 	api := engine.Group("/api") 
 	{
-		api.POST("/orders", CreateOrder(orderBook))
-		api.GET("/orderbook", GetOrderBook(orderBook))
-		api.GET("/orders", GetOrdersList(orderBook))
+		api.GET("/markets", readByIP, GetInstrumentsList(markets))
+		api.POST("/markets", placeByIP, authenticate, place, RequireAdmin(), CreateInstrument(sequencer))
+		api.GET("/markets/:symbol", readByIP, GetInstrument(markets))
+
+		market := api.Group("/markets/:symbol")
+		{
+			market.POST("/orders", placeByIP, authenticate, place, CreateOrder(sequencer))
+			market.GET("/orderbook", readByIP, GetOrderBook(markets))
+			market.GET("/orders", readByIP, authenticate, read, GetOrdersList(markets))
+			market.GET("/orders/:uuid", readByIP, authenticate, read, GetOrder(markets))
+			market.DELETE("/orders/:uuid", cancelByIP, authenticate, cancel, CancelOrder(sequencer))
+			market.PATCH("/orders/:uuid", placeByIP, authenticate, place, AmendOrder(sequencer))
+			market.GET("/trades", readByIP, GetTradesList(markets))
+			market.GET("/feed", readByIP, StreamMarketData(markets))
+			market.GET("/state", readByIP, GetMarketState(markets))
+			market.POST("/halt", placeByIP, authenticate, place, RequireAdmin(), HaltMarket(sequencer))
+			market.POST("/resume", placeByIP, authenticate, place, RequireAdmin(), ResumeMarket(sequencer))
+			market.POST("/close", placeByIP, authenticate, place, RequireAdmin(), CloseMarket(sequencer))
+		}
+
+		api.GET("/executions", readByIP, authenticate, read, StreamExecutions(markets))
+
+		if cfg.DefaultMarket != "" {
+			// the routes of the single market the service had before it
+			// listed instruments
+			legacy := api.Group("", DefaultMarket(cfg.DefaultMarket))
+			legacy.POST("/orders", placeByIP, authenticate, place, CreateOrder(sequencer))
+			legacy.GET("/orderbook", readByIP, GetOrderBook(markets))
+			legacy.GET("/orders", readByIP, authenticate, read, GetOrdersList(markets))
+			legacy.GET("/orders/:uuid", readByIP, authenticate, read, GetOrder(markets))
+			legacy.DELETE("/orders/:uuid", cancelByIP, authenticate, cancel, CancelOrder(sequencer))
+			legacy.PATCH("/orders/:uuid", placeByIP, authenticate, place, AmendOrder(sequencer))
+			legacy.GET("/trades", readByIP, GetTradesList(markets))
+		}
+
+		account := api.Group("/accounts/:account")
+		{
+			account.POST("/deposits", placeByIP, authenticate, place, RequireAccount(), RequireAdmin(), Deposit(sequencer))
+			account.POST("/withdrawals", placeByIP, authenticate, place, RequireAccount(), Withdraw(sequencer))
+			account.GET("/balances", readByIP, authenticate, read, RequireAccount(), GetBalances(markets))
+		}
+	}
+}
+
+// DefaultMarket serves a route without a symbol for the market of symbol. The
+// response marks the route as deprecated and links to the route that replaces
+// it.
+func DefaultMarket(symbol string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Params = append(c.Params, gin.Param{Key: "symbol", Value: symbol})
+		c.Header("Deprecation", "true")
+		c.Header("Link", fmt.Sprintf("</api/markets/%s%s>; rel=\"successor-version\"", symbol, strings.TrimPrefix(c.Request.URL.Path, "/api")))
 	}
 }
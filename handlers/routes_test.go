@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultMarket(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.GET("/api/orderbook", DefaultMarket("BTC-USD"), GetOrderBook(initMarkets(t).Markets()))
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/orderbook", nil)
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "true", recorder.Header().Get("Deprecation"))
+	assert.Equal(t, `</api/markets/BTC-USD/orderbook>; rel="successor-version"`, recorder.Header().Get("Link"))
+
+	response := new(Response)
+	json.Unmarshal(recorder.Body.Bytes(), response)
+	assert.Equal(t, 4, len(response.Data))
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+	"order-matching/models"
+	"order-matching/services"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TradesResponse struct {
+	Message string `json:"message"`
+	Data []models.Trade `json:"data"`
+}
+
+// GetTradesList retrieves a paginated list of the trades executed in a market.
+//
+//	@Summary		Get list of trades
+//	@Description	Returns a paginated list of the trades executed in a market, oldest first, optionally restricted to a time range.
+//	@Tags			Trades
+//	@Produce		json
+//	@Param			symbol		path	string	true	"Instrument symbol"
+//	@Param			page		query	int		false	"Page number (default is 1)"
+//	@Param			page_size	query	int		false	"Number of trades per page (default is 10)"
+//	@Param			from		query	string	false	"Only trades executed at or after this time (RFC 3339)"
+//	@Param			to			query	string	false	"Only trades executed before this time (RFC 3339)"
+//	@Success		200			{object}	TradesResponse	"Successfully retrieved list of trades"
+//	@Failure		422			{object}	TradesResponse	"Invalid time filter"
+//	@Failure		404			{object}	TradesResponse	"Market not found"
+//	@Failure		429			{object}	MessageResponse	"Too many requests"
+//	@Router			/markets/{symbol}/trades [get]
+//	@Example		{json} Success-Response
+//	{
+//	  "message": "success",
+//	  "data": [
+//	    {
+//	      "id": 1,
+//	      "symbol": "BTC-USD",
+//	      "maker_order_uuid": "550e8400-e29b-41d4-a716-446655440001",
+//	      "taker_order_uuid": "550e8400-e29b-41d4-a716-446655440000",
+//	      "price": "99.5",
+//	      "amount": "1",
+//	      "aggressor_side": "BUY",
+//	      "timestamp": "2025-03-01T10:00:00Z",
+//	      "sequence": 2
+//	    }
+//	  ]
+//	}
+func GetTradesList(markets *services.BookManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		market, err := markets.Market(c.Param("symbol"))
+		if err != nil {
+			status, message := orderErrorResponse(err)
+			c.JSON(status, TradesResponse{
+				Message: message,
+				Data: []models.Trade{},
+			})
+			return
+		}
+
+		page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+		if err != nil || page <= 0 {
+			page = 1
+		}
+
+		pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+		if err != nil || pageSize <= 0 {
+			pageSize = 10
+		}
+
+		from, fromErr := parseTimeQuery(c, "from")
+		to, toErr := parseTimeQuery(c, "to")
+		if fromErr != nil || toErr != nil {
+			c.JSON(http.StatusUnprocessableEntity, TradesResponse{
+				Message: "Invalid time filter",
+				Data: []models.Trade{},
+			})
+			return
+		}
+
+		trades := market.TradeHistory.GetTradeList(from, to, page, pageSize)
+
+		c.JSON(http.StatusOK, TradesResponse{
+			Message: "success",
+			Data: trades,
+		})
+	}
+}
+
+// parseTimeQuery reads an optional RFC 3339 query parameter. A missing
+// parameter yields the zero time.
+func parseTimeQuery(c *gin.Context, key string) (time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"order-matching/models"
+	"order-matching/services"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTradesList(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+
+	markets := services.NewBookManager(0)
+	markets.CreateInstrument(models.Instrument{Symbol: "BTC-USD", BaseAsset: "BTC", QuoteAsset: "USD", TickSize: decimal("0.01"), LotSize: decimal("0.01")})
+	market, _ := markets.Market("BTC-USD")
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	for i := 1; i <= 3; i++ {
+		market.TradeHistory.Record(models.Trade{
+			ID:        uint64(i),
+			Price:     decimal("100.0"),
+			Amount:    decimal("1.0"),
+			Timestamp: start.Add(time.Duration(i) * time.Minute),
+		})
+	}
+
+	t.Run("It returns trades filtered by time", func(t *testing.T) {
+		t.Parallel()
+		engine := gin.New()
+		engine.GET("/api/markets/:symbol/trades", GetTradesList(markets))
+
+		req, _ := http.NewRequest(http.MethodGet, "/api/markets/BTC-USD/trades?from=2025-03-01T10:02:00Z&page_size=1", nil)
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		response := new(TradesResponse)
+		json.Unmarshal(recorder.Body.Bytes(), response)
+		assert.Equal(t, 1, len(response.Data))
+		assert.Equal(t, uint64(2), response.Data[0].ID)
+	})
+
+	t.Run("It returns 422 error for an invalid time filter", func(t *testing.T) {
+		t.Parallel()
+		engine := gin.New()
+		engine.GET("/api/markets/:symbol/trades", GetTradesList(markets))
+
+		req, _ := http.NewRequest(http.MethodGet, "/api/markets/BTC-USD/trades?to=yesterday", nil)
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+
+		response := new(TradesResponse)
+		json.Unmarshal(recorder.Body.Bytes(), response)
+		assert.Equal(t, "Invalid time filter", response.Message)
+	})
+}
@@ -1,9 +1,19 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"net"
 	"net/http"
+	"order-matching/config"
 	"order-matching/handlers"
+	"order-matching/repository"
+	"order-matching/services"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -18,10 +28,109 @@ import (
 //	@BasePath		/api
 //  @schemes		http
 
+//	@securityDefinitions.apikey	APIKey
+//	@in							header
+//	@name						X-API-Key
+//	@description				The API key of an account. Every request must also carry X-API-Timestamp (milliseconds since the Unix epoch), X-API-Nonce (unique per key, up to 64 characters) and X-API-Signature: the hex encoded HMAC-SHA256, keyed with the secret of the key, of the timestamp, nonce, method and path with query, each followed by a newline, then the body.
+
 func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	markets := services.NewBookManager(cfg.SessionClose)
+	store, err := repository.Open(cfg.Repository, cfg.SQLitePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// the database is written to in the background, so that the sequencer
+	// never waits on it
+	writer := repository.NewAsyncRepository(store, time.Second, cfg.RepositoryQueueSize)
+	defer writer.Close()
+	markets.SetRepository(writer)
+	markets.SetRiskLimits(services.RiskLimits{
+		MaxOrderQuantity: cfg.MaxOrderQuantity,
+		MaxOrderNotional: cfg.MaxOrderNotional,
+		FatFinger: cfg.FatFinger,
+		PriceCollar: cfg.PriceCollar,
+		MaxOpenOrders: cfg.MaxOpenOrders,
+		MaxPosition: cfg.MaxPosition,
+	})
+	markets.SetHaltPolicy(services.HaltPolicy{
+		Band: cfg.CircuitBreakerBand,
+		Window: cfg.CircuitBreakerWindow,
+		Cooldown: cfg.CircuitBreakerCooldown,
+		Auction: cfg.AuctionDuration,
+		Queue: cfg.HaltMode == "queue",
+	})
+
+	var journal *services.Journal
+	if cfg.JournalDir != "" {
+		snapshot, err := services.LoadSnapshot(cfg.JournalDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if snapshot != nil {
+			if err := markets.Restore(snapshot); err != nil {
+				log.Fatal(err)
+			}
+			fmt.Printf("Restored snapshot at journal entry %d\n", snapshot.Sequence)
+		}
+
+		var entries []services.JournalEntry
+		journal, entries, err = services.OpenJournal(cfg.JournalDir, cfg.JournalSyncInterval)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer journal.Close()
+
+		if cfg.EventLog {
+			eventLog, err := services.OpenEventLog(filepath.Join(cfg.JournalDir, "events.log"))
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer eventLog.Close()
+			markets.Subscribe(eventLog.Publish)
+		}
+
+		if err := markets.Recover(journal, entries); err != nil {
+			log.Fatal(err)
+		}
+		go journal.Run(ctx)
+		fmt.Printf("Recovered up to journal entry %d from %s\n", journal.Sequence(), cfg.JournalDir)
+	}
+
+	// the sequencer outlives the server, so that requests in flight at
+	// shutdown are still answered
+	sequencer := services.NewSequencer(markets, cfg.CommandQueueSize)
+	sequencerCtx, stopSequencer := context.WithCancel(context.Background())
+	go sequencer.Run(sequencerCtx)
+
 	engine := gin.New()
-	handlers.RegisterRoutes(engine)
+	// the client IP is rate limited, so forwarding headers are only believed
+	// from the configured proxies
+	if err := engine.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal(err)
+	}
+	handlers.RegisterRoutes(engine, sequencer, journal, cfg)
 	engine.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+
+	// requests see the shutdown through their context, so that the streams
+	// end and let the server shut down
+	server := &http.Server{Addr: ":8080", Handler: engine, BaseContext: func(net.Listener) context.Context { return ctx }}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
 	fmt.Println("Server started on port 8080")
-    http.ListenAndServe(":8080", engine)
+
+	<-ctx.Done()
+	server.Shutdown(context.Background())
+	stopSequencer()
+	<-sequencer.Done()
 }
@@ -0,0 +1,17 @@
+package models
+
+// Balance is what an account has of one asset. Available funds can be
+// withdrawn or committed to new orders; held funds are reserved for the open
+// orders of the account until they trade, are canceled or expire.
+type Balance struct {
+	Asset string `json:"asset" example:"USD"`
+	Available Decimal `json:"available" swaggertype:"string"`
+	Held Decimal `json:"held" swaggertype:"string"`
+}
+
+// Transfer moves funds of an asset into or out of an account.
+type Transfer struct {
+	AccountID string `json:"account_id"` // taken from the request path
+	Asset string `json:"asset" binding:"required" example:"USD"`
+	Amount Decimal `json:"amount" binding:"required,gt=0" example:"1000.0" swaggertype:"string"`
+}
@@ -0,0 +1,37 @@
+package models
+
+// We use a min heap of pending buy stop orders by stop price, so the first one
+// a rising price triggers is always on top. Orders with the same stop price
+// are kept in the order they were placed.
+type BuyStopHeap []*Order
+
+func (bh BuyStopHeap) Len() int {
+	return len(bh)
+}
+
+func (bh BuyStopHeap) Less(i int, j int) bool {
+	if bh[i].StopPrice != bh[j].StopPrice {
+		return bh[i].StopPrice < bh[j].StopPrice
+	}
+
+	return bh[i].Sequence < bh[j].Sequence
+}
+
+func (bh BuyStopHeap) Swap(i int, j int) {
+	bh[i], bh[j] = bh[j], bh[i]
+}
+
+func (bh *BuyStopHeap) Push(element any) {
+	order := element.(*Order)
+	*bh = append(*bh, order)
+}
+
+func (bh *BuyStopHeap) Pop() any {
+	old := *bh
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*bh = old[0 : n-1]
+
+	return item
+}
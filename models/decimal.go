@@ -0,0 +1,149 @@
+package models
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DecimalPlaces is the number of decimal places every Decimal carries.
+const DecimalPlaces = 8
+
+// decimalOne is 1 expressed in Decimal units.
+const decimalOne = 100_000_000
+
+const MaxDecimal Decimal = math.MaxInt64
+const MinDecimal Decimal = math.MinInt64
+
+// Decimal is a fixed-point number stored as an integer count of 10^-8 units,
+// so prices and amounts add, subtract and compare exactly and can be used as
+// map keys. In JSON it is written as a decimal string; decimal strings and
+// plain numbers are both accepted.
+type Decimal int64
+
+var errInvalidDecimal = errors.New("invalid decimal")
+
+var ErrDecimalOverflow = errors.New("decimal out of range")
+
+// ParseDecimal reads a plain decimal such as "100", "-0.25" or "3.14159265".
+// More than DecimalPlaces decimal places are refused rather than rounded.
+func ParseDecimal(value string) (Decimal, error) {
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(value, "-")
+
+	integer, fraction, hasPoint := strings.Cut(value, ".")
+	if integer == "" || (hasPoint && fraction == "") || len(fraction) > DecimalPlaces {
+		return 0, fmt.Errorf("%w: %q", errInvalidDecimal, value)
+	}
+
+	digits := integer + fraction + strings.Repeat("0", DecimalPlaces-len(fraction))
+	for _, digit := range digits {
+		if digit < '0' || digit > '9' {
+			return 0, fmt.Errorf("%w: %q", errInvalidDecimal, value)
+		}
+	}
+
+	units, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", errInvalidDecimal, value)
+	}
+
+	if negative {
+		units = -units
+	}
+
+	return Decimal(units), nil
+}
+
+// MustParseDecimal is like ParseDecimal but panics on invalid input. It is
+// meant for constants and tests.
+func MustParseDecimal(value string) Decimal {
+	d, err := ParseDecimal(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return d
+}
+
+// NewDecimal returns the whole number value as a Decimal.
+func NewDecimal(value int64) Decimal {
+	return Decimal(value * decimalOne)
+}
+
+// String writes the decimal without trailing zeros, e.g. "100" or "2.5".
+func (d Decimal) String() string {
+	units := int64(d)
+	sign := ""
+	if units < 0 {
+		sign = "-"
+	}
+
+	magnitude := uint64(units)
+	if units < 0 {
+		magnitude = -magnitude
+	}
+
+	integer := magnitude / decimalOne
+	fraction := magnitude % decimalOne
+	if fraction == 0 {
+		return sign + strconv.FormatUint(integer, 10)
+	}
+
+	fractionDigits := strings.TrimRight(fmt.Sprintf("%08d", fraction), "0")
+	return sign + strconv.FormatUint(integer, 10) + "." + fractionDigits
+}
+
+// IsMultipleOf reports whether the decimal is a whole multiple of step.
+func (d Decimal) IsMultipleOf(step Decimal) bool {
+	return step != 0 && d%step == 0
+}
+
+// Mul multiplies two decimals, such as a price by an amount. Digits beyond
+// DecimalPlaces are dropped; a product out of range fails with
+// ErrDecimalOverflow.
+func (d Decimal) Mul(other Decimal) (Decimal, error) {
+	return d.mul(other, false)
+}
+
+// MulUp is like Mul but rounds the dropped digits away from zero, for amounts
+// that must cover the exact product.
+func (d Decimal) MulUp(other Decimal) (Decimal, error) {
+	return d.mul(other, true)
+}
+
+func (d Decimal) mul(other Decimal, up bool) (Decimal, error) {
+	product := new(big.Int).Mul(big.NewInt(int64(d)), big.NewInt(int64(other)))
+	quotient, remainder := product.QuoRem(product, big.NewInt(decimalOne), new(big.Int))
+	if up && remainder.Sign() != 0 {
+		quotient.Add(quotient, big.NewInt(int64(remainder.Sign())))
+	}
+	if !quotient.IsInt64() {
+		return 0, ErrDecimalOverflow
+	}
+
+	return Decimal(quotient.Int64()), nil
+}
+
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + d.String() + `"`), nil
+}
+
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	value := string(bytes.Trim(data, `"`))
+	parsed, err := ParseDecimal(value)
+	if err != nil {
+		return err
+	}
+
+	*d = parsed
+	return nil
+}
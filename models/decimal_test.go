@@ -0,0 +1,81 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDecimal(t *testing.T) {
+	t.Parallel()
+
+	valid := map[string]Decimal{
+		"100":        NewDecimal(100),
+		"100.0":      NewDecimal(100),
+		"0.1":        Decimal(10_000_000),
+		"-2.5":       Decimal(-250_000_000),
+		"0.00000001": Decimal(1),
+	}
+	for value, expected := range valid {
+		parsed, err := ParseDecimal(value)
+		assert.Nil(t, err, value)
+		assert.Equal(t, expected, parsed, value)
+	}
+
+	for _, value := range []string{"", ".5", "1.", "1e3", "abc", "0.000000001", "0.30000000000000004", "99999999999999999999"} {
+		_, err := ParseDecimal(value)
+		assert.NotNil(t, err, value)
+	}
+}
+
+func TestDecimal_IsExact(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, MustParseDecimal("0.3"), MustParseDecimal("0.1")+MustParseDecimal("0.2"))
+}
+
+func TestDecimal_String(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "100", NewDecimal(100).String())
+	assert.Equal(t, "2.5", MustParseDecimal("2.50").String())
+	assert.Equal(t, "-0.001", MustParseDecimal("-0.001").String())
+}
+
+func TestDecimal_Mul(t *testing.T) {
+	t.Parallel()
+
+	product, err := MustParseDecimal("100.25").Mul(MustParseDecimal("2.5"))
+	assert.Nil(t, err)
+	assert.Equal(t, MustParseDecimal("250.625"), product)
+
+	// 0.00000003 x 0.5 is 0.000000015, one digit too many
+	product, _ = MustParseDecimal("0.00000003").Mul(MustParseDecimal("0.5"))
+	assert.Equal(t, MustParseDecimal("0.00000001"), product)
+	product, _ = MustParseDecimal("0.00000003").MulUp(MustParseDecimal("0.5"))
+	assert.Equal(t, MustParseDecimal("0.00000002"), product)
+
+	_, err = NewDecimal(10_000_000).Mul(NewDecimal(10_000_000))
+	assert.ErrorIs(t, err, ErrDecimalOverflow)
+}
+
+func TestDecimal_JSON(t *testing.T) {
+	t.Parallel()
+
+	var order struct {
+		Price  Decimal `json:"price"`
+		Amount Decimal `json:"amount"`
+	}
+
+	err := json.Unmarshal([]byte(`{"price": "100.25", "amount": 2.5}`), &order)
+	assert.Nil(t, err)
+	assert.Equal(t, MustParseDecimal("100.25"), order.Price)
+	assert.Equal(t, MustParseDecimal("2.5"), order.Amount)
+
+	encoded, _ := json.Marshal(order)
+	assert.Equal(t, `{"price":"100.25","amount":"2.5"}`, string(encoded))
+
+	err = json.Unmarshal([]byte(`{"price": "0.1.2"}`), &order)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,37 @@
+package models
+
+// We use a min heap of resting orders by expiry time, so the next order to
+// expire is always on top. Orders with the same expiry time, such as every
+// DAY order, expire in the order they were placed.
+type ExpiryHeap []*Order
+
+func (eh ExpiryHeap) Len() int {
+	return len(eh)
+}
+
+func (eh ExpiryHeap) Less(i int, j int) bool {
+	if !eh[i].ExpireAt.Equal(eh[j].ExpireAt) {
+		return eh[i].ExpireAt.Before(eh[j].ExpireAt)
+	}
+
+	return eh[i].Sequence < eh[j].Sequence
+}
+
+func (eh ExpiryHeap) Swap(i int, j int) {
+	eh[i], eh[j] = eh[j], eh[i]
+}
+
+func (eh *ExpiryHeap) Push(element any) {
+	order := element.(*Order)
+	*eh = append(*eh, order)
+}
+
+func (eh *ExpiryHeap) Pop() any {
+	old := *eh
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*eh = old[0 : n-1]
+
+	return item
+}
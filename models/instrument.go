@@ -0,0 +1,86 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrQuantityLimits = errors.New("amount is outside the instrument's quantity limits")
+
+// InstrumentStatus decides the trading state the market of an instrument is
+// listed in.
+type InstrumentStatus string
+
+const InstrumentTrading InstrumentStatus = "TRADING" // listed OPEN
+const InstrumentSuspended InstrumentStatus = "SUSPENDED" // listed CLOSED, until an admin resumes it
+
+// Instrument is a tradable symbol and the rules its orders must follow. Its
+// tick size and lot size decide how many decimal places its prices and
+// amounts have.
+type Instrument struct {
+	Symbol string `json:"symbol" binding:"required,max=32" example:"BTC-USD"`
+	BaseAsset string `json:"base_asset" binding:"required" example:"BTC"`
+	QuoteAsset string `json:"quote_asset" binding:"required" example:"USD"`
+	TickSize Decimal `json:"tick_size" binding:"required,gt=0" example:"0.01" swaggertype:"string"` // prices are multiples of it
+	LotSize Decimal `json:"lot_size" binding:"required,gt=0" example:"0.001" swaggertype:"string"` // amounts are multiples of it
+	MinQuantity Decimal `json:"min_quantity" binding:"omitempty,gt=0" example:"0.001" swaggertype:"string"`
+	// No upper limit when left out
+	MaxQuantity Decimal `json:"max_quantity" binding:"omitempty,gtfield=MinQuantity" example:"100.0" swaggertype:"string"`
+	// TRADING when left out
+	Status InstrumentStatus `json:"status" binding:"omitempty,oneof=TRADING SUSPENDED" example:"TRADING"`
+}
+
+// Validate checks an incoming order against the instrument's tick size, lot
+// size and quantity limits, and that its amount times its price, or its stop
+// price, can be held and settled without overflowing.
+func (i *Instrument) Validate(order *Order) error {
+	if !order.Price.IsMultipleOf(i.TickSize) || !order.StopPrice.IsMultipleOf(i.TickSize) || !order.ProtectionBand.IsMultipleOf(i.TickSize) {
+		return errors.New("prices must be a multiple of the tick size")
+	}
+
+	if !order.Amount.IsMultipleOf(i.LotSize) || !order.DisplayAmount.IsMultipleOf(i.LotSize) {
+		return errors.New("amounts must be a multiple of the lot size")
+	}
+
+	if err := i.CheckQuantity(order.Amount); err != nil {
+		return err
+	}
+
+	return CheckNotional(order.Amount, order.Price, order.StopPrice)
+}
+
+// ValidateAmendment checks the new price and amount of an amendment against
+// the instrument's tick size and lot size.
+func (i *Instrument) ValidateAmendment(amendment OrderAmendment) error {
+	if amendment.Price != nil && !amendment.Price.IsMultipleOf(i.TickSize) {
+		return errors.New("prices must be a multiple of the tick size")
+	}
+
+	if amendment.Amount != nil && !amendment.Amount.IsMultipleOf(i.LotSize) {
+		return errors.New("amounts must be a multiple of the lot size")
+	}
+
+	return nil
+}
+
+// CheckQuantity fails with ErrQuantityLimits when the total amount of an
+// order is outside the instrument's quantity limits.
+func (i *Instrument) CheckQuantity(amount Decimal) error {
+	if amount < i.MinQuantity || (i.MaxQuantity > 0 && amount > i.MaxQuantity) {
+		return ErrQuantityLimits
+	}
+
+	return nil
+}
+
+// CheckNotional fails with ErrDecimalOverflow when amount times one of the
+// prices is out of range.
+func CheckNotional(amount Decimal, prices ...Decimal) error {
+	for _, price := range prices {
+		if _, err := price.Mul(amount); err != nil {
+			return fmt.Errorf("notional of %s at %s: %w", amount, price, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// TradingState decides whether a market matches orders. Only an OPEN market
+// does; resting orders can be canceled in every state.
+type TradingState string
+
+const StateOpen TradingState = "OPEN"
+const StateHalted TradingState = "HALTED" // new orders are refused or queued until the market reopens
+const StateClosed TradingState = "CLOSED" // new orders are refused
+const StateAuction TradingState = "AUCTION" // new orders are queued until the market reopens
+
+// MarketState is the trading state of a market and since when it holds.
+type MarketState struct {
+	State TradingState `json:"state" example:"HALTED"`
+	// Why the market left OPEN: CIRCUIT_BREAKER, or MANUAL for an admin.
+	Reason string `json:"reason,omitempty" example:"CIRCUIT_BREAKER"`
+	Since time.Time `json:"since"`
+	// When a halt or an auction ends on its own; zero while it lasts until
+	// the market is resumed.
+	Until time.Time `json:"until,omitempty"`
+}
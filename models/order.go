@@ -1,13 +1,258 @@
 package models
 
+import (
+	"errors"
+	"time"
+)
+
 type OrderType string
 
 const Buy OrderType = "BUY"
 const Sell OrderType = "SELL"
 
+// OrderKind decides how an order trades. LIMIT orders trade at their price or
+// better and rest in the book; MARKET orders take whatever liquidity is on the
+// opposite side and never rest. STOP and STOP_LIMIT orders wait in the trigger
+// book until the last trade price reaches their stop price, and then trade as
+// a MARKET or LIMIT order respectively.
+type OrderKind string
+
+const Limit OrderKind = "LIMIT"
+const Market OrderKind = "MARKET"
+const Stop OrderKind = "STOP"
+const StopLimit OrderKind = "STOP_LIMIT"
+
+// TimeInForce decides how long an order stays in the book.
+type TimeInForce string
+
+const GoodTillCanceled TimeInForce = "GTC" // rests until filled or canceled
+const ImmediateOrCancel TimeInForce = "IOC" // fills what crosses on arrival, the rest is canceled
+const FillOrKill TimeInForce = "FOK" // fills completely on arrival or not at all
+const GoodTillDate TimeInForce = "GTD" // rests until its expire_at
+const Day TimeInForce = "DAY" // rests until the end of the trading session
+
+// PostOnly decides what happens to a post-only order that would trade on
+// arrival instead of resting in the book.
+type PostOnly string
+
+const PostOnlyReject PostOnly = "REJECT" // the order is rejected
+const PostOnlyReprice PostOnly = "REPRICE" // the order rests one tick behind the best opposite price
+
+// SelfTradePrevention decides what happens when an incoming order would trade
+// against a resting order of the same account.
+type SelfTradePrevention string
+
+const CancelNewest SelfTradePrevention = "CANCEL_NEWEST" // the incoming order is canceled
+const CancelOldest SelfTradePrevention = "CANCEL_OLDEST" // the resting order is canceled
+const CancelBoth SelfTradePrevention = "CANCEL_BOTH" // both orders are canceled
+const Decrement SelfTradePrevention = "DECREMENT" // both orders are reduced by the smaller remaining amount
+
+// OrderStatus is the lifecycle state of an accepted order. An order starts as
+// NEW, may become PARTIALLY_FILLED, and ends in exactly one of the terminal
+// states FILLED, CANCELED, REJECTED or EXPIRED.
+type OrderStatus string
+
+const StatusNew OrderStatus = "NEW"
+const StatusPartiallyFilled OrderStatus = "PARTIALLY_FILLED"
+const StatusFilled OrderStatus = "FILLED"
+const StatusCanceled OrderStatus = "CANCELED"
+const StatusRejected OrderStatus = "REJECTED"
+const StatusExpired OrderStatus = "EXPIRED"
+
 type Order struct {
 	ID string `json:"uuid" binding:"required,uuid4" example:"550e8400-e29b-41d4-a716-646655440000"`
+	Symbol string `json:"symbol" example:"BTC-USD"` // taken from the request path
 	Action OrderType `json:"action" binding:"required,oneof=BUY SELL"`
-	Price float64 `json:"price" binding:"required" example:"100.0"`
-	Amount float64 `json:"amount" binding:"required" example:"10.0"`
+	Kind OrderKind `json:"type" binding:"omitempty,oneof=LIMIT MARKET STOP STOP_LIMIT" example:"LIMIT"` // LIMIT when left out
+	// Required for LIMIT and STOP_LIMIT orders, not allowed for MARKET and STOP orders.
+	Price Decimal `json:"price" binding:"omitempty,gt=0" example:"100.0" swaggertype:"string"`
+	Amount Decimal `json:"amount" binding:"required,gt=0" example:"10.0" swaggertype:"string"`
+	// Required for STOP and STOP_LIMIT orders: a buy stop triggers once the last
+	// trade price is at or above it, a sell stop once it is at or below it.
+	StopPrice Decimal `json:"stop_price,omitempty" binding:"omitempty,gt=0" example:"105.0" swaggertype:"string"`
+	// Set for orders that must not trade on arrival.
+	PostOnly PostOnly `json:"post_only,omitempty" binding:"omitempty,oneof=REJECT REPRICE" example:"REJECT"`
+	// The account the order holds its funds from, taken from the API key.
+	// Orders of the same account never trade against each other.
+	AccountID string `json:"account_id,omitempty" example:"account-1"`
+	// Applied when the order would trade against a resting order of the same
+	// account; CANCEL_NEWEST when left out.
+	SelfTradePrevention SelfTradePrevention `json:"stp_mode,omitempty" binding:"omitempty,oneof=CANCEL_NEWEST CANCEL_OLDEST CANCEL_BOTH DECREMENT" example:"CANCEL_NEWEST"`
+	// Makes the order an iceberg: only this much of it is shown in the order book
+	// at a time, the rest is held in reserve.
+	DisplayAmount Decimal `json:"display_amount,omitempty" binding:"omitempty,gt=0,ltfield=Amount" example:"1.0" swaggertype:"string"`
+	// Optional for MARKET orders: the furthest the order may trade from the best
+	// opposite price at the time it arrives.
+	ProtectionBand Decimal `json:"protection_band,omitempty" binding:"omitempty,gt=0" example:"0.5" swaggertype:"string"`
+	// GTC for LIMIT and IOC for MARKET orders when left out
+	TimeInForce TimeInForce `json:"time_in_force" binding:"omitempty,oneof=GTC IOC FOK GTD DAY" example:"GTC"`
+	// Required for GTD orders; set by the order book for DAY orders.
+	ExpireAt time.Time `json:"expire_at,omitempty" example:"2025-03-01T16:00:00Z"`
+
+	// maintained by the order book once the order is accepted
+	Sequence uint64 `json:"sequence"` // sequence number of the order placement
+	Status OrderStatus `json:"status"`
+	FilledAmount Decimal `json:"filled_amount" swaggertype:"string"`
+	RemainingAmount Decimal `json:"remaining_amount" swaggertype:"string"`
+	VisibleAmount Decimal `json:"visible_amount" swaggertype:"string"` // the part of the remaining amount shown in the order book
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	TriggeredAt time.Time `json:"triggered_at,omitempty"` // when a STOP or STOP_LIMIT order left the trigger book
+	// Why a REJECTED order was refused: POST_ONLY, or the code of the risk
+	// check it failed, such as MAX_NOTIONAL.
+	RejectReason string `json:"reject_reason,omitempty" example:"PRICE_COLLAR"`
+}
+
+// Validate checks the rules that span several fields of an incoming order and
+// cannot be expressed as binding tags.
+func (o *Order) Validate(now time.Time) error {
+	if o.IsMarket() {
+		if o.Price != 0 {
+			return errors.New("price is not allowed for MARKET and STOP orders")
+		}
+	} else if o.Price == 0 {
+		return errors.New("price is required for LIMIT and STOP_LIMIT orders")
+	}
+
+	if o.IsStop() {
+		if o.StopPrice == 0 {
+			return errors.New("stop_price is required for STOP and STOP_LIMIT orders")
+		}
+	} else if o.StopPrice != 0 {
+		return errors.New("stop_price is only allowed for STOP and STOP_LIMIT orders")
+	}
+
+	if o.DisplayAmount != 0 && !o.Rests() {
+		return errors.New("display_amount is only allowed for orders that rest in the book")
+	}
+
+	if o.PostOnly != "" && !o.Rests() {
+		return errors.New("post_only is only allowed for orders that rest in the book")
+	}
+
+	if o.IsMarket() && (o.TimeInForce == GoodTillCanceled || o.TimeInForce == GoodTillDate || o.TimeInForce == Day) {
+		return errors.New("market orders never rest and must be IOC or FOK")
+	}
+
+	if o.TimeInForce == GoodTillDate {
+		if !o.ExpireAt.After(now) {
+			return errors.New("GTD orders need an expire_at in the future")
+		}
+	} else if !o.ExpireAt.IsZero() {
+		return errors.New("expire_at is only allowed for GTD orders")
+	}
+
+	return nil
+}
+
+// Accept resets the order's lifecycle fields for a newly accepted order.
+func (o *Order) Accept(timestamp time.Time) {
+	if o.Kind == "" {
+		o.Kind = Limit
+	}
+	if o.TimeInForce == "" {
+		o.TimeInForce = GoodTillCanceled
+		if o.IsMarket() {
+			o.TimeInForce = ImmediateOrCancel
+		}
+	}
+	if o.SelfTradePrevention == "" && o.AccountID != "" {
+		o.SelfTradePrevention = CancelNewest
+	}
+	o.Status = StatusNew
+	o.RejectReason = ""
+	o.FilledAmount = 0
+	o.RemainingAmount = o.Amount
+	o.Replenish()
+	o.CreatedAt = timestamp
+	o.UpdatedAt = timestamp
+	o.TriggeredAt = time.Time{}
+}
+
+// Fill records an execution of amount against the order.
+func (o *Order) Fill(amount Decimal, timestamp time.Time) {
+	o.FilledAmount += amount
+	o.RemainingAmount -= amount
+	o.VisibleAmount = max(o.VisibleAmount-amount, 0)
+	o.UpdatedAt = timestamp
+
+	if o.RemainingAmount <= 0 {
+		o.RemainingAmount = 0
+		o.Status = StatusFilled
+	} else {
+		o.Status = StatusPartiallyFilled
+	}
+}
+
+// Resize changes the remaining amount of an open order. The total amount
+// follows so that it always equals filled plus remaining.
+func (o *Order) Resize(remaining Decimal, timestamp time.Time) {
+	o.RemainingAmount = remaining
+	o.VisibleAmount = min(o.VisibleAmount, remaining)
+	o.Amount = o.FilledAmount + remaining
+	o.UpdatedAt = timestamp
+}
+
+// Replenish shows a new peak of an iceberg order, taken from its reserve. For
+// any other order the whole remaining amount is visible.
+func (o *Order) Replenish() {
+	o.VisibleAmount = o.RemainingAmount
+	if o.DisplayAmount > 0 {
+		o.VisibleAmount = min(o.DisplayAmount, o.RemainingAmount)
+	}
+}
+
+// Close moves an open order to a terminal status such as CANCELED, REJECTED
+// or EXPIRED. Closing an order that is no longer open has no effect.
+func (o *Order) Close(status OrderStatus, timestamp time.Time) {
+	if !o.IsOpen() {
+		return
+	}
+
+	o.Status = status
+	o.UpdatedAt = timestamp
+}
+
+// Rests reports whether whatever is left of the order after matching on
+// arrival stays in the book.
+func (o *Order) Rests() bool {
+	return !o.IsMarket() && o.TimeInForce != ImmediateOrCancel && o.TimeInForce != FillOrKill
+}
+
+// IsMarket reports whether the order trades like a MARKET order, without a
+// limit price.
+func (o *Order) IsMarket() bool {
+	return o.Kind == Market || o.Kind == Stop
+}
+
+// IsStop reports whether the order waits for its stop price before trading.
+func (o *Order) IsStop() bool {
+	return o.Kind == Stop || o.Kind == StopLimit
+}
+
+// SelfTrades reports whether the order would trade against a resting order of
+// its own account.
+func (o *Order) SelfTrades(resting *Order) bool {
+	return o.AccountID != "" && o.AccountID == resting.AccountID
+}
+
+// Triggers reports whether a trade at lastPrice reaches the order's stop price.
+func (o *Order) Triggers(lastPrice Decimal) bool {
+	if o.Action == Buy {
+		return lastPrice >= o.StopPrice
+	}
+
+	return lastPrice <= o.StopPrice
+}
+
+// IsOpen reports whether the order can still trade.
+func (o *Order) IsOpen() bool {
+	return o.Status == StatusNew || o.Status == StatusPartiallyFilled
+}
+
+// OrderAmendment holds the changes requested for a resting order. Fields left
+// out of the request keep their current value.
+type OrderAmendment struct {
+	Price *Decimal `json:"price" binding:"omitempty,gt=0" example:"101.0" swaggertype:"string"`
+	Amount *Decimal `json:"amount" binding:"omitempty,gt=0" example:"5.0" swaggertype:"string"`
 }
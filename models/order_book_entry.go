@@ -1,7 +1,7 @@
 package models
 
 type OrderBookEntry struct {
-	Price float64 `json:"price"`
-	Liquidity float64 `json:"liquidity"`
+	Price Decimal `json:"price" swaggertype:"string"`
+	Liquidity Decimal `json:"liquidity" swaggertype:"string"`
 	Type OrderType `json:"type"`
 }
\ No newline at end of file
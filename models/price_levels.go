@@ -0,0 +1,242 @@
+package models
+
+import (
+	"iter"
+	"math/bits"
+)
+
+// PriceLevel is the queue of resting orders at one price, oldest first. It
+// keeps the total visible and remaining amount of its orders, so depth
+// queries do not have to walk the queue.
+type PriceLevel struct {
+	Price Decimal
+
+	orders []*Order
+	liquidity Decimal // sum of VisibleAmount
+	remaining Decimal // sum of RemainingAmount
+}
+
+// Orders returns the queue of the level, oldest first. The slice must not be
+// modified.
+func (pl *PriceLevel) Orders() []*Order {
+	return pl.orders
+}
+
+func (pl *PriceLevel) Len() int {
+	return len(pl.orders)
+}
+
+// Liquidity is the amount shown at the level: only the current peak of
+// iceberg orders counts.
+func (pl *PriceLevel) Liquidity() Decimal {
+	return pl.liquidity
+}
+
+// Remaining is the total amount resting at the level, hidden reserve included.
+func (pl *PriceLevel) Remaining() Decimal {
+	return pl.remaining
+}
+
+// Front returns the oldest order of the level.
+func (pl *PriceLevel) Front() *Order {
+	return pl.orders[0]
+}
+
+// Push adds an order to the back of the queue.
+func (pl *PriceLevel) Push(order *Order) {
+	pl.orders = append(pl.orders, order)
+	pl.liquidity += order.VisibleAmount
+	pl.remaining += order.RemainingAmount
+}
+
+// PopFront takes the oldest order out of the queue.
+func (pl *PriceLevel) PopFront() *Order {
+	order := pl.orders[0]
+	pl.orders[0] = nil
+	pl.orders = pl.orders[1:]
+	pl.liquidity -= order.VisibleAmount
+	pl.remaining -= order.RemainingAmount
+
+	return order
+}
+
+// Remove takes an arbitrary order out of the queue.
+func (pl *PriceLevel) Remove(order *Order) {
+	for index, resting := range pl.orders {
+		if resting == order {
+			pl.orders = append(pl.orders[:index], pl.orders[index+1:]...)
+			pl.liquidity -= order.VisibleAmount
+			pl.remaining -= order.RemainingAmount
+			return
+		}
+	}
+}
+
+// Update applies change to one of the level's orders and keeps the level's
+// totals in step with it.
+func (pl *PriceLevel) Update(order *Order, change func()) {
+	visible, remaining := order.VisibleAmount, order.RemainingAmount
+	change()
+	pl.liquidity += order.VisibleAmount - visible
+	pl.remaining += order.RemainingAmount - remaining
+}
+
+// maxSkipHeight bounds the skip list's height; with a branching factor of 4
+// it comfortably covers millions of levels.
+const maxSkipHeight = 16
+
+type skipNode struct {
+	level *PriceLevel
+	next []*skipNode
+}
+
+// PriceLevels is one side of the order book: its price levels kept in a skip
+// list ordered from the best price to the worst, with a map for direct
+// lookup. Levels are inserted and removed in O(log n), found by price in O(1)
+// and iterated in price order.
+type PriceLevels struct {
+	descending bool // bids are ordered from the highest price, asks from the lowest
+	head skipNode
+	height int
+	byPrice map[Decimal]*PriceLevel
+	seed uint64 // drives the node heights; fixed, so the shape of the list is reproducible
+}
+
+// NewBuyLevels returns an empty bid side, best (highest) price first.
+func NewBuyLevels() *PriceLevels {
+	return newPriceLevels(true)
+}
+
+// NewSellLevels returns an empty ask side, best (lowest) price first.
+func NewSellLevels() *PriceLevels {
+	return newPriceLevels(false)
+}
+
+func newPriceLevels(descending bool) *PriceLevels {
+	return &PriceLevels{
+		descending: descending,
+		head: skipNode{next: make([]*skipNode, maxSkipHeight)},
+		height: 1,
+		byPrice: make(map[Decimal]*PriceLevel),
+		seed: 0x9e3779b97f4a7c15,
+	}
+}
+
+// Len is the number of price levels.
+func (pls *PriceLevels) Len() int {
+	return len(pls.byPrice)
+}
+
+// Best returns the level with the best price, or nil if the side is empty.
+func (pls *PriceLevels) Best() *PriceLevel {
+	if pls.head.next[0] == nil {
+		return nil
+	}
+
+	return pls.head.next[0].level
+}
+
+// Get returns the level at price, or nil if there is none.
+func (pls *PriceLevels) Get(price Decimal) *PriceLevel {
+	return pls.byPrice[price]
+}
+
+// Orders returns the queue at price, or nil if there is no such level.
+func (pls *PriceLevels) Orders(price Decimal) []*Order {
+	if level, exists := pls.byPrice[price]; exists {
+		return level.orders
+	}
+
+	return nil
+}
+
+// GetOrCreate returns the level at price, inserting an empty one if needed.
+func (pls *PriceLevels) GetOrCreate(price Decimal) *PriceLevel {
+	if level, exists := pls.byPrice[price]; exists {
+		return level
+	}
+
+	var update [maxSkipHeight]*skipNode
+	node := &pls.head
+	for i := pls.height - 1; i >= 0; i-- {
+		for node.next[i] != nil && pls.before(node.next[i].level.Price, price) {
+			node = node.next[i]
+		}
+		update[i] = node
+	}
+
+	height := pls.randomHeight()
+	for i := pls.height; i < height; i++ {
+		update[i] = &pls.head
+	}
+	if height > pls.height {
+		pls.height = height
+	}
+
+	level := &PriceLevel{Price: price}
+	inserted := &skipNode{level: level, next: make([]*skipNode, height)}
+	for i := 0; i < height; i++ {
+		inserted.next[i] = update[i].next[i]
+		update[i].next[i] = inserted
+	}
+	pls.byPrice[price] = level
+
+	return level
+}
+
+// Remove deletes the level at price, whatever orders it still holds.
+func (pls *PriceLevels) Remove(price Decimal) {
+	if _, exists := pls.byPrice[price]; !exists {
+		return
+	}
+	delete(pls.byPrice, price)
+
+	node := &pls.head
+	for i := pls.height - 1; i >= 0; i-- {
+		for node.next[i] != nil && pls.before(node.next[i].level.Price, price) {
+			node = node.next[i]
+		}
+		if node.next[i] != nil && node.next[i].level.Price == price {
+			node.next[i] = node.next[i].next[i]
+		}
+	}
+
+	for pls.height > 1 && pls.head.next[pls.height-1] == nil {
+		pls.height--
+	}
+}
+
+// All iterates over the levels from the best price to the worst.
+func (pls *PriceLevels) All() iter.Seq[*PriceLevel] {
+	return func(yield func(*PriceLevel) bool) {
+		for node := pls.head.next[0]; node != nil; node = node.next[0] {
+			if !yield(node.level) {
+				return
+			}
+		}
+	}
+}
+
+// before reports whether price a is better than price b on this side.
+func (pls *PriceLevels) before(a Decimal, b Decimal) bool {
+	if pls.descending {
+		return a > b
+	}
+
+	return a < b
+}
+
+// randomHeight draws a node height where each extra level has a one in four
+// chance, from a xorshift generator with a fixed seed.
+func (pls *PriceLevels) randomHeight() int {
+	pls.seed ^= pls.seed << 13
+	pls.seed ^= pls.seed >> 7
+	pls.seed ^= pls.seed << 17
+
+	height := 1 + bits.TrailingZeros64(pls.seed)/2
+	if height > maxSkipHeight {
+		height = maxSkipHeight
+	}
+
+	return height
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func levelPrices(levels *PriceLevels) []Decimal {
+	var prices []Decimal
+	for level := range levels.All() {
+		prices = append(prices, level.Price)
+	}
+
+	return prices
+}
+
+func TestPriceLevels(t *testing.T) {
+	t.Parallel()
+
+	t.Run("bids are kept from the highest price down", func(t *testing.T) {
+		levels := NewBuyLevels()
+		for _, price := range []int64{100, 98, 102, 99, 101} {
+			levels.GetOrCreate(NewDecimal(price))
+		}
+
+		assert.Equal(t, 5, levels.Len())
+		assert.Equal(t, NewDecimal(102), levels.Best().Price)
+		assert.Equal(t, []Decimal{NewDecimal(102), NewDecimal(101), NewDecimal(100), NewDecimal(99), NewDecimal(98)}, levelPrices(levels))
+	})
+
+	t.Run("asks are kept from the lowest price up", func(t *testing.T) {
+		levels := NewSellLevels()
+		for _, price := range []int64{100, 98, 102, 99, 101} {
+			levels.GetOrCreate(NewDecimal(price))
+		}
+
+		assert.Equal(t, NewDecimal(98), levels.Best().Price)
+		assert.Equal(t, []Decimal{NewDecimal(98), NewDecimal(99), NewDecimal(100), NewDecimal(101), NewDecimal(102)}, levelPrices(levels))
+	})
+
+	t.Run("an existing level is reused", func(t *testing.T) {
+		levels := NewSellLevels()
+		level := levels.GetOrCreate(NewDecimal(100))
+
+		assert.Same(t, level, levels.GetOrCreate(NewDecimal(100)))
+		assert.Equal(t, 1, levels.Len())
+	})
+
+	t.Run("any level can be removed", func(t *testing.T) {
+		levels := NewSellLevels()
+		for price := int64(1); price <= 1000; price++ {
+			levels.GetOrCreate(NewDecimal(price))
+		}
+		for price := int64(2); price <= 1000; price += 2 {
+			levels.Remove(NewDecimal(price))
+		}
+		levels.Remove(NewDecimal(5000))
+
+		prices := levelPrices(levels)
+		assert.Equal(t, 500, levels.Len())
+		assert.Len(t, prices, 500)
+		for index, price := range prices {
+			assert.Equal(t, NewDecimal(int64(2*index+1)), price)
+		}
+		assert.Nil(t, levels.Get(NewDecimal(2)))
+		assert.Nil(t, levels.Orders(NewDecimal(2)))
+	})
+
+	t.Run("an empty side has no best level", func(t *testing.T) {
+		levels := NewBuyLevels()
+		levels.GetOrCreate(NewDecimal(100))
+		levels.Remove(NewDecimal(100))
+
+		assert.Nil(t, levels.Best())
+		assert.Equal(t, 0, levels.Len())
+	})
+}
+
+func TestPriceLevel(t *testing.T) {
+	t.Parallel()
+
+	newOrder := func(id string, amount int64, visible int64) *Order {
+		return &Order{ID: id, RemainingAmount: NewDecimal(amount), VisibleAmount: NewDecimal(visible)}
+	}
+
+	t.Run("orders queue in arrival order and the totals follow them", func(t *testing.T) {
+		level := &PriceLevel{Price: NewDecimal(100)}
+		first, second, third := newOrder("1", 2, 2), newOrder("2", 10, 1), newOrder("3", 3, 3)
+		level.Push(first)
+		level.Push(second)
+		level.Push(third)
+
+		assert.Equal(t, 3, level.Len())
+		assert.Same(t, first, level.Front())
+		assert.Equal(t, NewDecimal(6), level.Liquidity(), "only the peak of the iceberg order is shown")
+		assert.Equal(t, NewDecimal(15), level.Remaining())
+
+		level.Remove(second)
+		assert.Equal(t, []*Order{first, third}, level.Orders())
+		assert.Equal(t, NewDecimal(5), level.Liquidity())
+
+		assert.Same(t, first, level.PopFront())
+		assert.Same(t, third, level.Front())
+		assert.Equal(t, NewDecimal(3), level.Remaining())
+	})
+
+	t.Run("an update keeps the totals in step", func(t *testing.T) {
+		level := &PriceLevel{Price: NewDecimal(100)}
+		order := newOrder("1", 5, 5)
+		level.Push(order)
+
+		level.Update(order, func() {
+			order.RemainingAmount = NewDecimal(2)
+			order.VisibleAmount = NewDecimal(2)
+		})
+
+		assert.Equal(t, NewDecimal(2), level.Liquidity())
+		assert.Equal(t, NewDecimal(2), level.Remaining())
+	})
+}
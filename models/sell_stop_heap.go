@@ -0,0 +1,37 @@
+package models
+
+// We use a max heap of pending sell stop orders by stop price, so the first
+// one a falling price triggers is always on top. Orders with the same stop
+// price are kept in the order they were placed.
+type SellStopHeap []*Order
+
+func (sh SellStopHeap) Len() int {
+	return len(sh)
+}
+
+func (sh SellStopHeap) Less(i int, j int) bool {
+	if sh[i].StopPrice != sh[j].StopPrice {
+		return sh[i].StopPrice > sh[j].StopPrice
+	}
+
+	return sh[i].Sequence < sh[j].Sequence
+}
+
+func (sh SellStopHeap) Swap(i int, j int) {
+	sh[i], sh[j] = sh[j], sh[i]
+}
+
+func (sh *SellStopHeap) Push(element any) {
+	order := element.(*Order)
+	*sh = append(*sh, order)
+}
+
+func (sh *SellStopHeap) Pop() any {
+	old := *sh
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*sh = old[0 : n-1]
+
+	return item
+}
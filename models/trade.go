@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Trade is a single execution between an incoming (taker) order and a resting
+// (maker) order. It executes at the maker's price, except in the uncross of
+// a market reopening, where every trade is at the uncross price.
+type Trade struct {
+	ID            uint64    `json:"id"`
+	Symbol        string    `json:"symbol"`
+	MakerOrderID  string    `json:"maker_order_uuid"`
+	TakerOrderID  string    `json:"taker_order_uuid"`
+	Price         Decimal   `json:"price" swaggertype:"string"`
+	Amount        Decimal   `json:"amount" swaggertype:"string"`
+	AggressorSide OrderType `json:"aggressor_side"`
+	Timestamp     time.Time `json:"timestamp"`
+	Sequence      uint64    `json:"sequence"` // sequence number of the order placement that produced the trade
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"order-matching/models"
+	"sync"
+	"time"
+)
+
+var (
+	ErrRepositoryClosed = errors.New("repository is closed")
+	ErrRepositoryFull = errors.New("too many repository writes are waiting")
+)
+
+// maxRetryPause bounds the pause between attempts at a failing write.
+const maxRetryPause = time.Minute
+
+// AsyncRepository writes to another repository from a goroutine of its own,
+// so that the sequencer never waits on the database. Writes are made in the
+// order they were saved. A failed write is reported and retried, after a
+// pause that doubles up to a minute, and holds back the writes behind it so
+// that an older state of an order never overwrites a newer one; a write the
+// store refuses with ErrWriteRefused is reported and dropped instead. Once
+// limit writes are waiting, further ones are refused with ErrRepositoryFull.
+// It is safe for concurrent use.
+type AsyncRepository struct {
+	store Repository
+	retry time.Duration // pause before the first retry of a failed write
+	limit int // how many writes may wait
+	mutex sync.Mutex
+	queue []func(Repository) error
+	closed bool
+	wake chan struct{}
+	closing chan struct{}
+	done chan struct{}
+}
+
+// NewAsyncRepository starts writing to store in the background, with room
+// for limit writes to wait.
+func NewAsyncRepository(store Repository, retry time.Duration, limit int) *AsyncRepository {
+	ar := &AsyncRepository{
+		store: store,
+		retry: retry,
+		limit: limit,
+		wake: make(chan struct{}, 1),
+		closing: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go ar.run()
+
+	return ar
+}
+
+func (ar *AsyncRepository) SaveInstrument(instrument models.Instrument) error {
+	return ar.enqueue(func(store Repository) error {
+		return store.SaveInstrument(instrument)
+	})
+}
+
+func (ar *AsyncRepository) SaveOrders(orders ...models.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	orders = append([]models.Order{}, orders...)
+	return ar.enqueue(func(store Repository) error {
+		return store.SaveOrders(orders...)
+	})
+}
+
+func (ar *AsyncRepository) SaveTrades(trades ...models.Trade) error {
+	if len(trades) == 0 {
+		return nil
+	}
+
+	trades = append([]models.Trade{}, trades...)
+	return ar.enqueue(func(store Repository) error {
+		return store.SaveTrades(trades...)
+	})
+}
+
+// Pending is the number of writes not made yet.
+func (ar *AsyncRepository) Pending() int {
+	ar.mutex.Lock()
+	defer ar.mutex.Unlock()
+
+	return len(ar.queue)
+}
+
+// Close makes the writes still queued, giving up on those that fail, and
+// closes the repository written to.
+func (ar *AsyncRepository) Close() error {
+	ar.mutex.Lock()
+	if ar.closed {
+		ar.mutex.Unlock()
+		return ErrRepositoryClosed
+	}
+	ar.closed = true
+	ar.mutex.Unlock()
+
+	close(ar.closing)
+	<-ar.done
+
+	return ar.store.Close()
+}
+
+func (ar *AsyncRepository) enqueue(write func(Repository) error) error {
+	ar.mutex.Lock()
+	defer ar.mutex.Unlock()
+
+	if ar.closed {
+		return ErrRepositoryClosed
+	}
+	if len(ar.queue) >= ar.limit {
+		return ErrRepositoryFull
+	}
+	ar.queue = append(ar.queue, write)
+
+	select {
+	case ar.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func (ar *AsyncRepository) run() {
+	defer close(ar.done)
+
+	for {
+		select {
+		case <-ar.wake:
+			ar.drain()
+		case <-ar.closing:
+			ar.drain()
+			return
+		}
+	}
+}
+
+// drain makes the queued writes until the queue is empty.
+func (ar *AsyncRepository) drain() {
+	pause := ar.retry
+	for {
+		ar.mutex.Lock()
+		if len(ar.queue) == 0 {
+			ar.mutex.Unlock()
+			return
+		}
+		write := ar.queue[0]
+		waiting := len(ar.queue) - 1
+		ar.mutex.Unlock()
+
+		err := write(ar.store)
+		if errors.Is(err, ErrWriteRefused) {
+			fmt.Printf("repository write dropped: %s\n", err)
+		} else if err != nil {
+			select {
+			case <-ar.closing:
+				fmt.Printf("repository write given up at shutdown: %s\n", err)
+			default:
+				fmt.Printf("repository write failed, retrying in %s with %d writes waiting: %s\n", pause, waiting, err)
+				select {
+				case <-time.After(pause):
+				case <-ar.closing:
+				}
+				pause = min(2*pause, maxRetryPause)
+				continue
+			}
+		}
+
+		pause = ar.retry
+		ar.mutex.Lock()
+		ar.queue[0] = nil
+		ar.queue = ar.queue[1:]
+		ar.mutex.Unlock()
+	}
+}
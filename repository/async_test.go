@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"errors"
+	"order-matching/models"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsyncRepository(t *testing.T) {
+	t.Parallel()
+
+	order := models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Symbol: "BTC-USD", Status: models.StatusNew}
+	filled := order
+	filled.Status = models.StatusFilled
+
+	t.Run("It writes everything saved by the time it is closed", func(t *testing.T) {
+		t.Parallel()
+		store := &flakyStore{}
+		repo := NewAsyncRepository(store, time.Millisecond, 10)
+
+		assert.Nil(t, repo.SaveInstrument(models.Instrument{Symbol: "BTC-USD"}))
+		assert.Nil(t, repo.SaveOrders(order))
+		assert.Nil(t, repo.SaveTrades(models.Trade{ID: 1, Symbol: "BTC-USD"}))
+		assert.Nil(t, repo.Close())
+
+		assert.Equal(t, []string{"instrument", "order NEW", "trade"}, store.written())
+		assert.True(t, store.closed)
+		assert.ErrorIs(t, repo.SaveOrders(filled), ErrRepositoryClosed)
+	})
+
+	t.Run("It retries a failed write before the writes behind it", func(t *testing.T) {
+		t.Parallel()
+		store := &flakyStore{failures: 2}
+		repo := NewAsyncRepository(store, time.Millisecond, 10)
+
+		repo.SaveOrders(order)
+		repo.SaveOrders(filled)
+		assert.Eventually(t, func() bool {
+			return repo.Pending() == 0
+		}, time.Second, time.Millisecond)
+		repo.Close()
+
+		assert.Equal(t, []string{"order NEW", "order FILLED"}, store.written())
+		assert.Equal(t, 4, store.attempts, "two failed attempts and two writes")
+	})
+
+	t.Run("It drops a write the store refuses for good", func(t *testing.T) {
+		t.Parallel()
+		store := &flakyStore{failures: 1, failure: ErrWriteRefused}
+		repo := NewAsyncRepository(store, time.Hour, 10)
+
+		repo.SaveOrders(order)
+		repo.SaveOrders(filled)
+		assert.Eventually(t, func() bool {
+			return repo.Pending() == 0
+		}, time.Second, time.Millisecond)
+		repo.Close()
+
+		assert.Equal(t, []string{"order FILLED"}, store.written())
+	})
+
+	t.Run("It refuses writes beyond its limit", func(t *testing.T) {
+		t.Parallel()
+		store := &flakyStore{failures: 1}
+		repo := NewAsyncRepository(store, time.Hour, 1)
+
+		assert.Nil(t, repo.SaveOrders(order))
+		assert.ErrorIs(t, repo.SaveOrders(filled), ErrRepositoryFull)
+		repo.Close()
+	})
+}
+
+// flakyStore fails its first writes and records those that succeed.
+type flakyStore struct {
+	mutex sync.Mutex
+	failures int
+	failure error // what the failures fail with, a retryable error if nil
+	attempts int
+	writes []string
+	closed bool
+}
+
+func (fs *flakyStore) write(write string) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	fs.attempts++
+	if fs.failures > 0 {
+		fs.failures--
+		if fs.failure != nil {
+			return fs.failure
+		}
+		return errors.New("database is locked")
+	}
+	fs.writes = append(fs.writes, write)
+
+	return nil
+}
+
+func (fs *flakyStore) written() []string {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	return fs.writes
+}
+
+func (fs *flakyStore) SaveInstrument(instrument models.Instrument) error {
+	return fs.write("instrument")
+}
+
+func (fs *flakyStore) SaveOrders(orders ...models.Order) error {
+	return fs.write("order " + string(orders[0].Status))
+}
+
+func (fs *flakyStore) SaveTrades(trades ...models.Trade) error {
+	return fs.write("trade")
+}
+
+func (fs *flakyStore) Close() error {
+	fs.closed = true
+	return nil
+}
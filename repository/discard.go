@@ -0,0 +1,24 @@
+package repository
+
+import "order-matching/models"
+
+// DiscardRepository stores nothing. It is the repository of a service that is
+// not reported on: the API answers from the order books, which are recovered
+// from the journal and snapshots.
+type DiscardRepository struct{}
+
+func (DiscardRepository) SaveInstrument(instrument models.Instrument) error {
+	return nil
+}
+
+func (DiscardRepository) SaveOrders(orders ...models.Order) error {
+	return nil
+}
+
+func (DiscardRepository) SaveTrades(trades ...models.Trade) error {
+	return nil
+}
+
+func (DiscardRepository) Close() error {
+	return nil
+}
@@ -0,0 +1,44 @@
+// Package repository stores the instruments, orders and trades of the order
+// books outside the matching engine, where reporting tools can query them.
+// The engine keeps working from its own state; every change it makes is
+// written through to the repository after it is applied, in the background.
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"order-matching/models"
+)
+
+// ErrWriteRefused marks a write the store refused for good, such as one that
+// breaks a constraint or does not fit the schema: retrying it cannot help.
+var ErrWriteRefused = errors.New("write refused")
+
+// Repository is a store of instrument configuration, orders and trades.
+// Saving is idempotent: an instrument or order saved again replaces the one
+// stored under the same symbol or ID, and a trade replaces the one with the
+// same symbol and trade ID, so the journal can be replayed over it.
+type Repository interface {
+	SaveInstrument(instrument models.Instrument) error
+	// SaveOrders stores the current state of the orders.
+	SaveOrders(orders ...models.Order) error
+	SaveTrades(trades ...models.Trade) error
+	Close() error
+}
+
+const None = "none"
+const SQLite = "sqlite"
+
+// Open returns the repository of the given kind: "none", which stores
+// nothing, or "sqlite", which stores everything in the SQLite database at
+// path.
+func Open(kind string, path string) (Repository, error) {
+	switch kind {
+	case None:
+		return DiscardRepository{}, nil
+	case SQLite:
+		return OpenSQLiteRepository(path)
+	default:
+		return nil, fmt.Errorf("unknown repository %q", kind)
+	}
+}
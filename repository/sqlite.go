@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"order-matching/models"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteSchema creates the tables on first use. Prices and amounts are stored
+// as decimal strings, so they keep every decimal place, and timestamps as
+// RFC 3339 strings in UTC; times an order does not have are NULL.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS instruments (
+	symbol TEXT PRIMARY KEY,
+	base_asset TEXT NOT NULL,
+	quote_asset TEXT NOT NULL,
+	tick_size TEXT NOT NULL,
+	lot_size TEXT NOT NULL,
+	min_quantity TEXT NOT NULL,
+	max_quantity TEXT NOT NULL,
+	status TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS orders (
+	uuid TEXT PRIMARY KEY,
+	symbol TEXT NOT NULL,
+	sequence INTEGER NOT NULL,
+	action TEXT NOT NULL,
+	type TEXT NOT NULL,
+	price TEXT NOT NULL,
+	amount TEXT NOT NULL,
+	stop_price TEXT NOT NULL,
+	post_only TEXT NOT NULL,
+	account_id TEXT NOT NULL,
+	stp_mode TEXT NOT NULL,
+	display_amount TEXT NOT NULL,
+	protection_band TEXT NOT NULL,
+	time_in_force TEXT NOT NULL,
+	expire_at TEXT,
+	status TEXT NOT NULL,
+	filled_amount TEXT NOT NULL,
+	remaining_amount TEXT NOT NULL,
+	visible_amount TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL,
+	triggered_at TEXT,
+	reject_reason TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS orders_by_symbol ON orders (symbol, sequence);
+
+CREATE TABLE IF NOT EXISTS trades (
+	symbol TEXT NOT NULL,
+	id INTEGER NOT NULL,
+	maker_order_uuid TEXT NOT NULL,
+	taker_order_uuid TEXT NOT NULL,
+	price TEXT NOT NULL,
+	amount TEXT NOT NULL,
+	aggressor_side TEXT NOT NULL,
+	timestamp TEXT NOT NULL,
+	sequence INTEGER NOT NULL,
+	PRIMARY KEY (symbol, id)
+);
+
+CREATE INDEX IF NOT EXISTS trades_by_time ON trades (symbol, timestamp);
+`
+
+// sqliteMigrations add the columns added since the first schema to the tables
+// of an older database. Those that were applied already fail and are skipped.
+var sqliteMigrations = []string{
+	`ALTER TABLE orders ADD COLUMN reject_reason TEXT NOT NULL DEFAULT ''`,
+}
+
+// SQLiteRepository stores instruments, orders and trades in an embedded SQLite
+// database, using a pure Go driver.
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// OpenSQLiteRepository opens the database at path, creating it and its tables
+// if needed.
+func OpenSQLiteRepository(path string) (*SQLiteRepository, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+
+	// a single connection serializes the writes, which SQLite does anyway
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("PRAGMA journal_mode = WAL; PRAGMA busy_timeout = 5000;" + sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	for _, migration := range sqliteMigrations {
+		if _, err := db.Exec(migration); err != nil && !strings.Contains(err.Error(), "duplicate column") {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	return &SQLiteRepository{db: db}, nil
+}
+
+// DB gives access to the database, for queries the repository does not offer.
+func (sr *SQLiteRepository) DB() *sql.DB {
+	return sr.db
+}
+
+func (sr *SQLiteRepository) SaveInstrument(instrument models.Instrument) error {
+	_, err := sr.db.Exec(`INSERT OR REPLACE INTO instruments
+		(symbol, base_asset, quote_asset, tick_size, lot_size, min_quantity, max_quantity, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		instrument.Symbol, instrument.BaseAsset, instrument.QuoteAsset,
+		instrument.TickSize.String(), instrument.LotSize.String(), instrument.MinQuantity.String(), instrument.MaxQuantity.String(), string(instrument.Status))
+
+	return refused(err)
+}
+
+func (sr *SQLiteRepository) SaveOrders(orders ...models.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	return sr.transaction(func(tx *sql.Tx) error {
+		statement, err := tx.Prepare(`INSERT OR REPLACE INTO orders
+			(uuid, symbol, sequence, action, type, price, amount, stop_price, post_only, account_id, stp_mode, display_amount,
+			protection_band, time_in_force, expire_at, status, filled_amount, remaining_amount, visible_amount, created_at, updated_at, triggered_at,
+			reject_reason)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+		if err != nil {
+			return err
+		}
+		defer statement.Close()
+
+		for _, order := range orders {
+			_, err := statement.Exec(order.ID, order.Symbol, order.Sequence, string(order.Action), string(order.Kind),
+				order.Price.String(), order.Amount.String(), order.StopPrice.String(), string(order.PostOnly), order.AccountID,
+				string(order.SelfTradePrevention), order.DisplayAmount.String(), order.ProtectionBand.String(), string(order.TimeInForce),
+				nullableTime(order.ExpireAt), string(order.Status), order.FilledAmount.String(), order.RemainingAmount.String(),
+				order.VisibleAmount.String(), formatTime(order.CreatedAt), formatTime(order.UpdatedAt), nullableTime(order.TriggeredAt),
+				order.RejectReason)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (sr *SQLiteRepository) SaveTrades(trades ...models.Trade) error {
+	if len(trades) == 0 {
+		return nil
+	}
+
+	return sr.transaction(func(tx *sql.Tx) error {
+		statement, err := tx.Prepare(`INSERT OR REPLACE INTO trades
+			(symbol, id, maker_order_uuid, taker_order_uuid, price, amount, aggressor_side, timestamp, sequence)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+		if err != nil {
+			return err
+		}
+		defer statement.Close()
+
+		for _, trade := range trades {
+			_, err := statement.Exec(trade.Symbol, trade.ID, trade.MakerOrderID, trade.TakerOrderID, trade.Price.String(),
+				trade.Amount.String(), string(trade.AggressorSide), formatTime(trade.Timestamp), trade.Sequence)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (sr *SQLiteRepository) Close() error {
+	return sr.db.Close()
+}
+
+func (sr *SQLiteRepository) transaction(apply func(tx *sql.Tx) error) error {
+	tx, err := sr.db.Begin()
+	if err != nil {
+		return refused(err)
+	}
+
+	if err := apply(tx); err != nil {
+		tx.Rollback()
+		return refused(err)
+	}
+
+	return refused(tx.Commit())
+}
+
+// refused marks the error of a write with ErrWriteRefused, unless it may go
+// away on a retry: a busy or locked database, or a failing or full disk.
+func refused(err error) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+
+	switch sqliteErr.Code() & 0xff {
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED, sqlite3.SQLITE_IOERR, sqlite3.SQLITE_FULL, sqlite3.SQLITE_CANTOPEN, sqlite3.SQLITE_PROTOCOL:
+		return err
+	}
+
+	return fmt.Errorf("%w: %v", ErrWriteRefused, err)
+}
+
+// sqliteTime has a fixed number of decimal places, so that stored times sort
+// as strings.
+const sqliteTime = "2006-01-02T15:04:05.000000000Z"
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(sqliteTime)
+}
+
+func nullableTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+
+	return formatTime(t)
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"database/sql"
+	"order-matching/models"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteRepository(t *testing.T) {
+	t.Parallel()
+
+	instrument := models.Instrument{Symbol: "BTC-USD", BaseAsset: "BTC", QuoteAsset: "USD", TickSize: models.MustParseDecimal("0.01"), LotSize: models.MustParseDecimal("0.001"), Status: models.InstrumentTrading}
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC)
+	order := models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Symbol: "BTC-USD", Sequence: 1, Action: models.Sell, Kind: models.Limit, Price: models.MustParseDecimal("100.5"), Amount: models.MustParseDecimal("3.0"), Status: models.StatusNew, RemainingAmount: models.MustParseDecimal("3.0"), CreatedAt: createdAt, UpdatedAt: createdAt}
+	trade := models.Trade{ID: 1, Symbol: "BTC-USD", MakerOrderID: order.ID, TakerOrderID: "550e8400-e29b-41d4-a716-446655440001", Price: models.MustParseDecimal("100.5"), Amount: models.MustParseDecimal("1.0"), AggressorSide: models.Buy, Timestamp: createdAt, Sequence: 2}
+
+	t.Run("It stores instruments, orders and trades", func(t *testing.T) {
+		t.Parallel()
+		repo, err := OpenSQLiteRepository(filepath.Join(t.TempDir(), "orders.db"))
+		assert.Nil(t, err)
+		defer repo.Close()
+
+		assert.Nil(t, repo.SaveInstrument(instrument))
+		assert.Nil(t, repo.SaveOrders(order))
+		assert.Nil(t, repo.SaveTrades(trade))
+
+		var tickSize, status string
+		assert.Nil(t, repo.DB().QueryRow("SELECT tick_size, status FROM instruments WHERE symbol = ?", "BTC-USD").Scan(&tickSize, &status))
+		assert.Equal(t, "0.01", tickSize)
+		assert.Equal(t, string(models.InstrumentTrading), status)
+
+		var price, createdAtText string
+		var expireAt *string
+		assert.Nil(t, repo.DB().QueryRow("SELECT price, created_at, expire_at FROM orders WHERE uuid = ?", order.ID).Scan(&price, &createdAtText, &expireAt))
+		assert.Equal(t, "100.5", price)
+		assert.Equal(t, "2024-05-01T12:00:00.123456789Z", createdAtText)
+		assert.Nil(t, expireAt, "times an order does not have are NULL")
+
+		var makerOrderID, amount string
+		assert.Nil(t, repo.DB().QueryRow("SELECT maker_order_uuid, amount FROM trades WHERE symbol = ? AND id = ?", "BTC-USD", 1).Scan(&makerOrderID, &amount))
+		assert.Equal(t, order.ID, makerOrderID)
+		assert.Equal(t, "1", amount)
+	})
+
+	t.Run("It replaces what is saved again", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "orders.db")
+		repo, err := OpenSQLiteRepository(path)
+		assert.Nil(t, err)
+
+		filled := order
+		filled.Status = models.StatusPartiallyFilled
+		filled.FilledAmount = models.MustParseDecimal("1.0")
+		filled.RemainingAmount = models.MustParseDecimal("2.0")
+		assert.Nil(t, repo.SaveOrders(order, filled))
+		assert.Nil(t, repo.SaveTrades(trade))
+		repo.Close()
+
+		// reopening runs the schema again over the existing tables
+		repo, err = OpenSQLiteRepository(path)
+		assert.Nil(t, err)
+		defer repo.Close()
+		assert.Nil(t, repo.SaveTrades(trade))
+
+		var orders, trades int
+		var status, remaining string
+		repo.DB().QueryRow("SELECT COUNT(*) FROM orders").Scan(&orders)
+		repo.DB().QueryRow("SELECT COUNT(*) FROM trades").Scan(&trades)
+		repo.DB().QueryRow("SELECT status, remaining_amount FROM orders WHERE uuid = ?", order.ID).Scan(&status, &remaining)
+		assert.Equal(t, 1, orders)
+		assert.Equal(t, 1, trades)
+		assert.Equal(t, string(models.StatusPartiallyFilled), status)
+		assert.Equal(t, "2", remaining)
+	})
+
+	t.Run("It marks the writes the database refuses for good", func(t *testing.T) {
+		t.Parallel()
+		repo, err := OpenSQLiteRepository(filepath.Join(t.TempDir(), "orders.db"))
+		assert.Nil(t, err)
+		defer repo.Close()
+		_, err = repo.DB().Exec("DROP TABLE trades")
+		assert.Nil(t, err)
+
+		assert.ErrorIs(t, repo.SaveTrades(trade), ErrWriteRefused)
+	})
+
+	t.Run("It adds the columns missing from an older database", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "orders.db")
+		db, err := sql.Open("sqlite", path)
+		assert.Nil(t, err)
+		_, err = db.Exec("CREATE TABLE orders (uuid TEXT PRIMARY KEY, symbol TEXT NOT NULL, sequence INTEGER NOT NULL, action TEXT NOT NULL, type TEXT NOT NULL, price TEXT NOT NULL, amount TEXT NOT NULL, stop_price TEXT NOT NULL, post_only TEXT NOT NULL, account_id TEXT NOT NULL, stp_mode TEXT NOT NULL, display_amount TEXT NOT NULL, protection_band TEXT NOT NULL, time_in_force TEXT NOT NULL, expire_at TEXT, status TEXT NOT NULL, filled_amount TEXT NOT NULL, remaining_amount TEXT NOT NULL, visible_amount TEXT NOT NULL, created_at TEXT NOT NULL, updated_at TEXT NOT NULL, triggered_at TEXT)")
+		assert.Nil(t, err)
+		db.Close()
+
+		repo, err := OpenSQLiteRepository(path)
+		assert.Nil(t, err)
+		defer repo.Close()
+		rejected := order
+		rejected.Status = models.StatusRejected
+		rejected.RejectReason = "MAX_NOTIONAL"
+		assert.Nil(t, repo.SaveOrders(rejected))
+
+		var reason string
+		assert.Nil(t, repo.DB().QueryRow("SELECT reject_reason FROM orders WHERE uuid = ?", order.ID).Scan(&reason))
+		assert.Equal(t, "MAX_NOTIONAL", reason)
+	})
+}
+
+func TestOpen(t *testing.T) {
+	t.Parallel()
+
+	repo, err := Open(None, "")
+	assert.Nil(t, err)
+	assert.IsType(t, DiscardRepository{}, repo)
+
+	_, err = Open("postgres", "")
+	assert.NotNil(t, err)
+}
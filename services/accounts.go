@@ -0,0 +1,392 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"order-matching/models"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrUnboundedCost = errors.New("a STOP buy order cannot hold funds before it knows its price")
+)
+
+// Accounts keeps the balance of every asset of every account, and the funds
+// each open order holds. An order holds the quote asset to buy and the base
+// asset to sell from the moment it is accepted. Every fill moves the funds
+// between the two accounts, and whatever an order no longer needs, because it
+// traded at a better price, was reduced, or was canceled or expired, goes back
+// to the available balance. Orders without an account hold nothing.
+//
+// Only the sequencer changes the balances, as part of the commands it applies,
+// so that they are journaled and replayed together with the orders. Readers
+// may look at them at any time.
+type Accounts struct {
+	mutex sync.RWMutex
+	balances map[string]map[string]*models.Balance // by account ID, then asset
+	holds map[string]Hold // the funds held by every open order, by order ID
+}
+
+// Hold is the funds held for an open order.
+type Hold struct {
+	AccountID string
+	Asset string
+	Amount models.Decimal
+}
+
+// AccountsSnapshot is the state of the accounts.
+type AccountsSnapshot struct {
+	Balances map[string][]models.Balance
+	Holds map[string]Hold
+}
+
+func NewAccounts() *Accounts {
+	return &Accounts{
+		balances: make(map[string]map[string]*models.Balance),
+		holds: make(map[string]Hold),
+	}
+}
+
+// Balances returns the balance of every asset of an account, by asset.
+func (a *Accounts) Balances(accountID string) []models.Balance {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	balances := make([]models.Balance, 0, len(a.balances[accountID]))
+	for _, balance := range a.balances[accountID] {
+		balances = append(balances, *balance)
+	}
+	sort.Slice(balances, func(i, j int) bool {
+		return balances[i].Asset < balances[j].Asset
+	})
+
+	return balances
+}
+
+// balance returns the balance of an asset of an account, opening it if
+// needed. The caller holds the lock.
+func (a *Accounts) balance(accountID string, asset string) *models.Balance {
+	assets, exists := a.balances[accountID]
+	if !exists {
+		assets = make(map[string]*models.Balance)
+		a.balances[accountID] = assets
+	}
+
+	balance, exists := assets[asset]
+	if !exists {
+		balance = &models.Balance{Asset: asset}
+		assets[asset] = balance
+	}
+
+	return balance
+}
+
+// checkTransfer reports whether a deposit or withdrawal can be made.
+func (a *Accounts) checkTransfer(transfer models.Transfer, deposit bool) error {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	var available models.Decimal
+	if balance, exists := a.balances[transfer.AccountID][transfer.Asset]; exists {
+		available = balance.Available
+	}
+	if deposit && available > models.MaxDecimal-transfer.Amount {
+		return models.ErrDecimalOverflow
+	}
+	if !deposit && available < transfer.Amount {
+		return ErrInsufficientFunds
+	}
+
+	return nil
+}
+
+// transfer makes a deposit or withdrawal that checkTransfer allowed.
+func (a *Accounts) transfer(transfer models.Transfer, deposit bool) models.Balance {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	balance := a.balance(transfer.AccountID, transfer.Asset)
+	if deposit {
+		balance.Available += transfer.Amount
+	} else {
+		balance.Available -= transfer.Amount
+	}
+
+	return *balance
+}
+
+// orderHold returns the asset and the amount an order needs to hold to be
+// placed on the book as it is: the amount of the base asset to sell, or of the
+// quote asset to buy at its limit price. A MARKET buy holds the most it can
+// cost right now.
+func orderHold(instrument models.Instrument, book *OrderBook, order *models.Order) (string, models.Decimal, error) {
+	if order.Action == models.Sell {
+		// the proceeds have to fit a balance too
+		if _, err := order.Price.Mul(order.Amount); err != nil {
+			return "", 0, err
+		}
+		return instrument.BaseAsset, order.Amount, nil
+	}
+
+	if order.Kind == models.Stop {
+		return "", 0, ErrUnboundedCost
+	}
+	if order.IsMarket() {
+		cost, err := book.buyCost(order)
+		return instrument.QuoteAsset, cost, err
+	}
+
+	cost, err := order.Price.MulUp(order.Amount)
+	return instrument.QuoteAsset, cost, err
+}
+
+// checkOrder reports whether the account of a new order can hold its funds.
+func (a *Accounts) checkOrder(instrument models.Instrument, book *OrderBook, order *models.Order) error {
+	if order.AccountID == "" {
+		return nil
+	}
+
+	asset, amount, err := orderHold(instrument, book, order)
+	if errors.Is(err, models.ErrDecimalOverflow) {
+		return ErrInsufficientFunds
+	}
+	if err != nil {
+		return err
+	}
+
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if balance, exists := a.balances[order.AccountID][asset]; !exists || balance.Available < amount {
+		return ErrInsufficientFunds
+	}
+
+	return nil
+}
+
+// reserve holds the funds of a new order, before it is placed on the book.
+func (a *Accounts) reserve(instrument models.Instrument, book *OrderBook, order *models.Order) error {
+	if order.AccountID == "" {
+		return nil
+	}
+
+	asset, amount, err := orderHold(instrument, book, order)
+	if err != nil {
+		return err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	balance := a.balance(order.AccountID, asset)
+	if balance.Available < amount {
+		return ErrInsufficientFunds
+	}
+	balance.Available -= amount
+	balance.Held += amount
+	a.holds[order.ID] = Hold{AccountID: order.AccountID, Asset: asset, Amount: amount}
+
+	return nil
+}
+
+// amendmentHold returns how much more an order has to hold to be amended, or
+// nothing if it holds nothing or needs no more.
+func (a *Accounts) amendmentHold(order models.Order, amendment models.OrderAmendment) (Hold, error) {
+	hold, exists := a.holds[order.ID]
+	if !exists || !order.IsOpen() {
+		return Hold{}, nil
+	}
+
+	if amendment.Price != nil {
+		order.Price = *amendment.Price
+	}
+	if amendment.Amount != nil {
+		order.RemainingAmount = *amendment.Amount
+	}
+	needed, err := openOrderHold(order)
+	if err != nil {
+		return Hold{}, err
+	}
+
+	hold.Amount = max(needed-hold.Amount, 0)
+	return hold, nil
+}
+
+// checkAmendment reports whether the account of an order can hold the funds
+// the order needs once amended.
+func (a *Accounts) checkAmendment(order models.Order, amendment models.OrderAmendment) error {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	extra, err := a.amendmentHold(order, amendment)
+	if errors.Is(err, models.ErrDecimalOverflow) {
+		return ErrInsufficientFunds
+	}
+	if err != nil || extra.Amount == 0 {
+		return err
+	}
+	if a.balances[extra.AccountID][extra.Asset].Available < extra.Amount {
+		return ErrInsufficientFunds
+	}
+
+	return nil
+}
+
+// reserveAmendment holds the extra funds an order needs to be amended, before
+// the amendment is applied. If the book refuses the amendment, settle gives
+// them back.
+func (a *Accounts) reserveAmendment(order models.Order, amendment models.OrderAmendment) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	extra, err := a.amendmentHold(order, amendment)
+	if err != nil || extra.Amount == 0 {
+		return err
+	}
+
+	balance := a.balance(extra.AccountID, extra.Asset)
+	if balance.Available < extra.Amount {
+		return ErrInsufficientFunds
+	}
+	balance.Available -= extra.Amount
+	balance.Held += extra.Amount
+	hold := a.holds[order.ID]
+	hold.Amount += extra.Amount
+	a.holds[order.ID] = hold
+
+	return nil
+}
+
+// openOrderHold is what an order needs to hold as it is now: nothing once it
+// is closed, else its remaining amount to sell, or its remaining amount at its
+// limit price to buy.
+func openOrderHold(order models.Order) (models.Decimal, error) {
+	if !order.IsOpen() {
+		return 0, nil
+	}
+	if order.Action == models.Sell {
+		return order.RemainingAmount, nil
+	}
+
+	return order.Price.MulUp(order.RemainingAmount)
+}
+
+// settle moves the funds of every trade of a command from the holds of the
+// orders to the other account, all at once, and gives back whatever the
+// orders changed by the command no longer need. Each trade costs the buyer its
+// price times its amount, without the digits beyond what a decimal holds, so
+// the fills of an order never cost more than it held.
+func (a *Accounts) settle(instrument models.Instrument, trades []models.Trade, changes []models.Order) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	orders := make(map[string]models.Order, len(changes))
+	for _, order := range changes {
+		orders[order.ID] = order
+	}
+
+	for _, trade := range trades {
+		buyer, seller := orders[trade.TakerOrderID], orders[trade.MakerOrderID]
+		if trade.AggressorSide == models.Sell {
+			buyer, seller = seller, buyer
+		}
+
+		cost, err := trade.Price.Mul(trade.Amount)
+		if err != nil {
+			// the book manager refuses orders and amendments whose notional
+			// overflows, and no trade costs more than the notional of both
+			// of its orders; the balances cannot be trusted past this point
+			panic(fmt.Sprintf("trade %d cannot be settled: %v", trade.ID, err))
+		}
+		a.pay(buyer, instrument.QuoteAsset, cost, instrument.BaseAsset, trade.Amount)
+		a.pay(seller, instrument.BaseAsset, trade.Amount, instrument.QuoteAsset, cost)
+	}
+
+	for _, order := range changes {
+		a.release(order)
+	}
+}
+
+// pay takes what the order gives from its hold and credits what it gets to
+// its account.
+func (a *Accounts) pay(order models.Order, givenAsset string, given models.Decimal, receivedAsset string, received models.Decimal) {
+	hold, exists := a.holds[order.ID]
+	if !exists {
+		return
+	}
+
+	hold.Amount -= given
+	a.holds[order.ID] = hold
+	a.balance(hold.AccountID, givenAsset).Held -= given
+	a.balance(hold.AccountID, receivedAsset).Available += received
+}
+
+// release gives back the part of the hold of an order it no longer needs.
+func (a *Accounts) release(order models.Order) {
+	hold, exists := a.holds[order.ID]
+	if !exists {
+		return
+	}
+
+	needed, err := openOrderHold(order)
+	if err != nil || (needed > 0 && needed >= hold.Amount) {
+		return
+	}
+
+	balance := a.balance(hold.AccountID, hold.Asset)
+	balance.Held -= hold.Amount - needed
+	balance.Available += hold.Amount - needed
+	if needed == 0 {
+		delete(a.holds, order.ID)
+		return
+	}
+	hold.Amount = needed
+	a.holds[order.ID] = hold
+}
+
+// releaseOrder gives back what an order the last command did not change no
+// longer needs, after the book refused to amend it.
+func (a *Accounts) releaseOrder(order models.Order) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.release(order)
+}
+
+// Snapshot captures the balances and holds of every account.
+func (a *Accounts) Snapshot() AccountsSnapshot {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	snapshot := AccountsSnapshot{
+		Balances: make(map[string][]models.Balance, len(a.balances)),
+		Holds: make(map[string]Hold, len(a.holds)),
+	}
+	for accountID, assets := range a.balances {
+		for _, balance := range assets {
+			snapshot.Balances[accountID] = append(snapshot.Balances[accountID], *balance)
+		}
+	}
+	for orderID, hold := range a.holds {
+		snapshot.Holds[orderID] = hold
+	}
+
+	return snapshot
+}
+
+// restore loads the accounts from a snapshot.
+func (a *Accounts) restore(snapshot AccountsSnapshot) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for accountID, balances := range snapshot.Balances {
+		for _, balance := range balances {
+			*a.balance(accountID, balance.Asset) = balance
+		}
+	}
+	for orderID, hold := range snapshot.Holds {
+		a.holds[orderID] = hold
+	}
+}
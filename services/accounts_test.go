@@ -0,0 +1,166 @@
+package services
+
+import (
+	"order-matching/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccounts(t *testing.T) {
+	t.Parallel()
+
+	newMarkets := func(bm *BookManager) *Market {
+		bm.CreateInstrument(models.Instrument{Symbol: "BTC-USD", BaseAsset: "BTC", QuoteAsset: "USD", TickSize: decimal("0.01"), LotSize: decimal("0.01")})
+		for _, accountID := range []string{"buyer", "seller"} {
+			bm.Deposit(models.Transfer{AccountID: accountID, Asset: "BTC", Amount: decimal("10.0")})
+			bm.Deposit(models.Transfer{AccountID: accountID, Asset: "USD", Amount: decimal("1000.0")})
+		}
+		market, _ := bm.Market("BTC-USD")
+		return market
+	}
+
+	balance := func(bm *BookManager, accountID string, asset string) models.Balance {
+		for _, balance := range bm.Accounts().Balances(accountID) {
+			if balance.Asset == asset {
+				return balance
+			}
+		}
+		return models.Balance{Asset: asset}
+	}
+
+	t.Run("It holds the funds of open orders", func(t *testing.T) {
+		t.Parallel()
+		bm := NewBookManager(0)
+		market := newMarkets(bm)
+
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Symbol: "BTC-USD", AccountID: "buyer", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("2.0")})
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Symbol: "BTC-USD", AccountID: "seller", Action: models.Sell, Price: decimal("110.0"), Amount: decimal("3.0")})
+
+		assert.Equal(t, models.Balance{Asset: "USD", Available: decimal("800.0"), Held: decimal("200.0")}, balance(bm, "buyer", "USD"))
+		assert.Equal(t, models.Balance{Asset: "BTC", Available: decimal("7.0"), Held: decimal("3.0")}, balance(bm, "seller", "BTC"))
+	})
+
+	t.Run("It settles fills between the accounts and gives back what the buyer saved", func(t *testing.T) {
+		t.Parallel()
+		bm := NewBookManager(0)
+		market := newMarkets(bm)
+
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Symbol: "BTC-USD", AccountID: "seller", Action: models.Sell, Price: decimal("90.0"), Amount: decimal("1.0")})
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Symbol: "BTC-USD", AccountID: "buyer", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("2.0")})
+
+		assert.Equal(t, models.Balance{Asset: "USD", Available: decimal("810.0"), Held: decimal("100.0")}, balance(bm, "buyer", "USD"))
+		assert.Equal(t, models.Balance{Asset: "BTC", Available: decimal("11.0")}, balance(bm, "buyer", "BTC"))
+		assert.Equal(t, models.Balance{Asset: "USD", Available: decimal("1090.0")}, balance(bm, "seller", "USD"))
+		assert.Equal(t, models.Balance{Asset: "BTC", Available: decimal("9.0")}, balance(bm, "seller", "BTC"))
+	})
+
+	t.Run("It drops the holds of filled orders", func(t *testing.T) {
+		t.Parallel()
+		bm := NewBookManager(0)
+		market := newMarkets(bm)
+
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Symbol: "BTC-USD", AccountID: "seller", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("1.0")})
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Symbol: "BTC-USD", AccountID: "buyer", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0")})
+
+		assert.Equal(t, 0, len(bm.Accounts().Snapshot().Holds), "both orders are filled at the price they held for")
+	})
+
+	t.Run("It releases the funds of canceled and expired orders", func(t *testing.T) {
+		t.Parallel()
+		bm := NewBookManager(0)
+		market := newMarkets(bm)
+		expireAt := time.Now().UTC().Add(time.Hour)
+
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Symbol: "BTC-USD", AccountID: "buyer", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("2.0")})
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Symbol: "BTC-USD", AccountID: "seller", Action: models.Sell, Price: decimal("110.0"), Amount: decimal("3.0"), TimeInForce: models.GoodTillDate, ExpireAt: expireAt})
+		bm.CancelOrder(market, "550e8400-e29b-41d4-a716-446655440000")
+		bm.ExpireOrders(expireAt)
+
+		assert.Equal(t, models.Balance{Asset: "USD", Available: decimal("1000.0")}, balance(bm, "buyer", "USD"))
+		assert.Equal(t, models.Balance{Asset: "BTC", Available: decimal("10.0")}, balance(bm, "seller", "BTC"))
+	})
+
+	t.Run("It holds what a market buy can cost and releases the rest", func(t *testing.T) {
+		t.Parallel()
+		bm := NewBookManager(0)
+		market := newMarkets(bm)
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Symbol: "BTC-USD", AccountID: "seller", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("1.0")})
+
+		_, err := bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Symbol: "BTC-USD", AccountID: "buyer", Action: models.Buy, Kind: models.Market, Amount: decimal("2.0")})
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.Balance{Asset: "USD", Available: decimal("900.0")}, balance(bm, "buyer", "USD"))
+		assert.Equal(t, models.Balance{Asset: "BTC", Available: decimal("11.0")}, balance(bm, "buyer", "BTC"))
+	})
+
+	t.Run("It refuses orders and amendments the account cannot fund", func(t *testing.T) {
+		t.Parallel()
+		bm := NewBookManager(0)
+		market := newMarkets(bm)
+
+		_, err := bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Symbol: "BTC-USD", AccountID: "buyer", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("11.0")})
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+		_, exists := market.OrderBook.GetOrder("550e8400-e29b-41d4-a716-446655440000")
+		assert.False(t, exists)
+
+		_, err = bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Symbol: "BTC-USD", AccountID: "nobody", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("1.0")})
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+
+		_, err = bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440002", Symbol: "BTC-USD", AccountID: "buyer", Action: models.Buy, Kind: models.Stop, StopPrice: decimal("100.0"), Amount: decimal("1.0")})
+		assert.ErrorIs(t, err, ErrUnboundedCost)
+
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440003", Symbol: "BTC-USD", AccountID: "buyer", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("5.0")})
+		amount := decimal("11.0")
+		_, _, err = bm.AmendOrder(market, "550e8400-e29b-41d4-a716-446655440003", models.OrderAmendment{Amount: &amount})
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+
+		amount = decimal("8.0")
+		_, _, err = bm.AmendOrder(market, "550e8400-e29b-41d4-a716-446655440003", models.OrderAmendment{Amount: &amount})
+		assert.NoError(t, err)
+		assert.Equal(t, models.Balance{Asset: "USD", Available: decimal("200.0"), Held: decimal("800.0")}, balance(bm, "buyer", "USD"))
+	})
+
+	t.Run("It withdraws available funds only", func(t *testing.T) {
+		t.Parallel()
+		bm := NewBookManager(0)
+		market := newMarkets(bm)
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Symbol: "BTC-USD", AccountID: "seller", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("4.0")})
+
+		_, err := bm.Withdraw(models.Transfer{AccountID: "seller", Asset: "BTC", Amount: decimal("7.0")})
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+
+		withdrawn, err := bm.Withdraw(models.Transfer{AccountID: "seller", Asset: "BTC", Amount: decimal("6.0")})
+		assert.NoError(t, err)
+		assert.Equal(t, models.Balance{Asset: "BTC", Held: decimal("4.0")}, withdrawn)
+	})
+
+	t.Run("It recovers the balances from the journal and from a snapshot", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		journal, entries, _ := OpenJournal(dir, 0)
+		bm := NewBookManager(0)
+		assert.Nil(t, bm.Recover(journal, entries))
+		market := newMarkets(bm)
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Symbol: "BTC-USD", AccountID: "seller", Action: models.Sell, Price: decimal("90.0"), Amount: decimal("3.0")})
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Symbol: "BTC-USD", AccountID: "buyer", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0")})
+		bm.Withdraw(models.Transfer{AccountID: "buyer", Asset: "USD", Amount: decimal("10.0")})
+		journal.Close()
+
+		journal, entries, _ = OpenJournal(dir, 0)
+		defer journal.Close()
+		replayed := NewBookManager(0)
+		assert.Nil(t, replayed.Recover(journal, entries))
+		restored := NewBookManager(0)
+		assert.Nil(t, restored.Restore(bm.Snapshot()))
+
+		for _, recovered := range []*BookManager{replayed, restored} {
+			assert.Equal(t, bm.Accounts().Balances("buyer"), recovered.Accounts().Balances("buyer"))
+			assert.Equal(t, bm.Accounts().Balances("seller"), recovered.Accounts().Balances("seller"))
+			market, _ := recovered.Market("BTC-USD")
+			recovered.CancelOrder(market, "550e8400-e29b-41d4-a716-446655440000")
+			assert.Equal(t, models.Balance{Asset: "BTC", Available: decimal("9.0")}, balance(recovered, "seller", "BTC"))
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"order-matching/models"
+	"slices"
+)
+
+// BookDepth is an immutable copy of every price level of an order book. The
+// sequencer publishes a new one after the commands that change the book, so
+// that readers never touch the book itself.
+type BookDepth struct {
+	Asks []models.OrderBookEntry `json:"asks"` // best, lowest, price first
+	Bids []models.OrderBookEntry `json:"bids"` // best, highest, price first
+}
+
+var emptyDepth = &BookDepth{Asks: []models.OrderBookEntry{}, Bids: []models.OrderBookEntry{}}
+
+// depth copies the price levels of the book.
+func (ob *OrderBook) depth() *BookDepth {
+	depth := &BookDepth{
+		Asks: make([]models.OrderBookEntry, 0, ob.SellLevels.Len()),
+		Bids: make([]models.OrderBookEntry, 0, ob.BuyLevels.Len()),
+	}
+
+	for level := range ob.SellLevels.All() {
+		depth.Asks = append(depth.Asks, models.OrderBookEntry{Price: level.Price, Liquidity: level.Liquidity(), Type: models.Sell})
+	}
+	for level := range ob.BuyLevels.All() {
+		depth.Bids = append(depth.Bids, models.OrderBookEntry{Price: level.Price, Liquidity: level.Liquidity(), Type: models.Buy})
+	}
+
+	return depth
+}
+
+// GetOrderBook returns up to limit price levels of each side of the last
+// published depth of the market, laid out like OrderBook.GetOrderBook: the
+// asks from the highest shown price down to the best ask, then the bids from
+// the best bid down.
+func (m *Market) GetOrderBook(limit int) []models.OrderBookEntry {
+	depth := m.Feed.Depth()
+
+	asks := slices.Clone(depth.Asks[:min(limit, len(depth.Asks))])
+	slices.Reverse(asks)
+
+	return append(asks, depth.Bids[:min(limit, len(depth.Bids))]...)
+}
+
+// publishDepth publishes the depth of the order book, and the trades executed
+// since, to the feed of the market if a command changed the book since it was
+// last published. Only the owner of the order book calls it.
+func (m *Market) publishDepth() {
+	if !m.changed {
+		return
+	}
+
+	m.Feed.publish(m.OrderBook.depth(), m.trades)
+	m.trades = nil
+	m.changed = false
+}
+
+// publishDepth publishes the depth of every market changed since the last
+// call. Only the owner of the order books calls it.
+func (bm *BookManager) publishDepth() {
+	bm.mutex.RLock()
+	defer bm.mutex.RUnlock()
+
+	for _, symbol := range bm.symbols {
+		bm.markets[symbol].publishDepth()
+	}
+}
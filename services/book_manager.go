@@ -0,0 +1,546 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"order-matching/models"
+	"order-matching/repository"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInstrumentNotFound = errors.New("instrument not found")
+	ErrInstrumentExists = errors.New("instrument already exists")
+	ErrDuplicateOrder = errors.New("order has been processed already")
+)
+
+// Market is a listed instrument together with its order book, the history of
+// its orders and trades and its market data feed. Its orders hold the funds of
+// their accounts. The order book belongs to the sequencer; readers use the
+// histories and the depth published by the feed, which are safe for
+// concurrent use.
+type Market struct {
+	Instrument models.Instrument
+	OrderBook *OrderBook
+	OrderHistory *OrderHistory
+	TradeHistory *TradeHistory
+	Feed *MarketFeed
+
+	repository repository.Repository // every change to the market is written through to it
+	executions *Executions
+	accounts *Accounts
+	risk *Risk
+	publish EventHandler
+	stateMutex sync.RWMutex
+	state models.MarketState // guarded by stateMutex, written by the sequencer only
+	window []models.Trade // trades the circuit breaker compares the last one with, oldest first
+	changed bool // whether the order book changed since its depth was published
+	trades []models.Trade // trades executed since the depth was published
+}
+
+// BookManager is the instrument registry: it holds one market per symbol. The
+// registry itself is safe for concurrent use; the order books it hands out
+// are not, and are only changed by the sequencer once it runs.
+//
+// Every change to the registry or an order book goes through the manager,
+// which writes it to the journal, if there is one, before applying it, and to
+// the repository after. The events of every command are turned into execution
+// reports for the accounts of the orders and handed to the subscribed event
+// handlers.
+type BookManager struct {
+	mutex sync.RWMutex
+	markets map[string]*Market
+	symbols []string // listed symbols in alphabetical order
+	orderIDs map[string]struct{} // the ID of every order ever placed, in any market; used by the sequencer only
+
+	sessionClose time.Duration // passed on to every order book
+	journal *Journal
+	repository repository.Repository
+	executions *Executions
+	accounts *Accounts
+	risk *Risk
+	halts HaltPolicy
+	handlers []EventHandler
+	restored uint64 // sequence of the last journal entry covered by a restored snapshot
+}
+
+func NewBookManager(sessionClose time.Duration) *BookManager {
+	return &BookManager{
+		markets: make(map[string]*Market),
+		orderIDs: make(map[string]struct{}),
+		sessionClose: sessionClose,
+		repository: repository.DiscardRepository{},
+		executions: NewExecutions(),
+		accounts: NewAccounts(),
+		risk: NewRisk(),
+	}
+}
+
+// Executions returns the execution reports of the accounts.
+func (bm *BookManager) Executions() *Executions {
+	return bm.executions
+}
+
+// Accounts returns the balances of the accounts.
+func (bm *BookManager) Accounts() *Accounts {
+	return bm.accounts
+}
+
+// SetRiskLimits sets the limits the risk checks hold every new order and
+// amendment to. It must be called before the markets are taken into use.
+func (bm *BookManager) SetRiskLimits(limits RiskLimits) {
+	bm.risk.limits = limits
+}
+
+// SetRepository sets the repository the markets are written through to, which
+// stores nothing by default. Its writes are made while the command is applied,
+// so anything slower than memory should be an AsyncRepository. It must be
+// called before any instrument is listed or restored.
+func (bm *BookManager) SetRepository(repository repository.Repository) {
+	bm.repository = repository
+}
+
+// Subscribe adds a handler for the events of every command applied to the
+// markets from now on, including those replayed by Recover. It must be called
+// before the markets are recovered or taken into use.
+func (bm *BookManager) Subscribe(handler EventHandler) {
+	bm.handlers = append(bm.handlers, handler)
+}
+
+func (bm *BookManager) publish(events []Event) {
+	for _, handler := range bm.handlers {
+		handler(events)
+	}
+}
+
+// Recover rebuilds the markets from the entries read back from the journal
+// and then journals every further change to it. Entries covered by a restored
+// snapshot are skipped.
+func (bm *BookManager) Recover(journal *Journal, entries []JournalEntry) error {
+	if len(entries) > 0 && entries[0].Sequence > bm.restored+1 {
+		return fmt.Errorf("%w: entries %d to %d are missing", ErrJournalCorrupt, bm.restored+1, entries[0].Sequence-1)
+	}
+
+	for _, entry := range entries {
+		if entry.Sequence <= bm.restored {
+			continue
+		}
+		if err := bm.apply(entry); err != nil {
+			return fmt.Errorf("replaying journal entry %d: %w", entry.Sequence, err)
+		}
+	}
+
+	bm.publishDepth()
+	bm.journal = journal
+	return nil
+}
+
+// CreateInstrument lists a new instrument and opens an empty order book for it.
+func (bm *BookManager) CreateInstrument(instrument models.Instrument) (models.Instrument, error) {
+	if _, err := bm.Market(instrument.Symbol); err == nil {
+		return models.Instrument{}, ErrInstrumentExists
+	}
+
+	if instrument.Status == "" {
+		instrument.Status = models.InstrumentTrading
+	}
+
+	sessionClose := bm.sessionClose
+	if _, err := bm.record(JournalEntry{Command: CommandCreateInstrument, Timestamp: time.Now().UTC(), Symbol: instrument.Symbol, Instrument: &instrument, SessionClose: &sessionClose}); err != nil {
+		return models.Instrument{}, err
+	}
+
+	// the registry is only locked to list the market, not while journaling
+	bm.mutex.Lock()
+	bm.createInstrument(instrument, sessionClose, time.Now().UTC())
+	bm.mutex.Unlock()
+
+	return instrument, nil
+}
+
+// createInstrument opens the market of a new instrument, open for trading at
+// since unless the instrument is listed as suspended. The caller holds the
+// registry lock.
+func (bm *BookManager) createInstrument(instrument models.Instrument, sessionClose time.Duration, since time.Time) {
+	orderBook := NewOrderBook()
+	state := models.MarketState{State: models.StateOpen, Since: since}
+	if instrument.Status == models.InstrumentSuspended {
+		state.State = models.StateClosed
+		orderBook.Halt()
+	}
+	orderBook.SessionClose = sessionClose
+	orderBook.TickSize = instrument.TickSize
+
+	market := &Market{
+		Instrument: instrument,
+		OrderBook: orderBook,
+		OrderHistory: NewOrderHistory(),
+		TradeHistory: NewTradeHistory(),
+		Feed: NewMarketFeed(instrument.Symbol, emptyDepth),
+		repository: bm.repository,
+		executions: bm.executions,
+		accounts: bm.accounts,
+		risk: bm.risk,
+		publish: bm.publish,
+		state: state,
+	}
+	bm.markets[instrument.Symbol] = market
+	if err := bm.repository.SaveInstrument(instrument); err != nil {
+		fmt.Println(err.Error())
+	}
+
+	index := sort.SearchStrings(bm.symbols, instrument.Symbol)
+	bm.symbols = append(bm.symbols, "")
+	copy(bm.symbols[index+1:], bm.symbols[index:])
+	bm.symbols[index] = instrument.Symbol
+}
+
+// GetInstrument looks up a listed instrument by its symbol.
+func (bm *BookManager) GetInstrument(symbol string) (models.Instrument, error) {
+	market, err := bm.Market(symbol)
+	if err != nil {
+		return models.Instrument{}, err
+	}
+
+	return market.Instrument, nil
+}
+
+// GetInstrumentList returns every listed instrument ordered by symbol.
+func (bm *BookManager) GetInstrumentList() []models.Instrument {
+	bm.mutex.RLock()
+	defer bm.mutex.RUnlock()
+
+	instruments := make([]models.Instrument, 0, len(bm.symbols))
+	for _, symbol := range bm.symbols {
+		instruments = append(instruments, bm.markets[symbol].Instrument)
+	}
+
+	return instruments
+}
+
+// Market looks up the market of a listed instrument.
+func (bm *BookManager) Market(symbol string) (*Market, error) {
+	bm.mutex.RLock()
+	defer bm.mutex.RUnlock()
+
+	market, exists := bm.markets[symbol]
+	if !exists {
+		return nil, ErrInstrumentNotFound
+	}
+
+	return market, nil
+}
+
+// PlaceOrder places an order in the order book of the market and records its
+// trades. An order ID that was placed before, in any market, is refused with
+// ErrDuplicateOrder, an order whose amount times its price or stop price is
+// out of range with ErrDecimalOverflow, and an order of an account that
+// cannot hold its funds with ErrInsufficientFunds. An order that fails the risk checks is recorded
+// as REJECTED, with the reason in the order, and a RiskError is returned. A
+// closed market refuses the order with ErrMarketClosed, and a halted one
+// either queues it until the market reopens or refuses it with
+// ErrMarketHalted, see HaltPolicy. Only the sequencer calls it once it runs.
+func (bm *BookManager) PlaceOrder(market *Market, order *models.Order) ([]models.Trade, error) {
+	if _, exists := bm.orderIDs[order.ID]; exists {
+		return nil, ErrDuplicateOrder
+	}
+	// every trade of the order then costs less than its notional, so that
+	// settling it cannot overflow
+	if err := models.CheckNotional(order.Amount, order.Price, order.StopPrice); err != nil {
+		return nil, err
+	}
+	if err := bm.checkOrder(market, order); err != nil {
+		return nil, err
+	}
+	// the outcome of the risk checks is journaled with the order, so that
+	// replaying it does not depend on the limits of the time
+	order.RejectReason = bm.risk.check(market.Instrument.Symbol, market.OrderBook, *order, order.Amount)
+	if order.RejectReason == "" {
+		if err := bm.accounts.checkOrder(market.Instrument, market.OrderBook, order); err != nil {
+			return nil, err
+		}
+	}
+
+	command, err := bm.record(JournalEntry{Command: CommandPlaceOrder, Timestamp: time.Now().UTC(), Symbol: market.Instrument.Symbol, Order: order})
+	if err != nil {
+		return nil, err
+	}
+	bm.orderIDs[order.ID] = struct{}{}
+
+	trades, err := market.placeOrder(command)
+	bm.tripBreaker(market, trades, command.Timestamp)
+
+	return trades, err
+}
+
+// CancelOrder cancels an order in the order book of the market. Only the
+// sequencer calls it once it runs.
+func (bm *BookManager) CancelOrder(market *Market, id string) (models.Order, error) {
+	command, err := bm.record(JournalEntry{Command: CommandCancelOrder, Timestamp: time.Now().UTC(), Symbol: market.Instrument.Symbol, OrderID: id})
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	return market.cancelOrder(command)
+}
+
+// AmendOrder amends an order in the order book of the market and records the
+// trades of the amendment. An amendment that takes the order's total amount
+// outside the instrument's quantity limits is refused with
+// models.ErrQuantityLimits, one that fails the risk checks with a RiskError,
+// and one the account of the order cannot hold the funds for with
+// ErrInsufficientFunds. A market that is not open refuses every amendment
+// with ErrMarketHalted or ErrMarketClosed. Only the sequencer calls it once
+// it runs.
+func (bm *BookManager) AmendOrder(market *Market, id string, amendment models.OrderAmendment) (models.Order, []models.Trade, error) {
+	if err := bm.checkAmendment(market); err != nil {
+		return models.Order{}, nil, err
+	}
+	if order, exists := market.OrderBook.GetOrder(id); exists && order.IsOpen() {
+		amended := order
+		if amendment.Price != nil {
+			amended.Price = *amendment.Price
+		}
+		if amendment.Amount != nil {
+			amended.RemainingAmount = *amendment.Amount
+		}
+		amended.Amount = amended.FilledAmount + amended.RemainingAmount
+		if err := market.Instrument.CheckQuantity(amended.Amount); err != nil {
+			return models.Order{}, nil, err
+		}
+		if err := models.CheckNotional(amended.Amount, amended.Price, amended.StopPrice); err != nil {
+			return models.Order{}, nil, err
+		}
+		if reason := bm.risk.check(market.Instrument.Symbol, market.OrderBook, amended, amended.RemainingAmount); reason != "" {
+			return models.Order{}, nil, &RiskError{Reason: reason}
+		}
+		if err := bm.accounts.checkAmendment(order, amendment); err != nil {
+			return models.Order{}, nil, err
+		}
+	}
+
+	command, err := bm.record(JournalEntry{Command: CommandAmendOrder, Timestamp: time.Now().UTC(), Symbol: market.Instrument.Symbol, OrderID: id, Amendment: &amendment})
+	if err != nil {
+		return models.Order{}, nil, err
+	}
+
+	order, trades, err := market.amendOrder(command)
+	bm.tripBreaker(market, trades, command.Timestamp)
+
+	return order, trades, err
+}
+
+// Deposit adds funds of an asset to an account.
+func (bm *BookManager) Deposit(transfer models.Transfer) (models.Balance, error) {
+	return bm.transfer(CommandDeposit, transfer)
+}
+
+// Withdraw takes available funds of an asset out of an account, or refuses
+// with ErrInsufficientFunds.
+func (bm *BookManager) Withdraw(transfer models.Transfer) (models.Balance, error) {
+	return bm.transfer(CommandWithdraw, transfer)
+}
+
+func (bm *BookManager) transfer(command JournalCommand, transfer models.Transfer) (models.Balance, error) {
+	if err := bm.accounts.checkTransfer(transfer, command == CommandDeposit); err != nil {
+		return models.Balance{}, err
+	}
+	if _, err := bm.record(JournalEntry{Command: command, Timestamp: time.Now().UTC(), Transfer: &transfer}); err != nil {
+		return models.Balance{}, err
+	}
+
+	return bm.accounts.transfer(transfer, command == CommandDeposit), nil
+}
+
+// ExpireOrders expires the due orders of every market, in symbol order. A
+// market whose expiry cannot be journaled is left for the next sweep.
+func (bm *BookManager) ExpireOrders(now time.Time) []models.Order {
+	bm.mutex.RLock()
+	defer bm.mutex.RUnlock()
+
+	expired := []models.Order{}
+	for _, symbol := range bm.symbols {
+		if !bm.markets[symbol].OrderBook.HasExpiredOrders(now) {
+			continue
+		}
+
+		command, err := bm.record(JournalEntry{Command: CommandExpireOrders, Timestamp: now, Symbol: symbol})
+		if err != nil {
+			fmt.Println(err.Error())
+			continue
+		}
+
+		expired = append(expired, bm.markets[symbol].expireOrders(command)...)
+	}
+
+	return expired
+}
+
+// record writes an entry to the journal, if there is one, and returns it with
+// its sequence number.
+func (bm *BookManager) record(entry JournalEntry) (JournalEntry, error) {
+	if bm.journal == nil {
+		return entry, nil
+	}
+
+	return bm.journal.Append(entry)
+}
+
+// apply repeats a journaled command. Commands that failed when they were
+// first applied fail the same way again, so only a command the registry
+// cannot take at all is an error.
+func (bm *BookManager) apply(entry JournalEntry) error {
+	if entry.Command == CommandCreateInstrument {
+		if entry.Instrument == nil {
+			return fmt.Errorf("%w: instrument missing", ErrJournalCorrupt)
+		}
+
+		bm.mutex.Lock()
+		defer bm.mutex.Unlock()
+		if _, exists := bm.markets[entry.Instrument.Symbol]; exists {
+			return ErrInstrumentExists
+		}
+		sessionClose := bm.sessionClose
+		if entry.SessionClose != nil {
+			sessionClose = *entry.SessionClose
+		}
+		bm.createInstrument(*entry.Instrument, sessionClose, entry.Timestamp)
+		return nil
+	}
+
+	if entry.Command == CommandDeposit || entry.Command == CommandWithdraw {
+		if entry.Transfer == nil {
+			return fmt.Errorf("%w: transfer missing", ErrJournalCorrupt)
+		}
+
+		deposit := entry.Command == CommandDeposit
+		if bm.accounts.checkTransfer(*entry.Transfer, deposit) == nil {
+			bm.accounts.transfer(*entry.Transfer, deposit)
+		}
+		return nil
+	}
+
+	market, err := bm.Market(entry.Symbol)
+	if err != nil {
+		return err
+	}
+
+	switch entry.Command {
+	case CommandPlaceOrder:
+		if entry.Order == nil {
+			return fmt.Errorf("%w: order missing", ErrJournalCorrupt)
+		}
+		bm.orderIDs[entry.Order.ID] = struct{}{}
+		market.placeOrder(entry)
+	case CommandCancelOrder:
+		market.cancelOrder(entry)
+	case CommandAmendOrder:
+		if entry.Amendment == nil {
+			return fmt.Errorf("%w: amendment missing", ErrJournalCorrupt)
+		}
+		market.amendOrder(entry)
+	case CommandExpireOrders:
+		market.expireOrders(entry)
+	case CommandSetMarketState:
+		if entry.State == nil {
+			return fmt.Errorf("%w: state missing", ErrJournalCorrupt)
+		}
+		market.setState(entry)
+	default:
+		return fmt.Errorf("%w: unknown command %q", ErrJournalCorrupt, entry.Command)
+	}
+
+	return nil
+}
+
+// The market helpers apply a sequenced command to the order book. Everything
+// the command does follows from the command itself, its timestamp included,
+// so that the journal can repeat it exactly.
+
+func (m *Market) placeOrder(command JournalEntry) ([]models.Trade, error) {
+	if reason := command.Order.RejectReason; reason != "" {
+		m.OrderBook.RejectOrderAt(command.Order, reason, command.Timestamp)
+		m.commit(command, nil)
+		return nil, &RiskError{Reason: reason}
+	}
+
+	if err := m.accounts.reserve(m.Instrument, m.OrderBook, command.Order); err != nil {
+		return nil, err
+	}
+
+	trades := m.OrderBook.PlaceOrderAt(command.Order, command.Timestamp)
+	m.TradeHistory.Record(trades...)
+	m.commit(command, trades)
+
+	return trades, nil
+}
+
+func (m *Market) cancelOrder(command JournalEntry) (models.Order, error) {
+	order, err := m.OrderBook.CancelOrderAt(command.OrderID, command.Timestamp)
+	m.commit(command, nil)
+
+	return order, err
+}
+
+func (m *Market) amendOrder(command JournalEntry) (models.Order, []models.Trade, error) {
+	current, exists := m.OrderBook.GetOrder(command.OrderID)
+	if exists {
+		if err := m.accounts.reserveAmendment(current, *command.Amendment); err != nil {
+			return models.Order{}, nil, err
+		}
+	}
+
+	order, trades, err := m.OrderBook.AmendOrderAt(command.OrderID, *command.Amendment, command.Timestamp)
+	if err != nil && exists {
+		// the order is unchanged and needs no more than before
+		m.accounts.releaseOrder(current)
+	}
+	m.TradeHistory.Record(trades...)
+	m.commit(command, trades)
+
+	return order, trades, err
+}
+
+func (m *Market) expireOrders(command JournalEntry) []models.Order {
+	expired := m.OrderBook.ExpireOrders(command.Timestamp)
+	m.commit(command, nil)
+
+	return expired
+}
+
+// commit records the orders changed by a command for readers, settles the
+// command's trades between the accounts, moves their positions, hands the
+// orders and trades to the repository, reports its events to the accounts of
+// the orders and publishes them. The depth of the order book and the trades
+// are published to the feed by the sequencer once the batch of commands is
+// done. The markets are recovered from the journal, not from the repository,
+// so the command stands whatever becomes of the write; the repository reports
+// and retries failed writes itself.
+func (m *Market) commit(command JournalEntry, trades []models.Trade) {
+	changes := m.OrderBook.Changes()
+	m.OrderHistory.Record(changes...)
+	m.changed = true
+	m.trades = append(m.trades, trades...)
+	m.accounts.settle(m.Instrument, trades, changes)
+	m.risk.record(m.Instrument.Symbol, trades, changes)
+
+	if err := m.repository.SaveOrders(changes...); err != nil {
+		fmt.Println(err.Error())
+	}
+	if err := m.repository.SaveTrades(trades...); err != nil {
+		fmt.Println(err.Error())
+	}
+
+	events := m.OrderBook.Events()
+	if len(events) == 0 {
+		return
+	}
+	for index := range events {
+		events[index].Command = command.Sequence
+		events[index].Symbol = m.Instrument.Symbol
+	}
+	m.executions.record(events, m.OrderHistory)
+	m.publish(events)
+}
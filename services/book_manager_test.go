@@ -0,0 +1,196 @@
+package services
+
+import (
+	"order-matching/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBookManager(t *testing.T) {
+	t.Parallel()
+
+	newBookManager := func() *BookManager {
+		bm := NewBookManager(0)
+		bm.CreateInstrument(models.Instrument{Symbol: "ETH-USD", BaseAsset: "ETH", QuoteAsset: "USD", TickSize: decimal("0.01"), LotSize: decimal("0.01")})
+		bm.CreateInstrument(models.Instrument{Symbol: "BTC-USD", BaseAsset: "BTC", QuoteAsset: "USD", TickSize: decimal("0.5"), LotSize: decimal("0.001")})
+		return bm
+	}
+
+	t.Run("It lists instruments by symbol", func(t *testing.T) {
+		t.Parallel()
+		bm := newBookManager()
+
+		instruments := bm.GetInstrumentList()
+
+		assert.Equal(t, 2, len(instruments))
+		assert.Equal(t, "BTC-USD", instruments[0].Symbol)
+		assert.Equal(t, "ETH-USD", instruments[1].Symbol)
+		assert.Equal(t, models.InstrumentTrading, instruments[0].Status)
+	})
+
+	t.Run("It refuses a symbol that is already listed", func(t *testing.T) {
+		t.Parallel()
+		bm := newBookManager()
+
+		_, err := bm.CreateInstrument(models.Instrument{Symbol: "BTC-USD", BaseAsset: "BTC", QuoteAsset: "EUR", TickSize: decimal("0.01"), LotSize: decimal("0.01")})
+
+		assert.ErrorIs(t, err, ErrInstrumentExists)
+		instrument, _ := bm.GetInstrument("BTC-USD")
+		assert.Equal(t, "USD", instrument.QuoteAsset)
+	})
+
+	t.Run("It returns an error for an unknown symbol", func(t *testing.T) {
+		t.Parallel()
+		bm := newBookManager()
+
+		_, err := bm.Market("DOGE-USD")
+
+		assert.ErrorIs(t, err, ErrInstrumentNotFound)
+	})
+
+	t.Run("It keeps a separate order book per market", func(t *testing.T) {
+		t.Parallel()
+		bm := newBookManager()
+		btc, _ := bm.Market("BTC-USD")
+		eth, _ := bm.Market("ETH-USD")
+
+		btc.OrderBook.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Symbol: "BTC-USD", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("1.0")})
+		trades := eth.OrderBook.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Symbol: "ETH-USD", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0")})
+
+		assert.Equal(t, 0, len(trades))
+		assert.Equal(t, decimal("0.5"), btc.OrderBook.TickSize)
+		assert.Equal(t, 1, btc.OrderBook.SellLevels.Len())
+		assert.Equal(t, 1, eth.OrderBook.BuyLevels.Len())
+	})
+
+	t.Run("It refuses an order whose notional overflows", func(t *testing.T) {
+		t.Parallel()
+		bm := newBookManager()
+		eth, _ := bm.Market("ETH-USD")
+
+		_, err := bm.PlaceOrder(eth, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Sell, Price: decimal("90000000000.0"), Amount: decimal("2.0")})
+
+		assert.ErrorIs(t, err, models.ErrDecimalOverflow)
+		_, exists := eth.OrderHistory.GetOrder("550e8400-e29b-41d4-a716-446655440000")
+		assert.False(t, exists)
+	})
+
+	t.Run("It refuses an amendment outside the quantity limits", func(t *testing.T) {
+		t.Parallel()
+		bm := NewBookManager(0)
+		bm.CreateInstrument(models.Instrument{Symbol: "ETH-USD", BaseAsset: "ETH", QuoteAsset: "USD", TickSize: decimal("0.01"), LotSize: decimal("0.01"), MinQuantity: decimal("1.0"), MaxQuantity: decimal("5.0")})
+		eth, _ := bm.Market("ETH-USD")
+		bm.PlaceOrder(eth, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("2.0")})
+		bm.PlaceOrder(eth, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0")})
+
+		above := decimal("5.0")
+		_, _, err := bm.AmendOrder(eth, "550e8400-e29b-41d4-a716-446655440000", models.OrderAmendment{Amount: &above})
+		assert.ErrorIs(t, err, models.ErrQuantityLimits, "1.0 filled and 5.0 remaining is above the maximum")
+
+		bm.PlaceOrder(eth, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440002", Action: models.Sell, Price: decimal("110.0"), Amount: decimal("2.0")})
+		below := decimal("0.5")
+		_, _, err = bm.AmendOrder(eth, "550e8400-e29b-41d4-a716-446655440002", models.OrderAmendment{Amount: &below})
+		assert.ErrorIs(t, err, models.ErrQuantityLimits)
+		order, _ := eth.OrderBook.GetOrder("550e8400-e29b-41d4-a716-446655440002")
+		assert.Equal(t, decimal("2.0"), order.RemainingAmount)
+	})
+
+	t.Run("It refuses an amendment whose notional overflows", func(t *testing.T) {
+		t.Parallel()
+		bm := newBookManager()
+		eth, _ := bm.Market("ETH-USD")
+		bm.PlaceOrder(eth, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0")})
+
+		price := decimal("90000000000.0")
+		amount := decimal("2.0")
+		_, _, err := bm.AmendOrder(eth, "550e8400-e29b-41d4-a716-446655440000", models.OrderAmendment{Price: &price, Amount: &amount})
+
+		assert.ErrorIs(t, err, models.ErrDecimalOverflow)
+		order, _ := eth.OrderBook.GetOrder("550e8400-e29b-41d4-a716-446655440000")
+		assert.Equal(t, decimal("100.0"), order.Price)
+	})
+
+	t.Run("It expires orders in every market", func(t *testing.T) {
+		t.Parallel()
+		bm := newBookManager()
+		btc, _ := bm.Market("BTC-USD")
+		eth, _ := bm.Market("ETH-USD")
+		expireAt := time.Now().UTC().Add(time.Hour)
+
+		btc.OrderBook.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("1.0"), TimeInForce: models.GoodTillDate, ExpireAt: expireAt})
+		eth.OrderBook.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("1.0"), TimeInForce: models.GoodTillDate, ExpireAt: expireAt})
+
+		expired := bm.ExpireOrders(expireAt)
+
+		assert.Equal(t, 2, len(expired))
+	})
+
+	t.Run("It writes every change through to the repository", func(t *testing.T) {
+		t.Parallel()
+		repo := newRecordingRepository()
+		bm := NewBookManager(0)
+		bm.SetRepository(repo)
+		bm.CreateInstrument(models.Instrument{Symbol: "BTC-USD", BaseAsset: "BTC", QuoteAsset: "USD", TickSize: decimal("0.01"), LotSize: decimal("0.01")})
+		market, _ := bm.Market("BTC-USD")
+		expireAt := time.Now().UTC().Add(time.Hour)
+
+		bm.PlaceOrder(market, &models.Order{Symbol: "BTC-USD", ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("3.0")})
+		bm.PlaceOrder(market, &models.Order{Symbol: "BTC-USD", ID: "550e8400-e29b-41d4-a716-446655440001", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0")})
+		bm.PlaceOrder(market, &models.Order{Symbol: "BTC-USD", ID: "550e8400-e29b-41d4-a716-446655440002", Action: models.Buy, Price: decimal("99.0"), Amount: decimal("1.0")})
+		bm.PlaceOrder(market, &models.Order{Symbol: "BTC-USD", ID: "550e8400-e29b-41d4-a716-446655440003", Action: models.Buy, Price: decimal("98.0"), Amount: decimal("1.0"), TimeInForce: models.GoodTillDate, ExpireAt: expireAt})
+		bm.CancelOrder(market, "550e8400-e29b-41d4-a716-446655440002")
+		bm.ExpireOrders(expireAt)
+
+		assert.Equal(t, market.Instrument, repo.instruments["BTC-USD"])
+
+		maker := repo.orders["550e8400-e29b-41d4-a716-446655440000"]
+		assert.Equal(t, models.StatusPartiallyFilled, maker.Status, "the resting maker is updated by the fill")
+		assert.Equal(t, decimal("2.0"), maker.RemainingAmount)
+		taker := repo.orders["550e8400-e29b-41d4-a716-446655440001"]
+		assert.Equal(t, models.StatusFilled, taker.Status)
+		canceled := repo.orders["550e8400-e29b-41d4-a716-446655440002"]
+		assert.Equal(t, models.StatusCanceled, canceled.Status)
+		expired := repo.orders["550e8400-e29b-41d4-a716-446655440003"]
+		assert.Equal(t, models.StatusExpired, expired.Status)
+
+		assert.Equal(t, 1, len(repo.trades))
+		assert.Equal(t, "550e8400-e29b-41d4-a716-446655440000", repo.trades[0].MakerOrderID)
+	})
+}
+
+// recordingRepository keeps the latest state of everything saved to it.
+type recordingRepository struct {
+	instruments map[string]models.Instrument
+	orders map[string]models.Order
+	trades []models.Trade
+}
+
+func newRecordingRepository() *recordingRepository {
+	return &recordingRepository{
+		instruments: make(map[string]models.Instrument),
+		orders: make(map[string]models.Order),
+	}
+}
+
+func (rr *recordingRepository) SaveInstrument(instrument models.Instrument) error {
+	rr.instruments[instrument.Symbol] = instrument
+	return nil
+}
+
+func (rr *recordingRepository) SaveOrders(orders ...models.Order) error {
+	for _, order := range orders {
+		rr.orders[order.ID] = order
+	}
+	return nil
+}
+
+func (rr *recordingRepository) SaveTrades(trades ...models.Trade) error {
+	rr.trades = append(rr.trades, trades...)
+	return nil
+}
+
+func (rr *recordingRepository) Close() error {
+	return nil
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"order-matching/models"
+	"time"
+)
+
+var (
+	ErrMarketClosed = errors.New("market is closed")
+	ErrMarketHalted = errors.New("market is halted")
+)
+
+// Reasons a market leaves OPEN, given in its state.
+const ReasonCircuitBreaker = "CIRCUIT_BREAKER" // the last trade moved beyond the band
+const ReasonManual = "MANUAL" // an admin halted or closed the market
+
+// HaltPolicy decides when the circuit breaker halts a market, how it reopens
+// and what happens to the orders placed while it is halted.
+type HaltPolicy struct {
+	// A market halts when its last trade is further than this fraction of
+	// the price from any trade in the window before it; 0 turns the circuit
+	// breaker off.
+	Band models.Decimal
+	Window time.Duration
+	// A circuit breaker halt ends on its own after the cooldown; 0 keeps the
+	// market halted until it is resumed.
+	Cooldown time.Duration
+	// A halted market reopens through an auction of this length, in which
+	// orders are queued and then uncrossed at a single price, before
+	// matching restarts; 0 reopens it directly.
+	Auction time.Duration
+	// Whether orders placed while the market is halted are queued until it
+	// reopens, rather than refused with ErrMarketHalted.
+	Queue bool
+}
+
+// SetHaltPolicy sets when the circuit breaker halts the markets and how they
+// reopen. It must be called before the markets are taken into use.
+func (bm *BookManager) SetHaltPolicy(policy HaltPolicy) {
+	bm.halts = policy
+}
+
+// State returns the trading state of the market. It is safe for concurrent
+// use.
+func (m *Market) State() models.MarketState {
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+
+	return m.state
+}
+
+// checkOrder refuses an order the trading state of the market does not let in:
+// every order while the market is closed, and while it is not open those that
+// cannot rest in the book or, unless the policy queues them, any order at all.
+func (bm *BookManager) checkOrder(market *Market, order *models.Order) error {
+	switch state := market.State().State; {
+	case state == models.StateClosed:
+		return ErrMarketClosed
+	case state == models.StateOpen:
+		return nil
+	case !order.Rests() || order.IsStop():
+		// MARKET, IOC and FOK orders must trade against the book as it is
+		// now, and a stop order waits for a trade price the reopening
+		// cannot be expected to keep
+		return ErrMarketHalted
+	case state == models.StateHalted && !bm.halts.Queue:
+		return ErrMarketHalted
+	}
+
+	return nil
+}
+
+// checkAmendment refuses an amendment while the market is not open.
+func (bm *BookManager) checkAmendment(market *Market) error {
+	switch market.State().State {
+	case models.StateOpen:
+		return nil
+	case models.StateClosed:
+		return ErrMarketClosed
+	}
+
+	return ErrMarketHalted
+}
+
+// Halt stops matching in the market until it is resumed. Only the sequencer
+// calls it once it runs.
+func (bm *BookManager) Halt(market *Market) (models.MarketState, error) {
+	return bm.setState(market, models.MarketState{State: models.StateHalted, Reason: ReasonManual, Since: time.Now().UTC()})
+}
+
+// Close stops the market taking new orders until it is resumed; resting orders
+// can still be canceled. Only the sequencer calls it once it runs.
+func (bm *BookManager) Close(market *Market) (models.MarketState, error) {
+	return bm.setState(market, models.MarketState{State: models.StateClosed, Reason: ReasonManual, Since: time.Now().UTC()})
+}
+
+// Resume reopens a halted or closed market, through an auction if the policy
+// has one, and sends the orders queued in the meantime through matching once
+// it is open. Only the sequencer calls it once it runs.
+func (bm *BookManager) Resume(market *Market) (models.MarketState, error) {
+	state := market.State()
+	if state.State == models.StateOpen || state.State == models.StateAuction {
+		return state, nil
+	}
+
+	return bm.reopen(market, time.Now().UTC())
+}
+
+// ReopenMarkets moves on every market whose halt or auction is over at now:
+// a halted market to its auction or open, an auction to open. A market whose
+// change cannot be journaled is left for the next sweep.
+func (bm *BookManager) ReopenMarkets(now time.Time) {
+	bm.mutex.RLock()
+	defer bm.mutex.RUnlock()
+
+	for _, symbol := range bm.symbols {
+		market := bm.markets[symbol]
+		state := market.State()
+		if state.Until.IsZero() || now.Before(state.Until) {
+			continue
+		}
+
+		var err error
+		switch state.State {
+		case models.StateHalted:
+			_, err = bm.reopen(market, now)
+		case models.StateAuction:
+			_, err = bm.setState(market, models.MarketState{State: models.StateOpen, Since: now})
+		}
+		if err != nil {
+			fmt.Println(err.Error())
+		}
+	}
+}
+
+func (bm *BookManager) reopen(market *Market, now time.Time) (models.MarketState, error) {
+	if bm.halts.Auction > 0 {
+		return bm.setState(market, models.MarketState{State: models.StateAuction, Since: now, Until: now.Add(bm.halts.Auction)})
+	}
+
+	return bm.setState(market, models.MarketState{State: models.StateOpen, Since: now})
+}
+
+// setState journals and applies a change of the trading state of the market,
+// and returns the state the market is in afterwards: the trades of the orders
+// released by reopening it can trip the circuit breaker again.
+func (bm *BookManager) setState(market *Market, state models.MarketState) (models.MarketState, error) {
+	command, err := bm.record(JournalEntry{Command: CommandSetMarketState, Timestamp: state.Since, Symbol: market.Instrument.Symbol, State: &state})
+	if err != nil {
+		return models.MarketState{}, err
+	}
+
+	trades := market.setState(command)
+	bm.tripBreaker(market, trades, command.Timestamp)
+
+	return market.State(), nil
+}
+
+// tripBreaker halts a market whose last trade moved beyond the band from any
+// trade of the window before it. The halt is journaled like a manual one, so
+// replaying the journal does not depend on the policy of the time.
+func (bm *BookManager) tripBreaker(market *Market, trades []models.Trade, now time.Time) {
+	if bm.halts.Band == 0 || len(trades) == 0 {
+		return
+	}
+
+	market.window = append(market.window, trades...)
+	start := now.Add(-bm.halts.Window)
+	for len(market.window) > 0 && market.window[0].Timestamp.Before(start) {
+		market.window = market.window[1:]
+	}
+
+	last := trades[len(trades)-1].Price
+	for _, trade := range market.window {
+		if !beyond(last, trade.Price, bm.halts.Band) {
+			continue
+		}
+
+		halt := models.MarketState{State: models.StateHalted, Reason: ReasonCircuitBreaker, Since: now}
+		if bm.halts.Cooldown > 0 {
+			halt.Until = now.Add(bm.halts.Cooldown)
+		}
+		if _, err := bm.setState(market, halt); err != nil {
+			fmt.Println(err.Error())
+		}
+		return
+	}
+}
+
+// setState applies a change of the trading state. Matching stops in any state
+// but OPEN; reopening releases the orders queued in the meantime.
+func (m *Market) setState(command JournalEntry) []models.Trade {
+	m.stateMutex.Lock()
+	m.state = *command.State
+	m.stateMutex.Unlock()
+
+	if command.State.State != models.StateOpen {
+		// prices from before the halt say nothing about those after it
+		m.window = nil
+		m.OrderBook.Halt()
+		return nil
+	}
+	if !m.OrderBook.Halted() {
+		return nil
+	}
+
+	trades := m.OrderBook.ResumeAt(command.Timestamp)
+	m.TradeHistory.Record(trades...)
+	m.commit(command, trades)
+
+	return trades
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"order-matching/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	// newMarket lists BTC-USD with asks at 100 and 115 under the policy
+	newMarket := func(policy HaltPolicy) (*BookManager, *Market) {
+		bm := NewBookManager(0)
+		bm.SetHaltPolicy(policy)
+		bm.CreateInstrument(models.Instrument{Symbol: "BTC-USD", BaseAsset: "BTC", QuoteAsset: "USD", TickSize: decimal("0.01"), LotSize: decimal("0.01")})
+		market, _ := bm.Market("BTC-USD")
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("1.0")})
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Action: models.Sell, Price: decimal("115.0"), Amount: decimal("5.0")})
+		return bm, market
+	}
+	policy := HaltPolicy{Band: decimal("0.1"), Window: time.Minute, Cooldown: 5 * time.Minute}
+
+	t.Run("It halts the market when the last trade moves beyond the band", func(t *testing.T) {
+		t.Parallel()
+		bm, market := newMarket(policy)
+
+		trades, err := bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440010", Action: models.Buy, Price: decimal("115.0"), Amount: decimal("2.0")})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(trades), "the order that trips the breaker trades in full")
+		state := market.State()
+		assert.Equal(t, models.StateHalted, state.State)
+		assert.Equal(t, ReasonCircuitBreaker, state.Reason)
+		assert.Equal(t, state.Since.Add(5*time.Minute), state.Until)
+	})
+
+	t.Run("It compares the last trade with the trades of earlier commands", func(t *testing.T) {
+		t.Parallel()
+		bm, market := newMarket(policy)
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440010", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0")})
+		assert.Equal(t, models.StateOpen, market.State().State)
+
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440011", Action: models.Buy, Price: decimal("115.0"), Amount: decimal("1.0")})
+
+		assert.Equal(t, models.StateHalted, market.State().State)
+	})
+
+	t.Run("It keeps the market open for trades within the band", func(t *testing.T) {
+		t.Parallel()
+		bm, market := newMarket(HaltPolicy{Band: decimal("0.15"), Window: time.Minute})
+
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440010", Action: models.Buy, Price: decimal("115.0"), Amount: decimal("2.0")})
+
+		assert.Equal(t, models.StateOpen, market.State().State)
+	})
+
+	t.Run("It refuses orders and amendments while halted unless they are queued", func(t *testing.T) {
+		t.Parallel()
+		bm, market := newMarket(HaltPolicy{})
+		bm.Halt(market)
+
+		_, err := bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440010", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0")})
+		assert.Equal(t, ErrMarketHalted, err)
+		_, exists := market.OrderHistory.GetOrder("550e8400-e29b-41d4-a716-446655440010")
+		assert.False(t, exists)
+
+		amount := decimal("2.0")
+		_, _, err = bm.AmendOrder(market, "550e8400-e29b-41d4-a716-446655440000", models.OrderAmendment{Amount: &amount})
+		assert.Equal(t, ErrMarketHalted, err)
+	})
+
+	t.Run("It refuses orders that cannot rest while not open", func(t *testing.T) {
+		t.Parallel()
+		bm, market := newMarket(HaltPolicy{Queue: true})
+		bm.Halt(market)
+
+		for _, order := range []models.Order{
+			{ID: "550e8400-e29b-41d4-a716-446655440010", Action: models.Buy, Kind: models.Market, Amount: decimal("1.0")},
+			{ID: "550e8400-e29b-41d4-a716-446655440011", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0"), TimeInForce: models.ImmediateOrCancel},
+			{ID: "550e8400-e29b-41d4-a716-446655440012", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0"), TimeInForce: models.FillOrKill},
+			{ID: "550e8400-e29b-41d4-a716-446655440013", Action: models.Sell, Kind: models.Stop, StopPrice: decimal("90.0"), Amount: decimal("1.0")},
+			{ID: "550e8400-e29b-41d4-a716-446655440014", Action: models.Buy, Kind: models.StopLimit, StopPrice: decimal("110.0"), Price: decimal("110.0"), Amount: decimal("1.0")},
+		} {
+			_, err := bm.PlaceOrder(market, &order)
+			assert.Equal(t, ErrMarketHalted, err, order.ID)
+		}
+	})
+
+	t.Run("It refuses every order while closed", func(t *testing.T) {
+		t.Parallel()
+		bm, market := newMarket(HaltPolicy{Queue: true})
+		state, _ := bm.Close(market)
+		assert.Equal(t, models.MarketState{State: models.StateClosed, Reason: ReasonManual, Since: state.Since}, state)
+
+		_, err := bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440010", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0")})
+		assert.Equal(t, ErrMarketClosed, err)
+
+		_, err = bm.CancelOrder(market, "550e8400-e29b-41d4-a716-446655440000")
+		assert.Nil(t, err, "resting orders can still be canceled")
+	})
+
+	t.Run("It queues orders while halted and matches them in order once resumed", func(t *testing.T) {
+		t.Parallel()
+		bm, market := newMarket(HaltPolicy{Queue: true})
+		bm.Halt(market)
+
+		trades, err := bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440010", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0")})
+		assert.Nil(t, err)
+		assert.Nil(t, trades)
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440011", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0")})
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440012", Action: models.Buy, Price: decimal("115.0"), Amount: decimal("1.0")})
+		bm.CancelOrder(market, "550e8400-e29b-41d4-a716-446655440012")
+		_, err = bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440013", Action: models.Buy, Kind: models.Market, Amount: decimal("1.0")})
+		assert.Equal(t, ErrMarketHalted, err, "a market order is never queued")
+		order, _ := market.OrderHistory.GetOrder("550e8400-e29b-41d4-a716-446655440010")
+		assert.Equal(t, models.StatusNew, order.Status)
+
+		state, err := bm.Resume(market)
+
+		assert.Nil(t, err)
+		assert.Equal(t, models.StateOpen, state.State)
+		assert.Equal(t, 1, len(market.TradeHistory.all()))
+		assert.Equal(t, "550e8400-e29b-41d4-a716-446655440010", market.TradeHistory.all()[0].TakerOrderID)
+		order, _ = market.OrderHistory.GetOrder("550e8400-e29b-41d4-a716-446655440011")
+		assert.Equal(t, models.StatusNew, order.Status, "the second order rests behind the first")
+		order, _ = market.OrderHistory.GetOrder("550e8400-e29b-41d4-a716-446655440012")
+		assert.Equal(t, models.StatusCanceled, order.Status)
+	})
+
+	t.Run("It reopens the market through an auction once the cooldown is over", func(t *testing.T) {
+		t.Parallel()
+		bm, market := newMarket(HaltPolicy{Band: decimal("0.1"), Window: time.Minute, Cooldown: 5 * time.Minute, Auction: time.Minute})
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440010", Action: models.Buy, Price: decimal("115.0"), Amount: decimal("2.0")})
+		halt := market.State()
+
+		bm.ReopenMarkets(halt.Until.Add(-time.Second))
+		assert.Equal(t, models.StateHalted, market.State().State)
+
+		bm.ReopenMarkets(halt.Until)
+		assert.Equal(t, models.MarketState{State: models.StateAuction, Since: halt.Until, Until: halt.Until.Add(time.Minute)}, market.State())
+		_, err := bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440011", Action: models.Buy, Price: decimal("115.0"), Amount: decimal("1.0")})
+		assert.Nil(t, err, "an auction queues orders whatever the policy")
+
+		bm.ReopenMarkets(halt.Until.Add(time.Minute))
+		assert.Equal(t, models.MarketState{State: models.StateOpen, Since: halt.Until.Add(time.Minute)}, market.State())
+		order, _ := market.OrderHistory.GetOrder("550e8400-e29b-41d4-a716-446655440011")
+		assert.Equal(t, models.StatusFilled, order.Status)
+	})
+
+	t.Run("It keeps a halt without a cooldown until the market is resumed", func(t *testing.T) {
+		t.Parallel()
+		bm, market := newMarket(HaltPolicy{Band: decimal("0.1"), Window: time.Minute})
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440010", Action: models.Buy, Price: decimal("115.0"), Amount: decimal("2.0")})
+
+		bm.ReopenMarkets(time.Now().UTC().Add(time.Hour))
+
+		assert.Equal(t, models.StateHalted, market.State().State)
+		assert.True(t, market.State().Until.IsZero())
+	})
+
+	t.Run("It replays halts and queued orders from the journal whatever the policy", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		journal, entries, _ := OpenJournal(dir, 0)
+		bm := NewBookManager(0)
+		bm.Recover(journal, entries)
+		bm.SetHaltPolicy(HaltPolicy{Band: decimal("0.1"), Window: time.Minute, Queue: true})
+		bm.CreateInstrument(models.Instrument{Symbol: "BTC-USD", BaseAsset: "BTC", QuoteAsset: "USD", TickSize: decimal("0.01"), LotSize: decimal("0.01")})
+		market, _ := bm.Market("BTC-USD")
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("1.0")})
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Action: models.Sell, Price: decimal("115.0"), Amount: decimal("5.0")})
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440010", Action: models.Buy, Price: decimal("115.0"), Amount: decimal("2.0")})
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440011", Action: models.Buy, Price: decimal("115.0"), Amount: decimal("1.0")})
+		journal.Close()
+
+		journal, entries, _ = OpenJournal(dir, 0)
+		defer journal.Close()
+		recovered := NewBookManager(0)
+		assert.Nil(t, recovered.Recover(journal, entries))
+		recoveredMarket, _ := recovered.Market("BTC-USD")
+
+		assert.Equal(t, market.State(), recoveredMarket.State())
+		assert.Equal(t, market.OrderBook.Snapshot(), recoveredMarket.OrderBook.Snapshot())
+		recovered.Resume(recoveredMarket)
+		order, _ := recoveredMarket.OrderHistory.GetOrder("550e8400-e29b-41d4-a716-446655440011")
+		assert.Equal(t, models.StatusFilled, order.Status)
+	})
+
+	t.Run("It keeps the trades of the window through a snapshot", func(t *testing.T) {
+		t.Parallel()
+		bm, market := newMarket(policy)
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440010", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0")})
+
+		restored := NewBookManager(0)
+		restored.SetHaltPolicy(policy)
+		assert.Nil(t, restored.Restore(bm.Snapshot()))
+		restoredMarket, _ := restored.Market("BTC-USD")
+		restored.PlaceOrder(restoredMarket, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440011", Action: models.Buy, Price: decimal("115.0"), Amount: decimal("1.0")})
+
+		assert.Equal(t, models.StateHalted, restoredMarket.State().State)
+	})
+
+	t.Run("It restores the state and the queued orders from a snapshot", func(t *testing.T) {
+		t.Parallel()
+		bm, market := newMarket(HaltPolicy{Queue: true})
+		bm.Halt(market)
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440010", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0")})
+
+		restored := NewBookManager(0)
+		assert.Nil(t, restored.Restore(bm.Snapshot()))
+		restoredMarket, _ := restored.Market("BTC-USD")
+
+		assert.Equal(t, market.State(), restoredMarket.State())
+		assert.True(t, restoredMarket.OrderBook.Halted())
+		restored.Resume(restoredMarket)
+		order, _ := restoredMarket.OrderHistory.GetOrder("550e8400-e29b-41d4-a716-446655440010")
+		assert.Equal(t, models.StatusFilled, order.Status)
+	})
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+var ErrEventLogCorrupt = errors.New("event log is corrupt")
+
+// EventLog records the events of the markets in a file, one JSON encoded event
+// per line, so that a replay of the journal can be compared with what the
+// service actually did. It is written to on every command but only synced to
+// disk when it is closed: it is a record for investigations, and the markets
+// never depend on it. It is safe for concurrent use.
+type EventLog struct {
+	mutex sync.Mutex
+	file *os.File
+	command uint64 // journal sequence of the last command whose events are in the log
+}
+
+// OpenEventLog opens the event log at path, creating it if needed. The events
+// of a command are written together; if a crash cut them off, all of them are
+// dropped. Events of commands already in the log are not written again, so the
+// journal can be replayed into the markets with the log subscribed.
+func OpenEventLog(path string) (*EventLog, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	events, size, err := readEventLog(file)
+	if err == nil {
+		err = file.Truncate(size)
+	}
+	if err == nil {
+		_, err = file.Seek(size, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	eventLog := &EventLog{file: file}
+	if len(events) > 0 {
+		eventLog.command = events[len(events)-1].Command
+	}
+
+	return eventLog, nil
+}
+
+// ReadEventLog reads back every event in the event log at path.
+func ReadEventLog(path string) ([]Event, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	events, _, err := readEventLog(file)
+	return events, err
+}
+
+// readEventLog decodes the events of a log and returns them with the size of
+// the part of the file that holds the events of complete commands.
+func readEventLog(file *os.File) ([]Event, int64, error) {
+	reader := bufio.NewReader(file)
+	events := []Event{}
+	var offset int64
+	var commandStart int64 // offset of the first event of the last command
+	commandIndex := 0
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) == 0 {
+				return events, offset, nil
+			}
+			return events[:commandIndex], commandStart, nil // torn last line
+		} else if err != nil {
+			return nil, 0, err
+		}
+
+		var event Event
+		if err := json.Unmarshal(line, &event); err != nil {
+			if _, err := reader.Peek(1); err == io.EOF {
+				return events[:commandIndex], commandStart, nil
+			}
+			return nil, 0, fmt.Errorf("%w: bad event at offset %d", ErrEventLogCorrupt, offset)
+		}
+
+		if len(events) == 0 || event.Command != events[len(events)-1].Command {
+			commandStart = offset
+			commandIndex = len(events)
+		}
+		events = append(events, event)
+		offset += int64(len(line))
+	}
+}
+
+// Publish writes the events of a command to the log. It is an EventHandler.
+func (el *EventLog) Publish(events []Event) {
+	el.mutex.Lock()
+	defer el.mutex.Unlock()
+
+	if len(events) == 0 || events[0].Command <= el.command {
+		return
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+	}
+
+	if _, err := el.file.Write(buffer.Bytes()); err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	el.command = events[0].Command
+}
+
+// Close syncs the log to disk and closes it.
+func (el *EventLog) Close() error {
+	el.mutex.Lock()
+	defer el.mutex.Unlock()
+
+	if err := el.file.Sync(); err != nil {
+		el.file.Close()
+		return err
+	}
+
+	return el.file.Close()
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"order-matching/models"
+	"time"
+)
+
+// EventType is the kind of outcome an event reports.
+type EventType string
+
+const EventOrderAccepted EventType = "ORDER_ACCEPTED"
+const EventOrderTriggered EventType = "ORDER_TRIGGERED" // a stop order's stop price was reached
+const EventOrderRested EventType = "ORDER_RESTED" // the order joined the back of the queue at its price
+const EventTrade EventType = "TRADE"
+const EventOrderAmended EventType = "ORDER_AMENDED"
+const EventOrderCanceled EventType = "ORDER_CANCELED"
+const EventOrderExpired EventType = "ORDER_EXPIRED"
+const EventOrderRejected EventType = "ORDER_REJECTED"
+const EventCommandRejected EventType = "COMMAND_REJECTED" // a cancel or amendment the book could not apply
+
+// Reasons given with ORDER_CANCELED, ORDER_REJECTED and COMMAND_REJECTED
+// events. A rejected command carries the error it failed with instead.
+const ReasonCanceled = "CANCELED"
+const ReasonUnfilled = "UNFILLED" // the remainder of a MARKET or IOC order
+const ReasonFillOrKill = "FILL_OR_KILL"
+const ReasonSelfTrade = "SELF_TRADE_PREVENTION"
+const ReasonPostOnly = "POST_ONLY"
+
+// Event is one outcome of a command applied to an order book. Events are
+// numbered per market in the order they happen and carry the sequence number
+// of the journal entry of the command that produced them, so applying the
+// same commands to a fresh order book produces the same events again.
+type Event struct {
+	Sequence uint64 `json:"sequence"`
+	Command uint64 `json:"command"` // journal sequence of the command, 0 without a journal
+	Type EventType `json:"type"`
+	Symbol string `json:"symbol"`
+	Timestamp time.Time `json:"timestamp"`
+	OrderID string `json:"order_id,omitempty"`
+	Order *models.Order `json:"order,omitempty"` // the order as the event left it
+	Trade *models.Trade `json:"trade,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// EventHandler receives the events of every command applied to the markets,
+// in the order they happened. It is called with the order book still
+// guarded, so it must not block.
+type EventHandler func(events []Event)
+
+// emit records an event about an order, with a copy of the order as it is now.
+func (ob *OrderBook) emit(eventType EventType, order *models.Order, timestamp time.Time, reason string) {
+	ob.eventSequence++
+	copied := *order
+	ob.events = append(ob.events, Event{
+		Sequence: ob.eventSequence,
+		Type: eventType,
+		Timestamp: timestamp,
+		OrderID: order.ID,
+		Order: &copied,
+		Reason: reason,
+	})
+}
+
+func (ob *OrderBook) emitTrade(trade models.Trade) {
+	ob.eventSequence++
+	ob.events = append(ob.events, Event{
+		Sequence: ob.eventSequence,
+		Type: EventTrade,
+		Timestamp: trade.Timestamp,
+		Trade: &trade,
+	})
+}
+
+// emitRejection records a command the book refused.
+func (ob *OrderBook) emitRejection(id string, timestamp time.Time, err error) {
+	ob.eventSequence++
+	ob.events = append(ob.events, Event{
+		Sequence: ob.eventSequence,
+		Type: EventCommandRejected,
+		Timestamp: timestamp,
+		OrderID: id,
+		Reason: err.Error(),
+	})
+}
+
+// Replay feeds journaled commands into a fresh set of markets, starting from
+// the snapshot if one is given, and returns the events they produce.
+func Replay(snapshot *Snapshot, entries []JournalEntry) ([]Event, error) {
+	bm := NewBookManager(0)
+	if snapshot != nil {
+		if err := bm.Restore(snapshot); err != nil {
+			return nil, err
+		}
+	}
+
+	events := []Event{}
+	bm.Subscribe(func(produced []Event) {
+		events = append(events, produced...)
+	})
+	if err := bm.Recover(nil, entries); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// DiffEvents compares replayed events with recorded ones, as they would be
+// written to the event log, and describes the first difference. It returns an
+// empty string when they are the same.
+func DiffEvents(recorded []Event, replayed []Event) string {
+	for index := 0; index < len(recorded) || index < len(replayed); index++ {
+		if index >= len(recorded) {
+			return fmt.Sprintf("event %d was not recorded:\n  replayed: %s", index+1, encodeEvent(replayed[index]))
+		}
+		if index >= len(replayed) {
+			return fmt.Sprintf("event %d was not replayed:\n  recorded: %s", index+1, encodeEvent(recorded[index]))
+		}
+
+		expected, actual := encodeEvent(recorded[index]), encodeEvent(replayed[index])
+		if expected != actual {
+			return fmt.Sprintf("event %d differs, for journal entry %d:\n  recorded: %s\n  replayed: %s", index+1, recorded[index].Command, expected, actual)
+		}
+	}
+
+	return ""
+}
+
+func encodeEvent(event Event) string {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err.Error()
+	}
+
+	return string(data)
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"os"
+	"order-matching/models"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderBookEvents(t *testing.T) {
+	t.Parallel()
+
+	eventTypes := func(events []Event) []EventType {
+		types := []EventType{}
+		for _, event := range events {
+			types = append(types, event.Type)
+		}
+		return types
+	}
+
+	t.Run("It reports every outcome of a placement in order", func(t *testing.T) {
+		t.Parallel()
+		orderBook := NewOrderBook()
+		orderBook.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("1.0")})
+		assert.Equal(t, []EventType{EventOrderAccepted, EventOrderRested}, eventTypes(orderBook.Events()))
+
+		orderBook.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("2.0"), TimeInForce: models.ImmediateOrCancel})
+
+		events := orderBook.Events()
+		assert.Equal(t, []EventType{EventOrderAccepted, EventTrade, EventOrderCanceled}, eventTypes(events))
+		assert.Equal(t, uint64(3), events[0].Sequence, "events are numbered across commands")
+		assert.Equal(t, models.StatusNew, events[0].Order.Status, "each event has the order as it was then")
+		assert.Equal(t, decimal("1.0"), events[1].Trade.Amount)
+		assert.Equal(t, ReasonUnfilled, events[2].Reason)
+		assert.Equal(t, decimal("1.0"), events[2].Order.FilledAmount)
+	})
+
+	t.Run("It reports rejections, triggers, amendments and expiries", func(t *testing.T) {
+		t.Parallel()
+		orderBook := NewOrderBook()
+		now := time.Now().UTC()
+		orderBook.PlaceOrderAt(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("1.0")}, now)
+		orderBook.PlaceOrderAt(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Action: models.Buy, Price: decimal("99.0"), Amount: decimal("1.0"), TimeInForce: models.GoodTillDate, ExpireAt: now.Add(time.Minute)}, now)
+
+		orderBook.PlaceOrderAt(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440002", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0"), PostOnly: models.PostOnlyReject}, now)
+		assert.Equal(t, []EventType{EventOrderAccepted, EventOrderRejected}, eventTypes(orderBook.Events()))
+		assert.Equal(t, ReasonPostOnly, orderBook.Events()[1].Reason)
+
+		orderBook.CancelOrderAt("550e8400-e29b-41d4-a716-446655440009", now)
+		assert.Equal(t, []EventType{EventCommandRejected}, eventTypes(orderBook.Events()))
+		assert.Equal(t, ErrOrderNotFound.Error(), orderBook.Events()[0].Reason)
+
+		amount := decimal("0.5")
+		orderBook.AmendOrderAt("550e8400-e29b-41d4-a716-446655440001", models.OrderAmendment{Amount: &amount}, now)
+		assert.Equal(t, []EventType{EventOrderAmended}, eventTypes(orderBook.Events()))
+
+		orderBook.PlaceOrderAt(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440003", Action: models.Sell, Kind: models.Stop, StopPrice: decimal("100.0"), Amount: decimal("0.5")}, now)
+		assert.Equal(t, []EventType{EventOrderAccepted}, eventTypes(orderBook.Events()), "a pending stop only is accepted")
+		orderBook.PlaceOrderAt(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440004", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0")}, now)
+		assert.Equal(t, []EventType{EventOrderAccepted, EventTrade, EventOrderTriggered, EventTrade}, eventTypes(orderBook.Events()))
+
+		orderBook.ExpireOrders(now.Add(time.Minute))
+		assert.Equal(t, []EventType{}, eventTypes(orderBook.Events()), "the GTD order was filled by the stop")
+	})
+}
+
+func TestReplay(t *testing.T) {
+	t.Parallel()
+
+	// record runs a mix of commands through journaled markets with an event
+	// log subscribed, writing a snapshot halfway, and returns the directory.
+	record := func(t *testing.T) string {
+		dir := t.TempDir()
+		eventLog, err := OpenEventLog(filepath.Join(dir, "events.log"))
+		assert.Nil(t, err)
+		journal, entries, err := OpenJournal(dir, 0)
+		assert.Nil(t, err)
+		bm := NewBookManager(17 * time.Hour)
+		bm.Subscribe(eventLog.Publish)
+		assert.Nil(t, bm.Recover(journal, entries))
+		bm.CreateInstrument(models.Instrument{Symbol: "BTC-USD", BaseAsset: "BTC", QuoteAsset: "USD", TickSize: decimal("0.01"), LotSize: decimal("0.01")})
+		bm.CreateInstrument(models.Instrument{Symbol: "ETH-USD", BaseAsset: "ETH", QuoteAsset: "USD", TickSize: decimal("0.01"), LotSize: decimal("0.01")})
+		btc, _ := bm.Market("BTC-USD")
+		eth, _ := bm.Market("ETH-USD")
+
+		bm.PlaceOrder(btc, &models.Order{Symbol: "BTC-USD", ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("5.0"), DisplayAmount: decimal("2.0")})
+		bm.PlaceOrder(btc, &models.Order{Symbol: "BTC-USD", ID: "550e8400-e29b-41d4-a716-446655440001", Action: models.Buy, Price: decimal("98.0"), Amount: decimal("1.0"), TimeInForce: models.Day})
+		bm.PlaceOrder(btc, &models.Order{Symbol: "BTC-USD", ID: "550e8400-e29b-41d4-a716-446655440002", Action: models.Buy, Price: decimal("97.0"), Amount: decimal("1.0"), TimeInForce: models.Day})
+		bm.PlaceOrder(btc, &models.Order{Symbol: "BTC-USD", ID: "550e8400-e29b-41d4-a716-446655440003", Action: models.Sell, Kind: models.Stop, StopPrice: decimal("100.0"), Amount: decimal("1.0")})
+		bm.PlaceOrder(eth, &models.Order{Symbol: "ETH-USD", ID: "550e8400-e29b-41d4-a716-446655440004", Action: models.Buy, Price: decimal("10.0"), Amount: decimal("3.0"), TimeInForce: models.Day})
+		assert.Nil(t, WriteSnapshot(dir, bm.Snapshot())) // without compacting the journal
+
+		bm.PlaceOrder(btc, &models.Order{Symbol: "BTC-USD", ID: "550e8400-e29b-41d4-a716-446655440005", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("3.0")})
+		bm.PlaceOrder(btc, &models.Order{Symbol: "BTC-USD", ID: "550e8400-e29b-41d4-a716-446655440006", Action: models.Buy, Kind: models.Market, Amount: decimal("9.0")})
+		bm.CancelOrder(btc, "550e8400-e29b-41d4-a716-446655440000")
+		bm.PlaceOrder(btc, &models.Order{Symbol: "BTC-USD", ID: "550e8400-e29b-41d4-a716-446655440007", Action: models.Buy, Price: decimal("96.0"), Amount: decimal("1.0"), TimeInForce: models.Day})
+		bm.ExpireOrders(time.Now().UTC().Add(48 * time.Hour))
+
+		journal.Close()
+		eventLog.Close()
+		return dir
+	}
+
+	t.Run("It reproduces the recorded events from the journal", func(t *testing.T) {
+		t.Parallel()
+		dir := record(t)
+		entries, err := ReadJournal(dir)
+		assert.Nil(t, err)
+		recorded, err := ReadEventLog(filepath.Join(dir, "events.log"))
+		assert.Nil(t, err)
+
+		replayed, err := Replay(nil, entries)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "", DiffEvents(recorded, replayed))
+		assert.Equal(t, EventOrderExpired, replayed[len(replayed)-1].Type)
+	})
+
+	t.Run("It reproduces the events after a snapshot", func(t *testing.T) {
+		t.Parallel()
+		dir := record(t)
+		entries, _ := ReadJournal(dir)
+		recorded, _ := ReadEventLog(filepath.Join(dir, "events.log"))
+		snapshot, err := LoadSnapshot(dir)
+		assert.Nil(t, err)
+
+		replayed, err := Replay(snapshot, entries)
+
+		assert.Nil(t, err)
+		later := []Event{}
+		for _, event := range recorded {
+			if event.Command > snapshot.Sequence {
+				later = append(later, event)
+			}
+		}
+		assert.Equal(t, "", DiffEvents(later, replayed), "DAY orders expire in the same order after a restore")
+	})
+
+	t.Run("It describes the first difference", func(t *testing.T) {
+		t.Parallel()
+		dir := record(t)
+		entries, _ := ReadJournal(dir)
+		recorded, _ := ReadEventLog(filepath.Join(dir, "events.log"))
+		replayed, _ := Replay(nil, entries)
+
+		recorded[3].Order.Price = decimal("101.0")
+		assert.Contains(t, DiffEvents(recorded, replayed), "event 4 differs")
+		assert.Contains(t, DiffEvents(replayed, replayed[:len(replayed)-1]), "was not replayed")
+	})
+}
+
+func TestEventLog(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "events.log")
+	eventLog, err := OpenEventLog(path)
+	assert.Nil(t, err)
+	eventLog.Publish([]Event{{Sequence: 1, Command: 1, Type: EventOrderAccepted}, {Sequence: 2, Command: 1, Type: EventOrderRested}})
+	eventLog.Publish([]Event{{Sequence: 3, Command: 2, Type: EventOrderAccepted}, {Sequence: 4, Command: 2, Type: EventTrade}})
+	eventLog.Close()
+
+	// a crash in the middle of the second command's events
+	info, _ := os.Stat(path)
+	os.Truncate(path, info.Size()-10)
+
+	eventLog, err = OpenEventLog(path)
+	assert.Nil(t, err)
+	eventLog.Publish([]Event{{Sequence: 1, Command: 1, Type: EventOrderAccepted}, {Sequence: 2, Command: 1, Type: EventOrderRested}})
+	eventLog.Publish([]Event{{Sequence: 3, Command: 2, Type: EventOrderAccepted}, {Sequence: 4, Command: 2, Type: EventTrade}})
+	eventLog.Close()
+
+	events, err := ReadEventLog(path)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(events), "the cut off command is written again, the complete one is not")
+	assert.Equal(t, EventTrade, events[3].Type)
+}
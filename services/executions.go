@@ -0,0 +1,233 @@
+package services
+
+import (
+	"errors"
+	"order-matching/models"
+	"sync"
+	"time"
+)
+
+var ErrExecutionsGone = errors.New("execution reports are no longer kept")
+
+// executionsKept is how many of the latest execution reports are kept per
+// account for clients resuming their stream.
+const executionsKept = 1000
+
+// ExecutionType is the kind of change an execution report tells the owner of
+// an order about.
+type ExecutionType string
+
+const ExecutionNew ExecutionType = "NEW"
+const ExecutionTriggered ExecutionType = "TRIGGERED"
+const ExecutionFill ExecutionType = "FILL"
+const ExecutionAmended ExecutionType = "AMENDED"
+const ExecutionCanceled ExecutionType = "CANCELED"
+const ExecutionExpired ExecutionType = "EXPIRED"
+const ExecutionRejected ExecutionType = "REJECTED" // the order, or a cancel or amendment of it, was refused
+
+// ExecutionReport tells an account about a change to one of its orders. The
+// reports of an account are numbered in the order they happened; the numbers
+// are part of the snapshots, so they carry on after a restart.
+type ExecutionReport struct {
+	Sequence uint64 `json:"sequence"`
+	Type ExecutionType `json:"type"`
+	AccountID string `json:"account_id"`
+	Symbol string `json:"symbol"`
+	Timestamp time.Time `json:"timestamp"`
+	OrderID string `json:"order_uuid"`
+	// The order as the report left it; for a FILL, as the command left it.
+	Order *models.Order `json:"order,omitempty"`
+	Trade *models.Trade `json:"trade,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Executions turns the events of the order books into execution reports for
+// the accounts that own the orders, keeps the latest of them and streams them
+// to subscribers. Orders without an account get no reports. It never waits
+// for a subscriber, so one that falls behind is dropped and has to resume. It
+// is safe for concurrent use.
+type Executions struct {
+	mutex sync.Mutex
+	accounts map[string]*accountExecutions
+}
+
+type accountExecutions struct {
+	sequence uint64 // sequence of the last report
+	reports []ExecutionReport // the latest reports, oldest first
+	subscribers map[*ExecutionSubscription]struct{}
+}
+
+// ExecutionSubscription receives the execution reports of an account. Reports
+// is closed when the subscription ends, by Unsubscribe or because it fell
+// behind.
+type ExecutionSubscription struct {
+	Reports <-chan ExecutionReport
+	reports chan ExecutionReport
+	accountID string
+}
+
+func NewExecutions() *Executions {
+	return &Executions{
+		accounts: make(map[string]*accountExecutions),
+	}
+}
+
+func (e *Executions) account(accountID string) *accountExecutions {
+	account, exists := e.accounts[accountID]
+	if !exists {
+		account = &accountExecutions{subscribers: make(map[*ExecutionSubscription]struct{})}
+		e.accounts[accountID] = account
+	}
+
+	return account
+}
+
+// Subscribe returns a subscription to the reports of an account from now on.
+func (e *Executions) Subscribe(accountID string, bufferSize int) *ExecutionSubscription {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.subscribe(e.account(accountID), accountID, bufferSize)
+}
+
+// Resume returns the reports of an account after the given sequence number,
+// and a subscription to every report after those. It fails with
+// ErrExecutionsGone when some of the reports are no longer kept, or the
+// sequence number is one the account never reached.
+func (e *Executions) Resume(accountID string, after uint64, bufferSize int) ([]ExecutionReport, *ExecutionSubscription, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	account := e.account(accountID)
+	if after > account.sequence || account.sequence-after > uint64(len(account.reports)) {
+		return nil, nil, ErrExecutionsGone
+	}
+	missed := account.reports[uint64(len(account.reports))-(account.sequence-after):]
+
+	return append([]ExecutionReport{}, missed...), e.subscribe(account, accountID, bufferSize), nil
+}
+
+func (e *Executions) subscribe(account *accountExecutions, accountID string, bufferSize int) *ExecutionSubscription {
+	reports := make(chan ExecutionReport, bufferSize)
+	subscription := &ExecutionSubscription{Reports: reports, reports: reports, accountID: accountID}
+	account.subscribers[subscription] = struct{}{}
+
+	return subscription
+}
+
+// Unsubscribe ends a subscription. Ending one that was dropped does nothing.
+func (e *Executions) Unsubscribe(subscription *ExecutionSubscription) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.drop(e.accounts[subscription.accountID], subscription)
+}
+
+func (e *Executions) drop(account *accountExecutions, subscription *ExecutionSubscription) {
+	if _, exists := account.subscribers[subscription]; !exists {
+		return
+	}
+	delete(account.subscribers, subscription)
+	close(subscription.reports)
+}
+
+// record reports the events of a command to the accounts of the orders they
+// are about. orders holds every order of the market as the command left them.
+func (e *Executions) record(events []Event, orders *OrderHistory) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for _, event := range events {
+		report := ExecutionReport{Symbol: event.Symbol, Timestamp: event.Timestamp, OrderID: event.OrderID, Order: event.Order, Reason: event.Reason}
+
+		switch event.Type {
+		case EventOrderAccepted:
+			report.Type = ExecutionNew
+		case EventOrderTriggered:
+			report.Type = ExecutionTriggered
+		case EventOrderAmended:
+			report.Type = ExecutionAmended
+		case EventOrderCanceled:
+			report.Type = ExecutionCanceled
+		case EventOrderExpired:
+			report.Type = ExecutionExpired
+		case EventOrderRejected, EventCommandRejected:
+			report.Type = ExecutionRejected
+		case EventTrade:
+			report.Type = ExecutionFill
+			report.Trade = event.Trade
+			for _, id := range []string{event.Trade.MakerOrderID, event.Trade.TakerOrderID} {
+				order, _ := orders.GetOrder(id)
+				report.OrderID = id
+				report.Order = &order
+				e.report(report)
+			}
+			continue
+		default:
+			continue
+		}
+
+		if report.Order == nil {
+			// a refused cancel or amendment only names the order
+			order, exists := orders.GetOrder(event.OrderID)
+			if !exists {
+				continue
+			}
+			report.Order = &order
+		}
+		e.report(report)
+	}
+}
+
+// report numbers a report and hands it to the subscribers of its account.
+func (e *Executions) report(report ExecutionReport) {
+	if report.Order.AccountID == "" {
+		return
+	}
+
+	account := e.account(report.Order.AccountID)
+	account.sequence++
+	report.Sequence = account.sequence
+	report.AccountID = report.Order.AccountID
+
+	if len(account.reports) == executionsKept {
+		account.reports = append(account.reports[:0], account.reports[1:]...)
+	}
+	account.reports = append(account.reports, report)
+
+	for subscription := range account.subscribers {
+		select {
+		case subscription.reports <- report:
+		default:
+			e.drop(account, subscription)
+		}
+	}
+}
+
+// sequences returns the sequence of the last report of every account, for a
+// snapshot.
+func (e *Executions) sequences() map[string]uint64 {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	sequences := make(map[string]uint64, len(e.accounts))
+	for accountID, account := range e.accounts {
+		if account.sequence > 0 {
+			sequences[accountID] = account.sequence
+		}
+	}
+
+	return sequences
+}
+
+// restore carries on numbering the reports of every account from a snapshot.
+// The reports themselves are not part of it, so a client cannot resume from
+// before the snapshot.
+func (e *Executions) restore(sequences map[string]uint64) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for accountID, sequence := range sequences {
+		e.account(accountID).sequence = sequence
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"order-matching/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecutions(t *testing.T) {
+	t.Parallel()
+
+	newMarkets := func() (*BookManager, *Market) {
+		bm := NewBookManager(0)
+		bm.CreateInstrument(models.Instrument{Symbol: "BTC-USD", BaseAsset: "BTC", QuoteAsset: "USD", TickSize: decimal("0.01"), LotSize: decimal("0.01")})
+		for _, accountID := range []string{"maker", "taker"} {
+			bm.Deposit(models.Transfer{AccountID: accountID, Asset: "BTC", Amount: decimal("10.0")})
+			bm.Deposit(models.Transfer{AccountID: accountID, Asset: "USD", Amount: decimal("1000.0")})
+		}
+		market, _ := bm.Market("BTC-USD")
+		return bm, market
+	}
+
+	t.Run("It reports to the accounts of both sides of a trade", func(t *testing.T) {
+		t.Parallel()
+		bm, market := newMarkets()
+		maker := bm.Executions().Subscribe("maker", 10)
+		taker := bm.Executions().Subscribe("taker", 10)
+
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Symbol: "BTC-USD", AccountID: "maker", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("2.0")})
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Symbol: "BTC-USD", AccountID: "taker", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0")})
+
+		assert.Equal(t, ExecutionNew, (<-maker.Reports).Type)
+		fill := <-maker.Reports
+		assert.Equal(t, ExecutionFill, fill.Type)
+		assert.Equal(t, uint64(2), fill.Sequence)
+		assert.Equal(t, "maker", fill.AccountID)
+		assert.Equal(t, "550e8400-e29b-41d4-a716-446655440000", fill.OrderID)
+		assert.Equal(t, models.StatusPartiallyFilled, fill.Order.Status)
+		assert.Equal(t, decimal("1.0"), fill.Trade.Amount)
+
+		assert.Equal(t, ExecutionNew, (<-taker.Reports).Type)
+		fill = <-taker.Reports
+		assert.Equal(t, ExecutionFill, fill.Type)
+		assert.Equal(t, "550e8400-e29b-41d4-a716-446655440001", fill.OrderID)
+		assert.Equal(t, models.StatusFilled, fill.Order.Status)
+	})
+
+	t.Run("It reports cancels and refused commands", func(t *testing.T) {
+		t.Parallel()
+		bm, market := newMarkets()
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Symbol: "BTC-USD", AccountID: "maker", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("2.0")})
+		reports := bm.Executions().Subscribe("maker", 10)
+
+		bm.CancelOrder(market, "550e8400-e29b-41d4-a716-446655440000")
+		bm.CancelOrder(market, "550e8400-e29b-41d4-a716-446655440000")
+
+		canceled := <-reports.Reports
+		assert.Equal(t, ExecutionCanceled, canceled.Type)
+		assert.Equal(t, ReasonCanceled, canceled.Reason)
+		rejected := <-reports.Reports
+		assert.Equal(t, ExecutionRejected, rejected.Type)
+		assert.Equal(t, "550e8400-e29b-41d4-a716-446655440000", rejected.OrderID)
+	})
+
+	t.Run("It does not report orders without an account", func(t *testing.T) {
+		t.Parallel()
+		bm, market := newMarkets()
+
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Symbol: "BTC-USD", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("2.0")})
+
+		assert.Empty(t, bm.Executions().sequences())
+	})
+
+	t.Run("It resumes after the last report a client saw", func(t *testing.T) {
+		t.Parallel()
+		bm, market := newMarkets()
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Symbol: "BTC-USD", AccountID: "maker", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("2.0")})
+		bm.CancelOrder(market, "550e8400-e29b-41d4-a716-446655440000")
+
+		missed, subscription, err := bm.Executions().Resume("maker", 1, 10)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(missed))
+		assert.Equal(t, uint64(2), missed[0].Sequence)
+		assert.Equal(t, ExecutionCanceled, missed[0].Type)
+
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Symbol: "BTC-USD", AccountID: "maker", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("2.0")})
+		assert.Equal(t, uint64(3), (<-subscription.Reports).Sequence)
+
+		_, _, err = bm.Executions().Resume("maker", 4, 10)
+		assert.ErrorIs(t, err, ErrExecutionsGone)
+	})
+
+	t.Run("It carries on numbering after a snapshot is restored", func(t *testing.T) {
+		t.Parallel()
+		bm, market := newMarkets()
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Symbol: "BTC-USD", AccountID: "maker", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("2.0")})
+
+		restored := NewBookManager(0)
+		assert.NoError(t, restored.Restore(bm.Snapshot()))
+		market, _ = restored.Market("BTC-USD")
+		restored.CancelOrder(market, "550e8400-e29b-41d4-a716-446655440000")
+
+		missed, _, err := restored.Executions().Resume("maker", 1, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(2), missed[0].Sequence)
+		_, _, err = restored.Executions().Resume("maker", 0, 10)
+		assert.ErrorIs(t, err, ErrExecutionsGone)
+	})
+}
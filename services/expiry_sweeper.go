@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"order-matching/models"
+	"time"
+)
+
+// ExpirySweeper periodically removes GTD and DAY orders whose time has come
+// from the order books of every market, and reopens the markets whose halt or
+// auction is over.
+type ExpirySweeper struct {
+	sequencer *Sequencer // applies the expiry like any other command
+	interval  time.Duration
+}
+
+func NewExpirySweeper(sequencer *Sequencer, interval time.Duration) *ExpirySweeper {
+	return &ExpirySweeper{
+		sequencer: sequencer,
+		interval:  interval,
+	}
+}
+
+// Run sweeps the order books every interval until ctx is done.
+func (es *ExpirySweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(es.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := es.Sweep(now.UTC()); err != nil {
+				fmt.Println(err.Error())
+			}
+		}
+	}
+}
+
+// Sweep reopens the markets due at now, then expires every resting order due
+// at now and returns them. A busy sequencer leaves both for the next sweep.
+func (es *ExpirySweeper) Sweep(now time.Time) ([]models.Order, error) {
+	if err := es.sequencer.ReopenMarkets(now); err != nil {
+		return nil, err
+	}
+
+	return es.sequencer.ExpireOrders(now)
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+	"order-matching/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpirySweeper(t *testing.T) {
+	t.Parallel()
+	markets := NewBookManager(0)
+	markets.CreateInstrument(models.Instrument{Symbol: "BTC-USD", BaseAsset: "BTC", QuoteAsset: "USD", TickSize: decimal("0.01"), LotSize: decimal("0.01")})
+	market, _ := markets.Market("BTC-USD")
+	markets.PlaceOrder(market, &models.Order{
+		ID:          "550e8400-e29b-41d4-a716-446655440000",
+		Action:      models.Buy,
+		Price:       decimal("99.0"),
+		Amount:      decimal("2.0"),
+		TimeInForce: models.GoodTillDate,
+		ExpireAt:    time.Now().UTC().Add(50 * time.Millisecond),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go NewExpirySweeper(startSequencer(t, markets), 10*time.Millisecond).Run(ctx)
+
+	assert.Eventually(t, func() bool {
+		order, _ := market.OrderHistory.GetOrder("550e8400-e29b-41d4-a716-446655440000")
+		return order.Status == models.StatusExpired
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 0, len(market.GetOrderBook(10)))
+}
@@ -0,0 +1,490 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"order-matching/models"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrJournalCorrupt = errors.New("journal is corrupt")
+
+// ErrJournalFailed is returned by every append after the journal failed to
+// sync to disk, or to take back a record it only partly wrote.
+var ErrJournalFailed = errors.New("journal failed")
+
+// JournalCommand is the kind of state change a journal entry records.
+type JournalCommand string
+
+const CommandCreateInstrument JournalCommand = "CREATE_INSTRUMENT"
+const CommandPlaceOrder JournalCommand = "PLACE_ORDER"
+const CommandCancelOrder JournalCommand = "CANCEL_ORDER"
+const CommandAmendOrder JournalCommand = "AMEND_ORDER"
+const CommandExpireOrders JournalCommand = "EXPIRE_ORDERS"
+const CommandDeposit JournalCommand = "DEPOSIT"
+const CommandWithdraw JournalCommand = "WITHDRAW"
+const CommandSetMarketState JournalCommand = "SET_MARKET_STATE"
+
+// JournalEntry is one accepted command. Together with its timestamp it is all
+// the order books need to repeat the command exactly.
+type JournalEntry struct {
+	Sequence uint64 `json:"sequence"` // assigned by the journal, starting at 1
+	Command JournalCommand `json:"command"`
+	Timestamp time.Time `json:"timestamp"`
+	Symbol string `json:"symbol,omitempty"`
+	Instrument *models.Instrument `json:"instrument,omitempty"` // CREATE_INSTRUMENT
+	SessionClose *time.Duration `json:"session_close,omitempty"` // CREATE_INSTRUMENT, the service's setting at the time
+	Order *models.Order `json:"order,omitempty"` // PLACE_ORDER, as it was received
+	OrderID string `json:"order_id,omitempty"` // CANCEL_ORDER and AMEND_ORDER
+	Amendment *models.OrderAmendment `json:"amendment,omitempty"` // AMEND_ORDER
+	Transfer *models.Transfer `json:"transfer,omitempty"` // DEPOSIT and WITHDRAW
+	State *models.MarketState `json:"state,omitempty"` // SET_MARKET_STATE
+}
+
+// journalHeaderSize is the size of the header in front of every record: the
+// length of the encoded entry and its CRC-32C checksum, both big endian.
+const journalHeaderSize = 8
+
+// maxJournalRecord bounds the length of a single record, so a corrupt header
+// cannot make recovery allocate an absurd amount of memory.
+const maxJournalRecord = 1 << 20
+
+var journalChecksum = crc32.MakeTable(crc32.Castagnoli)
+
+// journalFile is the part of *os.File the journal appends through.
+type journalFile interface {
+	io.Writer
+	io.Seeker
+	Truncate(size int64) error
+	Sync() error
+	Close() error
+}
+
+// Journal is an append-only log of accepted commands, written before they are
+// applied. Each record is a JSON encoded entry behind a length and checksum
+// header. The log is split into segment files in one directory, each named
+// after the sequence number of its first entry, so that the part covered by
+// a snapshot can be deleted. It is safe for concurrent use.
+//
+// Once a sync fails, the kernel may have dropped the written data without
+// saying which, so the journal refuses every further append with
+// ErrJournalFailed until the service is restarted and reads back what
+// reached the disk.
+type Journal struct {
+	mutex sync.Mutex
+	dir string
+	file journalFile // the segment being appended to
+	size int64 // size of the records fully written to the current segment
+	segmentStart uint64 // sequence of the first entry of the current segment
+	sequence uint64 // sequence of the last entry written
+	syncInterval time.Duration // 0 syncs every append to disk before it returns
+	unsynced bool
+	failed error // why appends are refused, if they are
+}
+
+// OpenJournal opens the journal in dir, creating it if needed, and reads back
+// every entry in it. A torn final record, left behind by a crash in the
+// middle of an append, is cut off; damage anywhere else is reported as
+// ErrJournalCorrupt.
+func OpenJournal(dir string, syncInterval time.Duration) (*Journal, []JournalEntry, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, nil, err
+	}
+
+	starts, err := journalSegments(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	journal := &Journal{
+		dir: dir,
+		syncInterval: syncInterval,
+	}
+	if len(starts) == 0 {
+		return journal, []JournalEntry{}, journal.startSegment(1)
+	}
+
+	entries := []JournalEntry{}
+	for index, start := range starts {
+		last := index == len(starts)-1
+		file, err := os.OpenFile(journal.segmentPath(start), os.O_RDWR, 0o644)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		segment, size, torn, err := readJournal(file)
+		if err == nil && torn && !last {
+			err = fmt.Errorf("%w: segment %d is cut short", ErrJournalCorrupt, start)
+		}
+		if err == nil {
+			err = checkSequence(segment, start)
+		}
+		if err == nil && len(entries) > 0 && len(segment) > 0 && segment[0].Sequence != entries[len(entries)-1].Sequence+1 {
+			err = fmt.Errorf("%w: entries missing before segment %d", ErrJournalCorrupt, start)
+		}
+		if err == nil && last {
+			err = file.Truncate(size)
+		}
+		if err == nil && last {
+			_, err = file.Seek(size, io.SeekStart)
+		}
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+
+		entries = append(entries, segment...)
+		if !last {
+			file.Close()
+			continue
+		}
+
+		journal.file = file
+		journal.size = size
+		journal.segmentStart = start
+		journal.sequence = start - 1
+		if len(segment) > 0 {
+			journal.sequence = segment[len(segment)-1].Sequence
+		}
+	}
+
+	return journal, entries, nil
+}
+
+// journalSegments returns the first sequence number of every segment in dir,
+// in ascending order.
+func journalSegments(dir string) ([]uint64, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "journal-*.log"))
+	if err != nil {
+		return nil, err
+	}
+
+	starts := make([]uint64, 0, len(paths))
+	for _, path := range paths {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "journal-"), ".log")
+		start, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool {
+		return starts[i] < starts[j]
+	})
+
+	return starts, nil
+}
+
+// checkSequence makes sure the entries of a segment are numbered one after the
+// other from the segment's first sequence number.
+func checkSequence(entries []JournalEntry, start uint64) error {
+	for index, entry := range entries {
+		if entry.Sequence != start+uint64(index) {
+			return fmt.Errorf("%w: entry %d out of sequence in segment %d", ErrJournalCorrupt, entry.Sequence, start)
+		}
+	}
+
+	return nil
+}
+
+// ReadJournal reads back every entry of the journal in dir without opening it
+// for appending, so that a journal still in use can be inspected. A torn
+// final record is left out.
+func ReadJournal(dir string) ([]JournalEntry, error) {
+	starts, err := journalSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []JournalEntry{}
+	for index, start := range starts {
+		file, err := os.Open(journalSegmentPath(dir, start))
+		if err != nil {
+			return nil, err
+		}
+
+		segment, _, torn, err := readJournal(file)
+		file.Close()
+		if err == nil && torn && index < len(starts)-1 {
+			err = fmt.Errorf("%w: segment %d is cut short", ErrJournalCorrupt, start)
+		}
+		if err == nil {
+			err = checkSequence(segment, start)
+		}
+		if err == nil && len(entries) > 0 && len(segment) > 0 && segment[0].Sequence != entries[len(entries)-1].Sequence+1 {
+			err = fmt.Errorf("%w: entries missing before segment %d", ErrJournalCorrupt, start)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, segment...)
+	}
+
+	return entries, nil
+}
+
+func (j *Journal) segmentPath(start uint64) string {
+	return journalSegmentPath(j.dir, start)
+}
+
+func journalSegmentPath(dir string, start uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("journal-%020d.log", start))
+}
+
+// startSegment creates a new, empty segment whose first entry will have the
+// given sequence number and makes it the one appended to.
+func (j *Journal) startSegment(start uint64) error {
+	file, err := os.OpenFile(j.segmentPath(start), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := syncDir(j.dir); err != nil {
+		file.Close()
+		return err
+	}
+
+	j.file = file
+	j.size = 0
+	j.segmentStart = start
+	j.sequence = start - 1
+
+	return nil
+}
+
+// readJournal decodes records from the start of a segment and returns the
+// entries, the size of the intact part of the file and whether a torn record
+// follows it.
+func readJournal(file *os.File) ([]JournalEntry, int64, bool, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	reader := bufio.NewReader(file)
+	entries := []JournalEntry{}
+	var offset int64
+	header := make([]byte, journalHeaderSize)
+
+	for {
+		if _, err := io.ReadFull(reader, header); err == io.EOF {
+			return entries, offset, false, nil
+		} else if err == io.ErrUnexpectedEOF {
+			return entries, offset, true, nil // torn header
+		} else if err != nil {
+			return nil, 0, false, err
+		}
+
+		length := binary.BigEndian.Uint32(header[0:4])
+		checksum := binary.BigEndian.Uint32(header[4:8])
+		end := offset + journalHeaderSize + int64(length)
+		if end > info.Size() {
+			return entries, offset, true, nil // torn payload
+		}
+		if length > maxJournalRecord {
+			return nil, 0, false, fmt.Errorf("%w: record at offset %d is too long", ErrJournalCorrupt, offset)
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return nil, 0, false, err
+		}
+
+		var entry JournalEntry
+		if crc32.Checksum(payload, journalChecksum) != checksum || json.Unmarshal(payload, &entry) != nil {
+			if end == info.Size() {
+				return entries, offset, true, nil // the last record was only partly written
+			}
+			return nil, 0, false, fmt.Errorf("%w: bad record at offset %d", ErrJournalCorrupt, offset)
+		}
+
+		entries = append(entries, entry)
+		offset = end
+	}
+}
+
+// Append gives the entry the next sequence number and writes it to the
+// journal. Unless the journal syncs on an interval, the entry is on disk when
+// Append returns. A record that could only be partly written is cut off
+// again; a failed sync cuts off the record and fails the journal with
+// ErrJournalFailed.
+func (j *Journal) Append(entry JournalEntry) (JournalEntry, error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.failed != nil {
+		return JournalEntry{}, fmt.Errorf("%w: %v", ErrJournalFailed, j.failed)
+	}
+
+	entry.Sequence = j.sequence + 1
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return JournalEntry{}, err
+	}
+
+	record := make([]byte, journalHeaderSize, journalHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.Checksum(payload, journalChecksum))
+	record = append(record, payload...)
+
+	if _, err := j.file.Write(record); err != nil {
+		j.discard()
+		return JournalEntry{}, err
+	}
+
+	if j.syncInterval == 0 {
+		if err := j.sync(); err != nil {
+			// the command is not applied, so it must not be replayed either
+			j.discard()
+			return JournalEntry{}, err
+		}
+	} else {
+		j.unsynced = true
+	}
+	j.size += int64(len(record))
+	j.sequence = entry.Sequence
+
+	return entry, nil
+}
+
+// discard cuts whatever part of a record was written off the end of the
+// segment. If that fails as well, the journal fails, since a record appended
+// after the broken one could never be read back.
+func (j *Journal) discard() {
+	err := j.file.Truncate(j.size)
+	if err == nil {
+		_, err = j.file.Seek(j.size, io.SeekStart)
+	}
+	if err != nil {
+		j.failed = err
+	}
+}
+
+// sync flushes the current segment to disk and fails the journal if that
+// does not work.
+func (j *Journal) sync() error {
+	j.unsynced = false
+	if err := j.file.Sync(); err != nil {
+		j.failed = err
+		return fmt.Errorf("%w: %v", ErrJournalFailed, err)
+	}
+
+	return nil
+}
+
+// Sequence is the sequence number of the last entry written.
+func (j *Journal) Sequence() uint64 {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	return j.sequence
+}
+
+// Rotate closes the current segment and starts a new one for the entries
+// that follow, unless the current segment is still empty.
+func (j *Journal) Rotate() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.segmentStart == j.sequence+1 {
+		return nil
+	}
+
+	if err := j.sync(); err != nil {
+		return err
+	}
+	if err := j.file.Close(); err != nil {
+		return err
+	}
+
+	return j.startSegment(j.sequence + 1)
+}
+
+// Compact deletes every segment that holds only entries up to and including
+// sequence. The current segment is always kept.
+func (j *Journal) Compact(sequence uint64) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	starts, err := journalSegments(j.dir)
+	if err != nil {
+		return err
+	}
+
+	for index, start := range starts {
+		if start == j.segmentStart || index+1 == len(starts) || starts[index+1] > sequence+1 {
+			break
+		}
+		if err := os.Remove(j.segmentPath(start)); err != nil {
+			return err
+		}
+	}
+
+	return syncDir(j.dir)
+}
+
+// Sync flushes every entry appended so far to disk.
+func (j *Journal) Sync() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if !j.unsynced {
+		return nil
+	}
+
+	return j.sync()
+}
+
+// Run syncs the journal every sync interval until ctx is done. It returns
+// straight away for a journal that syncs every append.
+func (j *Journal) Run(ctx context.Context) {
+	if j.syncInterval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(j.syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := j.Sync(); err != nil {
+				fmt.Println(err.Error())
+			}
+		}
+	}
+}
+
+// Close syncs and closes the journal.
+func (j *Journal) Close() error {
+	if err := j.Sync(); err != nil {
+		return err
+	}
+
+	return j.file.Close()
+}
+
+// syncDir makes the creation, renaming or removal of files in dir durable.
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return file.Sync()
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"order-matching/models"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournal(t *testing.T) {
+	t.Parallel()
+
+	appendEntries := func(t *testing.T, dir string, count int) {
+		journal, _, err := OpenJournal(dir, 0)
+		assert.Nil(t, err)
+		for i := 0; i < count; i++ {
+			_, err := journal.Append(JournalEntry{Command: CommandCancelOrder, Symbol: "BTC-USD", OrderID: "550e8400-e29b-41d4-a716-446655440000"})
+			assert.Nil(t, err)
+		}
+		assert.Nil(t, journal.Close())
+	}
+
+	t.Run("It reads back every entry in sequence", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		appendEntries(t, dir, 3)
+
+		journal, entries, err := OpenJournal(dir, 0)
+		assert.Nil(t, err)
+		defer journal.Close()
+
+		assert.Equal(t, 3, len(entries))
+		assert.Equal(t, uint64(1), entries[0].Sequence)
+		assert.Equal(t, uint64(3), entries[2].Sequence)
+		assert.Equal(t, CommandCancelOrder, entries[2].Command)
+
+		entry, err := journal.Append(JournalEntry{Command: CommandExpireOrders, Symbol: "BTC-USD"})
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(4), entry.Sequence)
+	})
+
+	t.Run("It cuts off a torn final record", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		path := filepath.Join(dir, "journal-00000000000000000001.log")
+		appendEntries(t, dir, 2)
+		info, _ := os.Stat(path)
+		os.Truncate(path, info.Size()-5)
+
+		journal, entries, err := OpenJournal(dir, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(entries))
+
+		entry, _ := journal.Append(JournalEntry{Command: CommandExpireOrders, Symbol: "BTC-USD"})
+		journal.Close()
+		assert.Equal(t, uint64(2), entry.Sequence, "the torn entry is not counted")
+
+		_, entries, err = OpenJournal(dir, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(entries))
+		assert.Equal(t, CommandExpireOrders, entries[1].Command)
+	})
+
+	t.Run("It cuts off a final record that fails its checksum", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		path := filepath.Join(dir, "journal-00000000000000000001.log")
+		appendEntries(t, dir, 2)
+		data, _ := os.ReadFile(path)
+		data[len(data)-2] ^= 0xff
+		os.WriteFile(path, data, 0o644)
+
+		_, entries, err := OpenJournal(dir, 0)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(entries))
+	})
+
+	t.Run("It refuses a journal damaged before its end", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		path := filepath.Join(dir, "journal-00000000000000000001.log")
+		appendEntries(t, dir, 2)
+		data, _ := os.ReadFile(path)
+		data[journalHeaderSize+2] ^= 0xff
+		os.WriteFile(path, data, 0o644)
+
+		_, _, err := OpenJournal(dir, 0)
+
+		assert.ErrorIs(t, err, ErrJournalCorrupt)
+	})
+
+	t.Run("It carries on in a new segment and compacts the old ones", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		appendEntries(t, dir, 2)
+
+		journal, _, _ := OpenJournal(dir, 0)
+		assert.Nil(t, journal.Rotate())
+		journal.Append(JournalEntry{Command: CommandExpireOrders, Symbol: "BTC-USD"})
+		assert.Nil(t, journal.Rotate())
+		assert.Nil(t, journal.Rotate(), "an empty segment is not rotated")
+		journal.Close()
+
+		starts, _ := journalSegments(dir)
+		assert.Equal(t, []uint64{1, 3, 4}, starts)
+
+		journal, entries, err := OpenJournal(dir, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(entries))
+
+		assert.Nil(t, journal.Compact(2))
+		starts, _ = journalSegments(dir)
+		assert.Equal(t, []uint64{3, 4}, starts, "segment 3 holds entry 3, which is not covered")
+
+		assert.Nil(t, journal.Compact(3))
+		starts, _ = journalSegments(dir)
+		assert.Equal(t, []uint64{4}, starts)
+		journal.Close()
+
+		journal, entries, err = OpenJournal(dir, 0)
+		assert.Nil(t, err)
+		defer journal.Close()
+		assert.Equal(t, 0, len(entries))
+		assert.Equal(t, uint64(3), journal.Sequence(), "the sequence carries on after compaction")
+	})
+
+	t.Run("It cuts off a record it could only partly write", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		appendEntries(t, dir, 1)
+
+		journal, _, _ := OpenJournal(dir, 0)
+		file := &faultyFile{journalFile: journal.file, writeErr: errors.New("no space left on device")}
+		journal.file = file
+		_, err := journal.Append(JournalEntry{Command: CommandExpireOrders, Symbol: "BTC-USD"})
+		assert.NotNil(t, err)
+
+		file.writeErr = nil
+		entry, err := journal.Append(JournalEntry{Command: CommandExpireOrders, Symbol: "BTC-USD"})
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(2), entry.Sequence)
+		journal.Close()
+
+		_, entries, err := OpenJournal(dir, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(entries))
+	})
+
+	t.Run("It cuts off the record and refuses every append once a sync failed", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+
+		journal, _, _ := OpenJournal(dir, 0)
+		journal.Append(JournalEntry{Command: CommandExpireOrders, Symbol: "BTC-USD"})
+		file := &faultyFile{journalFile: journal.file, syncErr: errors.New("input/output error")}
+		journal.file = file
+		_, err := journal.Append(JournalEntry{Command: CommandExpireOrders, Symbol: "BTC-USD"})
+		assert.ErrorIs(t, err, ErrJournalFailed)
+		assert.Equal(t, uint64(1), journal.Sequence())
+
+		file.syncErr = nil
+		_, err = journal.Append(JournalEntry{Command: CommandExpireOrders, Symbol: "BTC-USD"})
+		assert.ErrorIs(t, err, ErrJournalFailed)
+		journal.Close()
+
+		_, entries, err := OpenJournal(dir, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(entries), "the command of the failed append was never applied")
+	})
+}
+
+// faultyFile writes only the first half of a record and fails, or fails to
+// sync, while its errors are set.
+type faultyFile struct {
+	journalFile
+	writeErr error
+	syncErr error
+}
+
+func (f *faultyFile) Write(data []byte) (int, error) {
+	if f.writeErr == nil {
+		return f.journalFile.Write(data)
+	}
+
+	written, _ := f.journalFile.Write(data[:len(data)/2])
+	return written, f.writeErr
+}
+
+func (f *faultyFile) Sync() error {
+	if f.syncErr != nil {
+		return f.syncErr
+	}
+
+	return f.journalFile.Sync()
+}
+
+func TestBookManagerRecover(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	journal, entries, err := OpenJournal(dir, 0)
+	assert.Nil(t, err)
+
+	bm := NewBookManager(0)
+	assert.Nil(t, bm.Recover(journal, entries))
+	bm.CreateInstrument(models.Instrument{Symbol: "BTC-USD", BaseAsset: "BTC", QuoteAsset: "USD", TickSize: decimal("0.01"), LotSize: decimal("0.01")})
+	market, _ := bm.Market("BTC-USD")
+
+	bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("3.0")})
+	bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Action: models.Sell, Price: decimal("101.0"), Amount: decimal("2.0"), DisplayAmount: decimal("1.0")})
+	bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440002", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0")})
+	bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440003", Action: models.Buy, Price: decimal("99.0"), Amount: decimal("4.0"), TimeInForce: models.GoodTillDate, ExpireAt: time.Now().UTC().Add(time.Minute)})
+	bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440004", Action: models.Sell, Kind: models.Stop, StopPrice: decimal("95.0"), Amount: decimal("1.0")})
+	amount := decimal("1.0")
+	bm.AmendOrder(market, "550e8400-e29b-41d4-a716-446655440000", models.OrderAmendment{Amount: &amount})
+	bm.CancelOrder(market, "550e8400-e29b-41d4-a716-446655440003")
+	journal.Close()
+
+	journal, entries, err = OpenJournal(dir, 0)
+	assert.Nil(t, err)
+	defer journal.Close()
+	assert.Equal(t, 8, len(entries))
+
+	recovered := NewBookManager(0)
+	assert.Nil(t, recovered.Recover(journal, entries))
+	recoveredMarket, err := recovered.Market("BTC-USD")
+	assert.Nil(t, err)
+
+	assert.Equal(t, market.Instrument, recoveredMarket.Instrument)
+	assert.Equal(t, market.OrderBook.GetOrderList(1, 10), recoveredMarket.OrderBook.GetOrderList(1, 10))
+	assert.Equal(t, market.OrderBook.GetOrderBook(10), recoveredMarket.OrderBook.GetOrderBook(10))
+	assert.Equal(t, market.TradeHistory.GetTradeList(time.Time{}, time.Time{}, 1, 10), recoveredMarket.TradeHistory.GetTradeList(time.Time{}, time.Time{}, 1, 10))
+	assert.Equal(t, market.OrderBook.sequence, recoveredMarket.OrderBook.sequence)
+	assert.Equal(t, market.OrderBook.lastTradeID, recoveredMarket.OrderBook.lastTradeID)
+
+	_, err = recovered.PlaceOrder(recoveredMarket, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440002", Action: models.Buy, Price: decimal("90.0"), Amount: decimal("1.0")})
+	assert.ErrorIs(t, err, ErrDuplicateOrder, "the dedupe set is rebuilt")
+
+	order := &models.Order{ID: "550e8400-e29b-41d4-a716-446655440005", Action: models.Buy, Price: decimal("90.0"), Amount: decimal("1.0")}
+	recovered.PlaceOrder(recoveredMarket, order)
+	assert.Equal(t, market.OrderBook.sequence+1, order.Sequence)
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"order-matching/models"
+	"sync"
+	"sync/atomic"
+)
+
+type FeedUpdateType string
+
+const (
+	FeedSnapshot FeedUpdateType = "SNAPSHOT"
+	FeedLevel FeedUpdateType = "LEVEL"
+	FeedTrade FeedUpdateType = "TRADE"
+)
+
+// FeedUpdate is one message of the market data feed of a market. A
+// subscription starts with a SNAPSHOT of the whole depth, followed by the
+// TRADEs executed and the LEVELs changed since, each with the next sequence
+// number. A LEVEL carries the new aggregate amount of a price level, zero once
+// the level is gone.
+type FeedUpdate struct {
+	Sequence uint64 `json:"sequence"`
+	Type FeedUpdateType `json:"type"`
+	Symbol string `json:"symbol"`
+	Depth *BookDepth `json:"depth,omitempty"`
+	Level *models.OrderBookEntry `json:"level,omitempty"`
+	Trade *models.Trade `json:"trade,omitempty"`
+}
+
+// MarketFeed publishes the depth of the order book of a market and streams the
+// changes to it to its subscribers. The sequencer publishes the trades and the
+// new depth after every batch of commands that changed the book; it never waits
+// for a subscriber, so one that falls behind is dropped and has to subscribe
+// again. It is safe for concurrent use.
+type MarketFeed struct {
+	mutex sync.Mutex
+	symbol string
+	depth atomic.Pointer[BookDepth]
+	sequence uint64 // sequence of the last update published
+	subscribers map[*FeedSubscription]struct{}
+}
+
+// FeedSubscription receives the updates of a market feed. Updates is closed
+// when the subscription ends, by Unsubscribe or because it fell behind.
+type FeedSubscription struct {
+	Updates <-chan FeedUpdate
+	updates chan FeedUpdate
+}
+
+func NewMarketFeed(symbol string, depth *BookDepth) *MarketFeed {
+	feed := &MarketFeed{
+		symbol: symbol,
+		subscribers: make(map[*FeedSubscription]struct{}),
+	}
+	feed.depth.Store(depth)
+
+	return feed
+}
+
+// Depth returns the last published depth.
+func (f *MarketFeed) Depth() *BookDepth {
+	return f.depth.Load()
+}
+
+// Subscribe returns a snapshot of the last published depth and a subscription
+// to every update after it. Up to bufferSize updates are held for the
+// subscriber before it is dropped.
+func (f *MarketFeed) Subscribe(bufferSize int) (FeedUpdate, *FeedSubscription) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	updates := make(chan FeedUpdate, bufferSize)
+	subscription := &FeedSubscription{Updates: updates, updates: updates}
+	f.subscribers[subscription] = struct{}{}
+
+	return FeedUpdate{Sequence: f.sequence, Type: FeedSnapshot, Symbol: f.symbol, Depth: f.depth.Load()}, subscription
+}
+
+// Unsubscribe ends a subscription. Ending one that was dropped does nothing.
+func (f *MarketFeed) Unsubscribe(subscription *FeedSubscription) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.drop(subscription)
+}
+
+func (f *MarketFeed) drop(subscription *FeedSubscription) {
+	if _, exists := f.subscribers[subscription]; !exists {
+		return
+	}
+	delete(f.subscribers, subscription)
+	close(subscription.updates)
+}
+
+// publish stores the new depth and sends the trades and the levels that
+// changed since the last depth to every subscriber. Only the owner of the
+// order book calls it.
+func (f *MarketFeed) publish(depth *BookDepth, trades []models.Trade) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	previous := f.depth.Swap(depth)
+	if len(f.subscribers) == 0 {
+		return
+	}
+
+	updates := make([]FeedUpdate, 0, len(trades))
+	for _, trade := range trades {
+		updates = append(updates, FeedUpdate{Type: FeedTrade, Trade: &trade})
+	}
+	for _, level := range changedLevels(previous.Asks, depth.Asks, models.Sell) {
+		updates = append(updates, FeedUpdate{Type: FeedLevel, Level: &level})
+	}
+	for _, level := range changedLevels(previous.Bids, depth.Bids, models.Buy) {
+		updates = append(updates, FeedUpdate{Type: FeedLevel, Level: &level})
+	}
+
+	for index := range updates {
+		f.sequence++
+		updates[index].Sequence = f.sequence
+		updates[index].Symbol = f.symbol
+	}
+
+	for subscription := range f.subscribers {
+		f.send(subscription, updates)
+	}
+}
+
+func (f *MarketFeed) send(subscription *FeedSubscription, updates []FeedUpdate) {
+	for _, update := range updates {
+		select {
+		case subscription.updates <- update:
+		default:
+			f.drop(subscription)
+			return
+		}
+	}
+}
+
+// changedLevels compares two copies of one side of a book, both best price
+// first, and returns every level whose amount changed, with an amount of zero
+// for those that are gone.
+func changedLevels(previous []models.OrderBookEntry, current []models.OrderBookEntry, side models.OrderType) []models.OrderBookEntry {
+	amounts := make(map[models.Decimal]models.Decimal, len(previous))
+	for _, level := range previous {
+		amounts[level.Price] = level.Liquidity
+	}
+
+	var changed []models.OrderBookEntry
+	for _, level := range current {
+		if amount, exists := amounts[level.Price]; !exists || amount != level.Liquidity {
+			changed = append(changed, level)
+		}
+		delete(amounts, level.Price)
+	}
+	for _, level := range previous {
+		if _, gone := amounts[level.Price]; gone {
+			changed = append(changed, models.OrderBookEntry{Price: level.Price, Liquidity: 0, Type: side})
+		}
+	}
+
+	return changed
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"order-matching/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarketFeed(t *testing.T) {
+	t.Parallel()
+
+	newSequencer := func(t *testing.T) (*Sequencer, *Market) {
+		markets := NewBookManager(0)
+		markets.CreateInstrument(models.Instrument{Symbol: "BTC-USD", BaseAsset: "BTC", QuoteAsset: "USD", TickSize: decimal("0.01"), LotSize: decimal("0.01")})
+		market, _ := markets.Market("BTC-USD")
+		sequencer := startSequencer(t, markets)
+		sequencer.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Symbol: "BTC-USD", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("2.0")})
+		sequencer.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Symbol: "BTC-USD", Action: models.Buy, Price: decimal("99.0"), Amount: decimal("1.0")})
+		return sequencer, market
+	}
+
+	t.Run("It starts with a snapshot of the depth", func(t *testing.T) {
+		t.Parallel()
+		_, market := newSequencer(t)
+
+		snapshot, _ := market.Feed.Subscribe(10)
+
+		assert.Equal(t, FeedSnapshot, snapshot.Type)
+		assert.Equal(t, "BTC-USD", snapshot.Symbol)
+		assert.Equal(t, []models.OrderBookEntry{{Price: decimal("100.0"), Liquidity: decimal("2.0"), Type: models.Sell}}, snapshot.Depth.Asks)
+		assert.Equal(t, []models.OrderBookEntry{{Price: decimal("99.0"), Liquidity: decimal("1.0"), Type: models.Buy}}, snapshot.Depth.Bids)
+	})
+
+	t.Run("It streams trades and changed levels with consecutive sequence numbers", func(t *testing.T) {
+		t.Parallel()
+		sequencer, market := newSequencer(t)
+		snapshot, subscription := market.Feed.Subscribe(10)
+
+		sequencer.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440002", Symbol: "BTC-USD", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0")})
+		sequencer.CancelOrder(market, "550e8400-e29b-41d4-a716-446655440001")
+
+		updates := []FeedUpdate{<-subscription.Updates, <-subscription.Updates, <-subscription.Updates}
+		for index, update := range updates {
+			assert.Equal(t, snapshot.Sequence+uint64(index)+1, update.Sequence)
+			assert.Equal(t, "BTC-USD", update.Symbol)
+		}
+		assert.Equal(t, FeedTrade, updates[0].Type)
+		assert.Equal(t, decimal("1.0"), updates[0].Trade.Amount)
+		assert.Equal(t, &models.OrderBookEntry{Price: decimal("100.0"), Liquidity: decimal("1.0"), Type: models.Sell}, updates[1].Level)
+		assert.Equal(t, &models.OrderBookEntry{Price: decimal("99.0"), Liquidity: 0, Type: models.Buy}, updates[2].Level)
+	})
+
+	t.Run("It drops a subscriber that falls behind", func(t *testing.T) {
+		t.Parallel()
+		sequencer, market := newSequencer(t)
+		_, subscription := market.Feed.Subscribe(1)
+
+		sequencer.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440002", Symbol: "BTC-USD", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0")})
+
+		<-subscription.Updates
+		_, open := <-subscription.Updates
+		assert.False(t, open)
+		market.Feed.Unsubscribe(subscription)
+	})
+}
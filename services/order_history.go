@@ -0,0 +1,90 @@
+package services
+
+import (
+	"order-matching/models"
+	"sync"
+)
+
+// OrderHistory keeps the latest state of every order accepted by an order
+// book, in the order they were accepted, for readers outside the sequencer.
+// The sequencer records the orders changed by every command. It is safe for
+// concurrent use.
+type OrderHistory struct {
+	mutex sync.RWMutex
+	orders []models.Order
+	positions map[string]int // position in orders of every order by its ID
+	accounts map[string][]int // positions in orders of the orders of every account
+}
+
+func NewOrderHistory() *OrderHistory {
+	return &OrderHistory{
+		positions: make(map[string]int),
+		accounts: make(map[string][]int),
+	}
+}
+
+// Record stores the current state of orders, adding those seen for the first
+// time at the end.
+func (oh *OrderHistory) Record(orders ...models.Order) {
+	oh.mutex.Lock()
+	defer oh.mutex.Unlock()
+
+	for _, order := range orders {
+		if position, exists := oh.positions[order.ID]; exists {
+			oh.orders[position] = order
+			continue
+		}
+		oh.positions[order.ID] = len(oh.orders)
+		oh.accounts[order.AccountID] = append(oh.accounts[order.AccountID], len(oh.orders))
+		oh.orders = append(oh.orders, order)
+	}
+}
+
+// GetOrder looks up any order ever accepted by its ID.
+func (oh *OrderHistory) GetOrder(id string) (models.Order, bool) {
+	oh.mutex.RLock()
+	defer oh.mutex.RUnlock()
+
+	position, exists := oh.positions[id]
+	if !exists {
+		return models.Order{}, false
+	}
+
+	return oh.orders[position], true
+}
+
+// GetOrderList returns a page of every order ever accepted, open or not, in
+// the order they were accepted.
+func (oh *OrderHistory) GetOrderList(page int, pageSize int) []models.Order {
+	oh.mutex.RLock()
+	defer oh.mutex.RUnlock()
+
+	start := (page - 1) * pageSize
+	if start >= len(oh.orders) {
+		return []models.Order{}
+	}
+	end := min(start+pageSize, len(oh.orders))
+
+	return append([]models.Order{}, oh.orders[start:end]...)
+}
+
+// GetAccountOrderList returns a page of every order of an account ever
+// accepted, in the order they were accepted.
+func (oh *OrderHistory) GetAccountOrderList(accountID string, page int, pageSize int) []models.Order {
+	oh.mutex.RLock()
+	defer oh.mutex.RUnlock()
+
+	positions := oh.accounts[accountID]
+	start := (page - 1) * pageSize
+	if start >= len(positions) {
+		return []models.Order{}
+	}
+	end := min(start+pageSize, len(positions))
+
+	orders := make([]models.Order, 0, end-start)
+	for _, position := range positions[start:end] {
+		orders = append(orders, oh.orders[position])
+	}
+
+	return orders
+}
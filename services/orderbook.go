@@ -1,73 +1,548 @@
 package services
 
 import (
+	"cmp"
 	"container/heap"
+	"errors"
+	"maps"
 	"order-matching/models"
+	"slices"
+	"sort"
+	"time"
+)
+
+var (
+	ErrOrderNotFound = errors.New("order not found")
+	ErrOrderNotOpen = errors.New("order is no longer open")
+	ErrOrderPendingTrigger = errors.New("order is waiting for its stop price")
+	ErrOrderQueued = errors.New("order is waiting for the market to reopen")
 )
 
 type OrderBook struct {
-	BuyPricesHeap models.BuyHeap
-	SellPricesHeap models.SellHeap
-	BuyOrders map[float64][]models.Order
-	SellOrders map[float64][]models.Order
+	BuyLevels *models.PriceLevels // bids, highest price first
+	SellLevels *models.PriceLevels // asks, lowest price first
+
+	orders map[string]*models.Order // every order ever accepted, by its ID
+	acceptedOrders []*models.Order // every order ever accepted, oldest first
+	orderIndex map[string]*models.Order // every resting order by its ID
+	expiryHeap models.ExpiryHeap // resting GTD and DAY orders, next to expire on top
+	buyStops models.BuyStopHeap // the trigger book: pending buy stop orders
+	sellStops models.SellStopHeap // the trigger book: pending sell stop orders
+	stopIndex map[string]*models.Order // every pending stop order by its ID
+	halted bool // whether matching is stopped, see Halt
+	auctionPrice models.Decimal // the price every trade is made at while the queued orders are uncrossed, 0 otherwise
+	queued []*models.Order // orders placed while matching is stopped, oldest first
+	queueIndex map[string]*models.Order // every queued order by its ID
+	sequence uint64 // incremented for every order placed, canceled or amended
+	lastTradeID uint64
+	lastTradePrice models.Decimal
+	changed []*models.Order // orders changed by the last command, see Changes
+	events []Event // events of the last command, see Events
+	eventSequence uint64 // sequence of the last event emitted
+
+	SessionClose time.Duration // time of day, in UTC, at which DAY orders expire
+	TickSize models.Decimal // the smallest price increment, used to reprice post-only orders
 }
 
 func NewOrderBook() *OrderBook {
 	orderBook := &OrderBook{
-		BuyPricesHeap: models.BuyHeap{},
-		SellPricesHeap: models.SellHeap{},
-		BuyOrders: make(map[float64][]models.Order),
-		SellOrders: make(map[float64][]models.Order),
+		BuyLevels: models.NewBuyLevels(),
+		SellLevels: models.NewSellLevels(),
+		orders: make(map[string]*models.Order),
+		orderIndex: make(map[string]*models.Order),
+		stopIndex: make(map[string]*models.Order),
+		queueIndex: make(map[string]*models.Order),
+		TickSize: models.MustParseDecimal("0.01"),
 	}
 
-	heap.Init(&orderBook.BuyPricesHeap)
-	heap.Init(&orderBook.SellPricesHeap)
+	heap.Init(&orderBook.expiryHeap)
+	heap.Init(&orderBook.buyStops)
+	heap.Init(&orderBook.sellStops)
 
 	return orderBook
 }
 
-func (ob *OrderBook) PlaceOrder(order *models.Order) (matchedOrders []models.Order){
-	if order.Action == models.Buy {
-		matchedOrders = ob.handleBuyAction(order)
-	} else { // sell action
-		matchedOrders = ob.handleSellAction(order)
+// PlaceOrder accepts the order and matches it against the opposite side of
+// the book with price-time priority. What happens to the unfilled remainder
+// depends on the order's type and time in force: GTC, GTD and DAY limit
+// orders rest in the book, while MARKET and IOC orders have it canceled. A FOK
+// order is canceled without trading unless it can be filled completely.
+// STOP and STOP_LIMIT orders wait in the trigger book until the last trade
+// price reaches their stop price. The order's lifecycle fields are filled in,
+// and one trade is returned per resting order that was traded against,
+// including the trades of any stop orders the placement triggered.
+func (ob *OrderBook) PlaceOrder(order *models.Order) []models.Trade {
+	return ob.PlaceOrderAt(order, time.Now().UTC())
+}
+
+// PlaceOrderAt is PlaceOrder at a given time, used to replay the journal.
+func (ob *OrderBook) PlaceOrderAt(order *models.Order, timestamp time.Time) (trades []models.Trade) {
+	ob.begin()
+	ob.sequence++
+
+	order.Accept(timestamp)
+	order.Sequence = ob.sequence
+	if order.TimeInForce == models.Day {
+		order.ExpireAt = nextSessionClose(timestamp, ob.SessionClose)
+	}
+
+	accepted := *order // the book keeps its own copy of the order
+	ob.orders[accepted.ID] = &accepted
+	ob.acceptedOrders = append(ob.acceptedOrders, &accepted)
+	ob.changed = append(ob.changed, &accepted)
+	ob.emit(EventOrderAccepted, &accepted, timestamp, "")
+
+	if ob.halted {
+		ob.queued = append(ob.queued, &accepted)
+		ob.queueIndex[accepted.ID] = &accepted
+	} else {
+		trades = ob.enter(&accepted, timestamp)
+	}
+
+	// whatever is still open rests in the book, or waits in the trigger book
+	// or the queue
+	if accepted.IsOpen() && !accepted.ExpireAt.IsZero() {
+		heap.Push(&ob.expiryHeap, &accepted)
 	}
 
-	return matchedOrders
+	trades = append(trades, ob.triggerStops(timestamp)...)
+	*order = accepted
+
+	return trades
 }
 
-func (ob *OrderBook) GetOrderBook(limit int) []models.OrderBookEntry {
-	var sellOrders, buyOrders []models.OrderBookEntry
+// enter sends an accepted order to the trigger book if it is a stop order
+// whose stop price has not been reached, and through matching otherwise.
+func (ob *OrderBook) enter(order *models.Order, timestamp time.Time) []models.Trade {
+	if order.IsStop() && !ob.triggered(order) {
+		ob.addStop(order)
+		return nil
+	}
+
+	if order.IsStop() {
+		order.TriggeredAt = timestamp
+		ob.emit(EventOrderTriggered, order, timestamp, "")
+	}
+	return ob.executeOrder(order, timestamp)
+}
+
+// Halt stops matching: orders placed from now on are accepted but wait in a
+// queue, without trading, until the book is resumed. Resting and queued
+// orders can still be canceled and expire.
+func (ob *OrderBook) Halt() {
+	ob.halted = true
+}
+
+// Halted reports whether matching is stopped.
+func (ob *OrderBook) Halted() bool {
+	return ob.halted
+}
+
+// ResumeAt restarts matching at a given time and uncrosses the queued orders:
+// those that can rest trade with each other and the book at the single price
+// that fills the most, see uncross. The others, such as post-only and stop
+// orders, then go through matching in the order they were placed, as if they
+// arrived then. It returns the trades, and those of the stop orders they
+// trigger.
+func (ob *OrderBook) ResumeAt(timestamp time.Time) (trades []models.Trade) {
+	ob.begin()
+	ob.halted = false
+	if len(ob.queued) == 0 {
+		return nil
+	}
+
+	ob.sequence++
+	queued := ob.queued
+	ob.queued = nil
+	var auction, later []*models.Order
+	for _, order := range queued {
+		delete(ob.queueIndex, order.ID)
+		if !order.IsOpen() {
+			continue
+		}
+		ob.changed = append(ob.changed, order)
+		if order.Kind == models.Limit && order.Rests() && order.PostOnly == "" {
+			auction = append(auction, order)
+		} else {
+			later = append(later, order)
+		}
+	}
+
+	trades = ob.uncross(auction, timestamp)
+	trades = append(trades, ob.triggerStops(timestamp)...)
+	for _, order := range later {
+		trades = append(trades, ob.enter(order, timestamp)...)
+		trades = append(trades, ob.triggerStops(timestamp)...)
+	}
+
+	return trades
+}
 
-	startIndex := limit
-	if limit >= ob.SellPricesHeap.Len() {
-		startIndex = ob.SellPricesHeap.Len() - 1
+// uncross adds the orders to the book and trades every bid and ask that cross
+// the uncross price at that price, in price-time priority on both sides. The
+// bids are the takers: they are taken out of the book and matched against the
+// asks in turn, and what is left of them rests again where it was, once it no
+// longer crosses the asks.
+func (ob *OrderBook) uncross(orders []*models.Order, timestamp time.Time) (trades []models.Trade) {
+	price, found := ob.uncrossPrice(orders)
+
+	var bids []*models.Order
+	arriving := make(map[*models.Order]bool)
+	for _, order := range orders {
+		if found && order.Action == models.Buy && order.Price >= price {
+			bids = append(bids, order)
+			arriving[order] = true
+		} else {
+			ob.restOrder(order, timestamp)
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	// the resting bids come first at each price, the new ones behind them
+	var resting []*models.Order
+	for ob.BuyLevels.Len() > 0 && ob.BuyLevels.Best().Price >= price {
+		level := ob.BuyLevels.Best()
+		resting = append(resting, level.Orders()...)
+		ob.BuyLevels.Remove(level.Price)
+	}
+	for _, order := range resting {
+		delete(ob.orderIndex, order.ID)
+	}
+	bids = append(resting, bids...)
+	slices.SortStableFunc(bids, func(a *models.Order, b *models.Order) int {
+		return cmp.Compare(b.Price, a.Price)
+	})
+
+	ob.auctionPrice = price
+	for _, bid := range bids {
+		ob.changed = append(ob.changed, bid)
+		trades = append(trades, ob.handleBuyAction(bid, price, timestamp)...)
+	}
+	ob.auctionPrice = 0
+
+	// the uncross price counts orders that self-trade prevention then takes
+	// out, so a bid can be left crossing asks above it: those trade at the
+	// asks' prices, as in continuous matching
+	var requeued []*models.Order
+	for _, bid := range bids {
+		trades = append(trades, ob.handleBuyAction(bid, bid.Price, timestamp)...)
+		switch {
+		case !bid.IsOpen():
+		case arriving[bid]:
+			ob.restOrder(bid, timestamp)
+		case bid.VisibleAmount == 0:
+			// an iceberg bid whose peak is used up goes to the back
+			requeued = append(requeued, bid)
+		default:
+			ob.BuyLevels.GetOrCreate(bid.Price).Push(bid)
+			ob.orderIndex[bid.ID] = bid
+		}
+	}
+	for _, bid := range requeued {
+		bid.Replenish()
+		ob.BuyLevels.GetOrCreate(bid.Price).Push(bid)
+		ob.orderIndex[bid.ID] = bid
 	}
 
-	for i := startIndex; i >= 0; i-- {
-		price := ob.SellPricesHeap[i]
-		liquidity := 0.0
-		for _, order := range ob.SellOrders[price] {
-			liquidity += order.Amount
+	return trades
+}
+
+// uncrossPrice is the single price at which the orders, added to the book,
+// would trade the largest amount. Ties go to the price that leaves the
+// smallest surplus on either side, then to the higher price if bids are left
+// over and the lower if asks are, then to the one closest to the last trade
+// price, then to the lowest. It reports false if nothing would trade.
+func (ob *OrderBook) uncrossPrice(orders []*models.Order) (models.Decimal, bool) {
+	bids := make(map[models.Decimal]models.Decimal)
+	asks := make(map[models.Decimal]models.Decimal)
+	for level := range ob.BuyLevels.All() {
+		bids[level.Price] += level.Remaining()
+	}
+	for level := range ob.SellLevels.All() {
+		asks[level.Price] += level.Remaining()
+	}
+	for _, order := range orders {
+		if order.Action == models.Buy {
+			bids[order.Price] += order.RemainingAmount
+		} else {
+			asks[order.Price] += order.RemainingAmount
+		}
+	}
+
+	prices := slices.Concat(slices.Collect(maps.Keys(bids)), slices.Collect(maps.Keys(asks)))
+	slices.Sort(prices)
+	prices = slices.Compact(prices)
+
+	// the amount bid at each price or higher, and offered at each price or
+	// lower
+	demand := make([]models.Decimal, len(prices))
+	supply := make([]models.Decimal, len(prices))
+	var total models.Decimal
+	for i := len(prices) - 1; i >= 0; i-- {
+		total += bids[prices[i]]
+		demand[i] = total
+	}
+	total = 0
+	for i, price := range prices {
+		total += asks[price]
+		supply[i] = total
+	}
+
+	var best, volume, surplus models.Decimal
+	for i, price := range prices {
+		amount := min(demand[i], supply[i])
+		left := max(demand[i], supply[i]) - amount
+		switch {
+		case amount == 0 || amount < volume:
+			continue
+		case amount == volume && left > surplus:
+			continue
+		case amount == volume && left == surplus && supply[i] > demand[i]:
+			continue
+		case amount == volume && left == 0 && !ob.closerToLastTrade(price, best):
+			continue
+		}
+		best, volume, surplus = price, amount, left
+	}
+
+	return best, volume > 0
+}
+
+// closerToLastTrade reports whether price is strictly closer to the last trade
+// price than other. Without a trade yet no price is closer.
+func (ob *OrderBook) closerToLastTrade(price models.Decimal, other models.Decimal) bool {
+	if ob.lastTradeID == 0 {
+		return false
+	}
+
+	distance := func(p models.Decimal) models.Decimal {
+		return max(p-ob.lastTradePrice, ob.lastTradePrice-p)
+	}
+	return distance(price) < distance(other)
+}
+
+// unqueue takes a queued order out of the queue.
+func (ob *OrderBook) unqueue(order *models.Order) {
+	delete(ob.queueIndex, order.ID)
+	ob.queued = slices.DeleteFunc(ob.queued, func(queued *models.Order) bool {
+		return queued == order
+	})
+}
+
+// RejectOrderAt records a new order refused before it could trade, such as
+// by the risk checks, as REJECTED with the reason. The order never reaches the
+// book.
+func (ob *OrderBook) RejectOrderAt(order *models.Order, reason string, timestamp time.Time) {
+	ob.begin()
+	ob.sequence++
+
+	order.Accept(timestamp)
+	order.Sequence = ob.sequence
+	order.RejectReason = reason
+	order.Close(models.StatusRejected, timestamp)
+
+	rejected := *order // the book keeps its own copy of the order
+	ob.orders[rejected.ID] = &rejected
+	ob.acceptedOrders = append(ob.acceptedOrders, &rejected)
+	ob.changed = append(ob.changed, &rejected)
+	ob.emit(EventOrderRejected, &rejected, timestamp, reason)
+}
+
+// ExpireOrders removes every resting or pending stop order whose expiry time
+// is not after now and returns them with status EXPIRED.
+func (ob *OrderBook) ExpireOrders(now time.Time) []models.Order {
+	ob.begin()
+	expired := []models.Order{}
+
+	for ob.expiryHeap.Len() > 0 && !ob.expiryHeap[0].ExpireAt.After(now) {
+		order := heap.Pop(&ob.expiryHeap).(*models.Order)
+		if _, pending := ob.stopIndex[order.ID]; pending {
+			ob.removeStop(order)
+		} else if _, resting := ob.orderIndex[order.ID]; resting {
+			ob.removeOrder(order)
+		} else if _, queued := ob.queueIndex[order.ID]; queued {
+			ob.unqueue(order)
+		} else {
+			continue // filled or canceled in the meantime
+		}
+
+		order.Close(models.StatusExpired, now)
+		ob.changed = append(ob.changed, order)
+		ob.emit(EventOrderExpired, order, now, "")
+		expired = append(expired, *order)
+	}
+
+	if len(expired) > 0 {
+		ob.sequence++
+	}
+
+	return expired
+}
+
+// HasExpiredOrders reports whether ExpireOrders(now) may have anything to do.
+func (ob *OrderBook) HasExpiredOrders(now time.Time) bool {
+	return ob.expiryHeap.Len() > 0 && !ob.expiryHeap[0].ExpireAt.After(now)
+}
+
+// Changes returns every order changed by the last command applied to the
+// book, in the order they were first changed.
+func (ob *OrderBook) Changes() []models.Order {
+	seen := make(map[string]struct{}, len(ob.changed))
+	changes := make([]models.Order, 0, len(ob.changed))
+	for _, order := range ob.changed {
+		if _, exists := seen[order.ID]; !exists {
+			seen[order.ID] = struct{}{}
+			changes = append(changes, *order)
+		}
+	}
+
+	return changes
+}
+
+// Events returns the events of the last command applied to the book, in the
+// order they happened.
+func (ob *OrderBook) Events() []Event {
+	return slices.Clone(ob.events)
+}
+
+// begin clears what the previous command changed, before the next one is
+// applied.
+func (ob *OrderBook) begin() {
+	ob.changed = ob.changed[:0]
+	ob.events = ob.events[:0]
+}
+
+// GetOrder looks up any order ever accepted by its ID.
+func (ob *OrderBook) GetOrder(id string) (models.Order, bool) {
+	order, exists := ob.orders[id]
+	if !exists {
+		return models.Order{}, false
+	}
+
+	return *order, true
+}
+
+// CancelOrder removes a resting order from the book, a pending stop order
+// from the trigger book, or a queued order from the queue, and returns it.
+func (ob *OrderBook) CancelOrder(id string) (models.Order, error) {
+	return ob.CancelOrderAt(id, time.Now().UTC())
+}
+
+// CancelOrderAt is CancelOrder at a given time, used to replay the journal.
+func (ob *OrderBook) CancelOrderAt(id string, timestamp time.Time) (models.Order, error) {
+	ob.begin()
+
+	if order, queued := ob.queueIndex[id]; queued {
+		ob.sequence++
+		ob.unqueue(order)
+		order.Close(models.StatusCanceled, timestamp)
+		ob.changed = append(ob.changed, order)
+		ob.emit(EventOrderCanceled, order, timestamp, ReasonCanceled)
+
+		return *order, nil
+	}
+
+	if order, pending := ob.stopIndex[id]; pending {
+		ob.sequence++
+		ob.removeStop(order)
+		order.Close(models.StatusCanceled, timestamp)
+		ob.changed = append(ob.changed, order)
+		ob.emit(EventOrderCanceled, order, timestamp, ReasonCanceled)
+
+		return *order, nil
+	}
+
+	order, err := ob.restingOrder(id)
+	if err != nil {
+		ob.emitRejection(id, timestamp, err)
+		return models.Order{}, err
+	}
+
+	ob.sequence++
+	ob.removeOrder(order)
+	order.Close(models.StatusCanceled, timestamp)
+	ob.changed = append(ob.changed, order)
+	ob.emit(EventOrderCanceled, order, timestamp, ReasonCanceled)
+
+	return *order, nil
+}
+
+// AmendOrder changes the price and/or remaining amount of a resting order.
+// Reducing the amount at the same price is done in place and keeps the order's
+// queue priority. Changing the price or increasing the amount sends the order
+// to the back of the queue at its (new) price, where it may trade like a newly
+// placed order; the resulting trades, and those of any stop orders they
+// trigger, are returned. Pending stop orders cannot be amended.
+func (ob *OrderBook) AmendOrder(id string, amendment models.OrderAmendment) (models.Order, []models.Trade, error) {
+	return ob.AmendOrderAt(id, amendment, time.Now().UTC())
+}
+
+// AmendOrderAt is AmendOrder at a given time, used to replay the journal.
+func (ob *OrderBook) AmendOrderAt(id string, amendment models.OrderAmendment, timestamp time.Time) (models.Order, []models.Trade, error) {
+	ob.begin()
+
+	order, err := ob.restingOrder(id)
+	if err != nil {
+		ob.emitRejection(id, timestamp, err)
+		return models.Order{}, nil, err
+	}
+
+	ob.sequence++
+	ob.changed = append(ob.changed, order)
+
+	price, remaining := order.Price, order.RemainingAmount
+	if amendment.Price != nil {
+		price = *amendment.Price
+	}
+	if amendment.Amount != nil {
+		remaining = *amendment.Amount
+	}
+
+	if price == order.Price && remaining <= order.RemainingAmount {
+		ob.levelsFor(order.Action).Get(order.Price).Update(order, func() {
+			order.Resize(remaining, timestamp)
+		})
+		ob.emit(EventOrderAmended, order, timestamp, "")
+		return *order, []models.Trade{}, nil
+	}
+
+	ob.removeOrder(order)
+	order.Price = price
+	order.Resize(remaining, timestamp)
+	ob.emit(EventOrderAmended, order, timestamp, "")
+	trades := ob.matchOrder(order, timestamp)
+	trades = append(trades, ob.triggerStops(timestamp)...)
+
+	return *order, trades, nil
+}
+
+// GetOrderBook returns up to limit price levels of each side: the asks from
+// the highest shown price down to the best ask, then the bids from the best
+// bid down.
+func (ob *OrderBook) GetOrderBook(limit int) []models.OrderBookEntry {
+	var sellOrders, buyOrders []models.OrderBookEntry
+
+	for level := range ob.SellLevels.All() {
+		if len(sellOrders) == limit {
+			break
 		}
-		
 		sellOrders = append(sellOrders, models.OrderBookEntry{
-			Price: price,
-			Liquidity: liquidity,
+			Price: level.Price,
+			Liquidity: level.Liquidity(),
 			Type: models.Sell,
 		})
 	}
+	slices.Reverse(sellOrders)
 
-	for i := 0; i < ob.BuyPricesHeap.Len() && i < limit; i++ {
-		price := ob.BuyPricesHeap[i]
-		liquidity := 0.0
-		for _, order := range ob.BuyOrders[price] {
-			liquidity += order.Amount
+	for level := range ob.BuyLevels.All() {
+		if len(buyOrders) == limit {
+			break
 		}
-		
 		buyOrders = append(buyOrders, models.OrderBookEntry{
-			Price: price,
-			Liquidity: liquidity,
+			Price: level.Price,
+			Liquidity: level.Liquidity(),
 			Type: models.Buy,
 		})
 	}
@@ -75,103 +550,487 @@ func (ob *OrderBook) GetOrderBook(limit int) []models.OrderBookEntry {
 	return append(sellOrders, buyOrders...)
 }
 
+// GetOrderList returns a page of every order ever accepted, open or not, in
+// the order they were accepted.
 func (ob *OrderBook) GetOrderList(page int, pageSize int) []models.Order {
-	var allOrders []models.Order
-	for _, orders := range ob.BuyOrders {
-		allOrders = append(allOrders, orders...)
-	}
-	for _, orders := range ob.SellOrders {
-		allOrders = append(allOrders, orders...)
-	}
+	totalOrders := len(ob.acceptedOrders)
 
-	totalOrders := len(allOrders)
-	
 	start := (page - 1) * pageSize
+	if start >= totalOrders {
+		return []models.Order{}
+	}
+
 	end := start + pageSize
 	if end > totalOrders {
 		end = totalOrders
 	}
 
-	return allOrders[start:end]
+	orders := make([]models.Order, 0, end-start)
+	for _, order := range ob.acceptedOrders[start:end] {
+		orders = append(orders, *order)
+	}
+
+	return orders
 }
 
-func (ob *OrderBook) handleBuyAction(order *models.Order) (matchedOrders []models.Order) {
-	if ob.SellPricesHeap.Len() > 0 {
-		cheapestSell := ob.SellPricesHeap[0]
+// restingOrder looks up an order that can still be canceled or amended.
+func (ob *OrderBook) restingOrder(id string) (*models.Order, error) {
+	if order, exists := ob.orderIndex[id]; exists {
+		return order, nil
+	}
 
-		if order.Price >= cheapestSell {
-			//check the map
-			if sellOrders, exists := ob.SellOrders[order.Price]; exists {
-				matchedOrders = findMatchingOrdersByAmount(sellOrders, order)
+	if _, pending := ob.stopIndex[id]; pending {
+		return nil, ErrOrderPendingTrigger
+	}
 
-				for i, sellOrder := range sellOrders {
-					if sellOrder.Amount == order.Amount {
-						ob.SellOrders[sellOrder.Price] = append(sellOrders[:i], sellOrders[i+1:]...)
-						if len(ob.SellOrders[order.Price]) == 0 {
-							delete(ob.SellOrders, order.Price)
-							heap.Pop(&ob.SellPricesHeap)
-						}
-						break
-					}
-				}
-			}
-			return
-		} 
+	if _, queued := ob.queueIndex[id]; queued {
+		return nil, ErrOrderQueued
 	}
 
-	// no match found or the SellPricesHeap were empty
-	heap.Push(&ob.BuyPricesHeap, order.Price)
-	// insert the order in map
-	if orders, exists := ob.BuyOrders[order.Price]; exists {
-		ob.BuyOrders[order.Price] = append(orders, *order) // makes a copy of the order and puts it in the heap
+	if _, exists := ob.orders[id]; exists {
+		return nil, ErrOrderNotOpen
+	}
+
+	return nil, ErrOrderNotFound
+}
+
+// executeOrder sends an order, newly placed or just triggered, through
+// matching. A FOK order that cannot be filled completely is canceled without
+// trading.
+func (ob *OrderBook) executeOrder(order *models.Order, timestamp time.Time) []models.Trade {
+	if order.TimeInForce == models.FillOrKill && ob.crossingLiquidity(order) < order.RemainingAmount {
+		order.Close(models.StatusCanceled, timestamp)
+		ob.emit(EventOrderCanceled, order, timestamp, ReasonFillOrKill)
+		return nil
+	}
+
+	return ob.matchOrder(order, timestamp)
+}
+
+// triggered reports whether the last trade price has reached the stop price
+// of a stop order.
+func (ob *OrderBook) triggered(order *models.Order) bool {
+	return ob.lastTradeID > 0 && order.Triggers(ob.lastTradePrice)
+}
+
+// triggerStops takes every stop order the last trade price has reached out of
+// the trigger book and sends it through matching. Stop orders fired by the
+// same trade are injected in the order they were placed, and the trades they
+// produce may in turn fire further stop orders.
+func (ob *OrderBook) triggerStops(timestamp time.Time) (trades []models.Trade) {
+	for {
+		var fired []*models.Order
+		for ob.buyStops.Len() > 0 && ob.triggered(ob.buyStops[0]) {
+			fired = append(fired, heap.Pop(&ob.buyStops).(*models.Order))
+		}
+		for ob.sellStops.Len() > 0 && ob.triggered(ob.sellStops[0]) {
+			fired = append(fired, heap.Pop(&ob.sellStops).(*models.Order))
+		}
+
+		if len(fired) == 0 {
+			return trades
+		}
+
+		sort.Slice(fired, func(i, j int) bool {
+			return fired[i].Sequence < fired[j].Sequence
+		})
+
+		for _, order := range fired {
+			ob.changed = append(ob.changed, order)
+			delete(ob.stopIndex, order.ID)
+			order.TriggeredAt = timestamp
+			ob.emit(EventOrderTriggered, order, timestamp, "")
+			trades = append(trades, ob.executeOrder(order, timestamp)...)
+		}
+	}
+}
+
+// addStop puts a stop order in the trigger book.
+func (ob *OrderBook) addStop(order *models.Order) {
+	if order.Action == models.Buy {
+		heap.Push(&ob.buyStops, order)
 	} else {
-		ob.BuyOrders[order.Price] = []models.Order{*order}
+		heap.Push(&ob.sellStops, order)
 	}
 
-	return
+	ob.stopIndex[order.ID] = order
 }
 
-func (ob *OrderBook) handleSellAction(order *models.Order) (matchedOrders []models.Order) {
-	if ob.BuyPricesHeap.Len() > 0 {
-		highestBid := ob.BuyPricesHeap[0] 
-		if highestBid >= order.Price {
-			//check the map
-			if buyOrders, exists := ob.BuyOrders[order.Price]; exists {
-				matchedOrders = findMatchingOrdersByAmount(buyOrders, order)
-
-				for i, buyOrder := range buyOrders {
-					if buyOrder.Amount == order.Amount {
-						ob.BuyOrders[buyOrder.Price] = append(buyOrders[:i], buyOrders[i+1:]...)
-						if len(ob.BuyOrders[order.Price]) == 0 {
-							delete(ob.BuyOrders, order.Price)
-							heap.Pop(&ob.BuyPricesHeap)
-						}
-						break
-					}
-				}
+// removeStop takes a pending stop order out of the trigger book.
+func (ob *OrderBook) removeStop(order *models.Order) {
+	delete(ob.stopIndex, order.ID)
+
+	if order.Action == models.Buy {
+		for i, pending := range ob.buyStops {
+			if pending == order {
+				heap.Remove(&ob.buyStops, i)
+				return
 			}
-			return
 		}
+	} else {
+		for i, pending := range ob.sellStops {
+			if pending == order {
+				heap.Remove(&ob.sellStops, i)
+				return
+			}
+		}
+	}
+}
+
+// matchOrder trades an accepted order against the opposite side of the book.
+// The unfilled remainder then either rests in the book or is canceled. A
+// post-only order that would trade is rejected or repriced instead; one that
+// has no positive price to be repriced to is rejected.
+func (ob *OrderBook) matchOrder(order *models.Order, timestamp time.Time) (trades []models.Trade) {
+	if order.PostOnly != "" && ob.crosses(order) {
+		repriced := ob.repricedPostOnly(order)
+		if order.PostOnly == models.PostOnlyReject || repriced <= 0 {
+			order.RejectReason = ReasonPostOnly
+			order.Close(models.StatusRejected, timestamp)
+			ob.emit(EventOrderRejected, order, timestamp, ReasonPostOnly)
+			return nil
+		}
+
+		order.Price = repriced
+		ob.restOrder(order, timestamp)
+		return nil
 	}
-	// no match found or BuyPricesHeap were empty
-	heap.Push(&ob.SellPricesHeap, order.Price)
-	if orders, exists := ob.SellOrders[order.Price]; exists {
-		ob.SellOrders[order.Price] = append(orders, *order) // makes a copy of the order and puts it in the heap
+
+	if order.Action == models.Buy {
+		trades = ob.handleBuyAction(order, ob.limitPrice(order), timestamp)
 	} else {
-		ob.SellOrders[order.Price] = []models.Order{*order}
+		trades = ob.handleSellAction(order, ob.limitPrice(order), timestamp)
+	}
+
+	if order.RemainingAmount == 0 || !order.IsOpen() {
+		return trades
+	}
+
+	if !order.Rests() {
+		order.Close(models.StatusCanceled, timestamp)
+		ob.emit(EventOrderCanceled, order, timestamp, ReasonUnfilled)
+		return trades
+	}
+
+	ob.restOrder(order, timestamp)
+
+	return trades
+}
+
+// crosses reports whether the order would trade against the best opposite
+// price right now.
+func (ob *OrderBook) crosses(order *models.Order) bool {
+	best := ob.oppositeLevels(order.Action).Best()
+	if best == nil {
+		return false
+	}
+
+	if order.Action == models.Buy {
+		return best.Price <= ob.limitPrice(order)
+	}
+
+	return best.Price >= ob.limitPrice(order)
+}
+
+// repricedPostOnly is the price one tick behind the best opposite price, the
+// most aggressive price at which a crossing post-only order can rest. It is
+// not positive when the best ask is a single tick, or when the best bid is so
+// high that adding a tick overflows.
+func (ob *OrderBook) repricedPostOnly(order *models.Order) models.Decimal {
+	if order.Action == models.Buy {
+		return ob.SellLevels.Best().Price - ob.TickSize
+	}
+
+	return ob.BuyLevels.Best().Price + ob.TickSize
+}
+
+// limitPrice is the worst price the order may trade at. For a MARKET order it
+// is bounded only by the protection band, measured from the best opposite
+// price when the order arrives.
+func (ob *OrderBook) limitPrice(order *models.Order) models.Decimal {
+	if !order.IsMarket() {
+		return order.Price
+	}
+
+	if order.Action == models.Buy {
+		if order.ProtectionBand == 0 || ob.SellLevels.Len() == 0 {
+			return models.MaxDecimal
+		}
+		return ob.SellLevels.Best().Price + order.ProtectionBand
+	}
+
+	if order.ProtectionBand == 0 || ob.BuyLevels.Len() == 0 {
+		return models.MinDecimal
+	}
+	return ob.BuyLevels.Best().Price - order.ProtectionBand
+}
+
+// crossingLiquidity is the total amount resting on the opposite side that the
+// order could trade against right now, including the hidden reserve of
+// iceberg orders. Orders of the order's own account are left out, since they
+// never trade against it.
+func (ob *OrderBook) crossingLiquidity(order *models.Order) models.Decimal {
+	limitPrice := ob.limitPrice(order)
+	var liquidity models.Decimal
+
+	for level := range ob.oppositeLevels(order.Action).All() {
+		if (order.Action == models.Buy && level.Price > limitPrice) || (order.Action == models.Sell && level.Price < limitPrice) {
+			break
+		}
+		liquidity += levelRemaining(level, order)
+	}
+
+	return liquidity
+}
+
+// buyCost is the most a buy order without a price can cost if it trades
+// right now: what it takes to buy its amount from the cheapest asks within its
+// protection band, leaving out those of its own account.
+func (ob *OrderBook) buyCost(order *models.Order) (models.Decimal, error) {
+	limitPrice := ob.limitPrice(order)
+	needed := order.Amount
+	var cost models.Decimal
+
+	for level := range ob.SellLevels.All() {
+		if needed == 0 || level.Price > limitPrice {
+			break
+		}
+		amount := min(needed, levelRemaining(level, order))
+		levelCost, err := level.Price.MulUp(amount)
+		if err != nil || cost > models.MaxDecimal-levelCost {
+			return 0, models.ErrDecimalOverflow
+		}
+		cost += levelCost
+		needed -= amount
+	}
+
+	return cost, nil
+}
+
+// reachPrice is the furthest price from the best opposite price at which
+// amount of the order would trade right now, within its limit price and
+// leaving out the orders of its own account. It is 0 if the order would not
+// trade at all.
+func (ob *OrderBook) reachPrice(order *models.Order, amount models.Decimal) models.Decimal {
+	limitPrice := ob.limitPrice(order)
+	var reached models.Decimal
+
+	for level := range ob.oppositeLevels(order.Action).All() {
+		if amount <= 0 || (order.Action == models.Buy && level.Price > limitPrice) || (order.Action == models.Sell && level.Price < limitPrice) {
+			break
+		}
+		if remaining := levelRemaining(level, order); remaining > 0 {
+			reached = level.Price
+			amount -= remaining
+		}
+	}
+
+	return reached
+}
+
+// nextSessionClose is the first session close strictly after now.
+func nextSessionClose(now time.Time, sessionClose time.Duration) time.Time {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	close := midnight.Add(sessionClose)
+	if !close.After(now) {
+		close = close.Add(24 * time.Hour)
+	}
+
+	return close
+}
+
+// handleBuyAction walks the sell side from the cheapest price upwards while
+// it is within limitPrice, filling resting orders in arrival order.
+func (ob *OrderBook) handleBuyAction(order *models.Order, limitPrice models.Decimal, timestamp time.Time) (trades []models.Trade) {
+	for order.RemainingAmount > 0 && order.IsOpen() && ob.SellLevels.Len() > 0 {
+		cheapestSell := ob.SellLevels.Best()
+		if limitPrice < cheapestSell.Price {
+			break
+		}
+
+		trades = append(trades, ob.matchPriceLevel(cheapestSell, order, timestamp)...)
+		if cheapestSell.Len() == 0 {
+			ob.SellLevels.Remove(cheapestSell.Price)
+		}
 	}
 
 	return
 }
 
-func findMatchingOrdersByAmount(orders []models.Order, order *models.Order) []models.Order {
-	matchedOrders := []models.Order{}
-	for _, o := range orders {
-		if o.Amount == order.Amount {
-			matchedOrders = append(matchedOrders, o)
+// handleSellAction is the mirror of handleBuyAction: it walks the buy side
+// from the highest bid downwards.
+func (ob *OrderBook) handleSellAction(order *models.Order, limitPrice models.Decimal, timestamp time.Time) (trades []models.Trade) {
+	for order.RemainingAmount > 0 && order.IsOpen() && ob.BuyLevels.Len() > 0 {
+		highestBid := ob.BuyLevels.Best()
+		if limitPrice > highestBid.Price {
+			break
+		}
+
+		trades = append(trades, ob.matchPriceLevel(highestBid, order, timestamp)...)
+		if highestBid.Len() == 0 {
+			ob.BuyLevels.Remove(highestBid.Price)
 		}
 	}
 
-	return matchedOrders
+	return
 }
 
+// restOrder adds an order to the back of the queue at its price. An iceberg
+// order shows a full peak when it joins the queue.
+func (ob *OrderBook) restOrder(order *models.Order, timestamp time.Time) {
+	order.Replenish()
+
+	ob.levelsFor(order.Action).GetOrCreate(order.Price).Push(order)
+	ob.orderIndex[order.ID] = order
+	ob.emit(EventOrderRested, order, timestamp, "")
+}
+
+// removeOrder takes a resting order out of its price level and the order
+// index. A level left empty is removed, so each side only ever holds prices
+// that have resting orders.
+func (ob *OrderBook) removeOrder(order *models.Order) {
+	delete(ob.orderIndex, order.ID)
+
+	levels := ob.levelsFor(order.Action)
+	level := levels.Get(order.Price)
+	level.Remove(order)
+	if level.Len() == 0 {
+		levels.Remove(order.Price)
+	}
+}
+
+func (ob *OrderBook) levelsFor(action models.OrderType) *models.PriceLevels {
+	if action == models.Buy {
+		return ob.BuyLevels
+	}
+
+	return ob.SellLevels
+}
+
+// oppositeLevels is the side an order of the given action trades against.
+func (ob *OrderBook) oppositeLevels(action models.OrderType) *models.PriceLevels {
+	if action == models.Buy {
+		return ob.SellLevels
+	}
+
+	return ob.BuyLevels
+}
+
+// levelRemaining is the total amount resting at a price level that the taker
+// can trade against, hidden reserve included.
+func levelRemaining(level *models.PriceLevel, taker *models.Order) models.Decimal {
+	if taker.AccountID == "" {
+		return level.Remaining()
+	}
+
+	var remaining models.Decimal
+	for _, order := range level.Orders() {
+		if !taker.SelfTrades(order) {
+			remaining += order.RemainingAmount
+		}
+	}
+
+	return remaining
+}
+
+// matchPriceLevel fills the taker order against the orders of a single price
+// level, oldest first, until either side runs out. Resting orders that are
+// only partially filled keep their place at the front of the queue, except for
+// an iceberg order whose peak is used up: it shows a new peak from its reserve
+// and goes to the back of the queue. Orders of the same account never trade
+// against each other; the taker's self-trade prevention mode decides which of
+// them is canceled or reduced instead.
+func (ob *OrderBook) matchPriceLevel(level *models.PriceLevel, taker *models.Order, timestamp time.Time) []models.Trade {
+	var trades []models.Trade
+
+	for level.Len() > 0 && taker.RemainingAmount > 0 && taker.IsOpen() {
+		maker := level.Front()
+		ob.changed = append(ob.changed, maker)
+		if taker.SelfTrades(maker) {
+			if ob.preventSelfTrade(level, maker, taker, timestamp) {
+				level.PopFront()
+				delete(ob.orderIndex, maker.ID)
+				maker.Close(models.StatusCanceled, timestamp)
+				ob.emit(EventOrderCanceled, maker, timestamp, ReasonSelfTrade)
+			} else if maker.VisibleAmount == 0 {
+				requeue(level)
+			}
+			continue
+		}
+
+		amount := min(maker.VisibleAmount, taker.RemainingAmount)
+		price := maker.Price
+		if ob.auctionPrice != 0 {
+			price = ob.auctionPrice
+		}
+
+		ob.lastTradeID++
+		ob.lastTradePrice = price
+		trade := models.Trade{
+			ID:            ob.lastTradeID,
+			Symbol:        taker.Symbol,
+			MakerOrderID:  maker.ID,
+			TakerOrderID:  taker.ID,
+			Price:         price,
+			Amount:        amount,
+			AggressorSide: taker.Action,
+			Timestamp:     timestamp,
+			Sequence:      ob.sequence,
+		}
+		trades = append(trades, trade)
+		ob.emitTrade(trade)
+
+		taker.Fill(amount, timestamp)
+		level.Update(maker, func() {
+			maker.Fill(amount, timestamp)
+		})
+		if maker.Status == models.StatusFilled {
+			level.PopFront()
+			delete(ob.orderIndex, maker.ID)
+		} else if maker.VisibleAmount == 0 {
+			requeue(level)
+		}
+	}
+
+	return trades
+}
+
+// requeue sends the iceberg order at the front of the level, whose peak is
+// used up, to the back of the queue with a new peak from its reserve.
+func requeue(level *models.PriceLevel) {
+	maker := level.PopFront()
+	maker.Replenish()
+	level.Push(maker)
+}
+
+// preventSelfTrade applies the taker's self-trade prevention mode to a maker
+// of the same account. It reports whether the maker is to be canceled; the
+// caller takes it out of the level.
+func (ob *OrderBook) preventSelfTrade(level *models.PriceLevel, maker *models.Order, taker *models.Order, timestamp time.Time) bool {
+	cancelMaker := false
+
+	switch taker.SelfTradePrevention {
+	case models.CancelOldest:
+		cancelMaker = true
+	case models.CancelBoth:
+		cancelMaker = true
+		taker.Close(models.StatusCanceled, timestamp)
+		ob.emit(EventOrderCanceled, taker, timestamp, ReasonSelfTrade)
+	case models.Decrement:
+		amount := min(maker.RemainingAmount, taker.RemainingAmount)
+		level.Update(maker, func() {
+			maker.Resize(maker.RemainingAmount-amount, timestamp)
+		})
+		taker.Resize(taker.RemainingAmount-amount, timestamp)
+		cancelMaker = maker.RemainingAmount == 0
+		if taker.RemainingAmount == 0 {
+			taker.Close(models.StatusCanceled, timestamp)
+			ob.emit(EventOrderCanceled, taker, timestamp, ReasonSelfTrade)
+		}
+	default:
+		taker.Close(models.StatusCanceled, timestamp)
+		ob.emit(EventOrderCanceled, taker, timestamp, ReasonSelfTrade)
+	}
+
+	return cancelMaker
+}
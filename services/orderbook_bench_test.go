@@ -0,0 +1,72 @@
+package services
+
+import (
+	"fmt"
+	"order-matching/models"
+	"testing"
+)
+
+// The benchmarks below build books with many price levels, where the cost of
+// the price-level structure dominates.
+const benchmarkLevels = 10_000
+
+// benchmarkBook rests one sell order per level from 1000.01 upwards and one
+// buy order per level from 999.99 downwards.
+func benchmarkBook(levels int) *OrderBook {
+	ob := NewOrderBook()
+	for i := 0; i < levels; i++ {
+		ob.PlaceOrder(&models.Order{ID: fmt.Sprintf("sell-%d", i), Action: models.Sell, Price: models.NewDecimal(1000) + models.Decimal(i+1)*ob.TickSize, Amount: models.NewDecimal(1)})
+		ob.PlaceOrder(&models.Order{ID: fmt.Sprintf("buy-%d", i), Action: models.Buy, Price: models.NewDecimal(1000) - models.Decimal(i+1)*ob.TickSize, Amount: models.NewDecimal(1)})
+	}
+
+	return ob
+}
+
+// BenchmarkPlaceOrder_Resting adds orders at scattered prices that do not
+// cross, each one opening or joining a level deep in the book.
+func BenchmarkPlaceOrder_Resting(b *testing.B) {
+	ob := benchmarkBook(benchmarkLevels)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		level := models.Decimal((i*7919)%(2*benchmarkLevels) + 1)
+		ob.PlaceOrder(&models.Order{ID: fmt.Sprintf("rest-%d", i), Action: models.Buy, Price: models.NewDecimal(1000) - level*ob.TickSize, Amount: models.NewDecimal(1)})
+	}
+}
+
+// BenchmarkPlaceOrder_Crossing takes out the best sell level and puts it
+// back, so every iteration empties and recreates the top of the book.
+func BenchmarkPlaceOrder_Crossing(b *testing.B) {
+	ob := benchmarkBook(benchmarkLevels)
+	best := models.NewDecimal(1000) + ob.TickSize
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ob.PlaceOrder(&models.Order{ID: fmt.Sprintf("take-%d", i), Action: models.Buy, Price: best, Amount: models.NewDecimal(1)})
+		ob.PlaceOrder(&models.Order{ID: fmt.Sprintf("refill-%d", i), Action: models.Sell, Price: best, Amount: models.NewDecimal(1)})
+	}
+}
+
+// BenchmarkCancelOrder cancels the only order of a level deep in the book
+// and places it again.
+func BenchmarkCancelOrder(b *testing.B) {
+	ob := benchmarkBook(benchmarkLevels)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		level := (i * 7919) % benchmarkLevels
+		id := fmt.Sprintf("cancel-%d", i)
+		ob.PlaceOrder(&models.Order{ID: id, Action: models.Sell, Price: models.NewDecimal(2000) + models.Decimal(level)*ob.TickSize, Amount: models.NewDecimal(1)})
+		ob.CancelOrder(id)
+	}
+}
+
+// BenchmarkGetOrderBook reads the top ten levels of each side.
+func BenchmarkGetOrderBook(b *testing.B) {
+	ob := benchmarkBook(benchmarkLevels)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ob.GetOrderBook(10)
+	}
+}
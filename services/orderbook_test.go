@@ -1,9 +1,9 @@
 package services
 
 import (
-	"container/heap"
 	"order-matching/models"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -15,167 +15,117 @@ func TestPlaceSellOrder_WhenBuyHeapIsEmpty(t *testing.T) {
 	sellOrder := models.Order{
 		ID:     "550e8400-e29b-41d4-a716-446655440000",
         Action: models.Sell,
-        Price:  100.0,
-        Amount: 2.0,
+        Price:  decimal("100.0"),
+        Amount: decimal("2.0"),
 	}
 
 	matchedOrder := ob.PlaceOrder(&sellOrder)
 
 	assert.Equal(t, 0, len(matchedOrder))
-	assert.Equal(t, 1, len(ob.SellOrders[sellOrder.Price]))
-	assert.Equal(t, 1, len(ob.SellPricesHeap))
+	assert.Equal(t, 1, len(ob.SellLevels.Orders(sellOrder.Price)))
+	assert.Equal(t, 1, ob.SellLevels.Len())
 }
 
 func TestPlaceSellOrder_WhenNoBuyOrderIsMatched(t *testing.T) {
 	t.Parallel()
 	ob := NewOrderBook()
 
-	ob.BuyOrders = map[float64][]models.Order{
-		80.0: {
-			{
-				ID:     "550e8400-e29b-41d4-a716-666655442000",
-        		Action: models.Buy,
-       			Price:  80.0,
-       			Amount: 2.0,
-			},
-		},
-	}
-
-	heap.Push(&ob.BuyPricesHeap, 80.0)
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-666655442000", Action: models.Buy, Price: decimal("80.0"), Amount: decimal("2.0")})
 
 	sellOrder := models.Order{
 		ID:     "550e8400-e29b-41d4-a716-446655440000",
         Action: models.Sell,
-        Price:  100.0,
-        Amount: 2.0,
+        Price:  decimal("100.0"),
+        Amount: decimal("2.0"),
 	}
 
 	matchedOrder := ob.PlaceOrder(&sellOrder)
 
 	assert.Equal(t, 0, len(matchedOrder))
-	assert.Equal(t, 1, len(ob.SellOrders[sellOrder.Price]))
-	assert.Equal(t, 1, len(ob.SellPricesHeap))
+	assert.Equal(t, 1, len(ob.SellLevels.Orders(sellOrder.Price)))
+	assert.Equal(t, 1, ob.SellLevels.Len())
 }
 
 func TestPlaceSellOrder_WhenOneBuyOrderIsMatched(t *testing.T) {
 	t.Parallel()
 	ob := NewOrderBook()
 
-	ob.BuyOrders = map[float64][]models.Order{
-		80.0: {
-			{
-				ID:     "550e8400-e29b-41d4-a716-666655442000",
-        		Action: models.Buy,
-       			Price:  80.0,
-       			Amount: 2.0,
-			},
-		},
-		100.0: {
-			{
-				ID:     "550e8400-e29b-41d4-a716-77755442000",
-        		Action: models.Buy,
-       			Price:  100.0,
-       			Amount: 2.0,
-			},
-		},
-	}
-
-	heap.Push(&ob.BuyPricesHeap, 80.0)
-	heap.Push(&ob.BuyPricesHeap, 100.0)
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-666655442000", Action: models.Buy, Price: decimal("80.0"), Amount: decimal("2.0")})
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442000", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("2.0")})
 
 	sellOrder := models.Order{
 		ID:     "550e8400-e29b-41d4-a716-446655440000",
         Action: models.Sell,
-        Price:  100.0,
-        Amount: 2.0,
+        Price:  decimal("100.0"),
+        Amount: decimal("2.0"),
 	}
 
 	matchedOrders := ob.PlaceOrder(&sellOrder)
 	
 	assert.Equal(t, 1, len(matchedOrders))
-	expectedMatchedOrders := []models.Order {
+	expectedTrades := []models.Trade {
 		{
-			ID:     "550e8400-e29b-41d4-a716-77755442000",
-			Action: models.Buy,
-			   Price:  100.0,
-			   Amount: 2.0,
+			ID:            1,
+			MakerOrderID:  "550e8400-e29b-41d4-a716-77755442000",
+			TakerOrderID:  "550e8400-e29b-41d4-a716-446655440000",
+			Price:         decimal("100.0"),
+			Amount:        decimal("2.0"),
+			AggressorSide: models.Sell,
+			Sequence:      3,
 		},
 	}
-	assert.Equal(t, expectedMatchedOrders, matchedOrders)
+	assert.Equal(t, expectedTrades, withoutTimestamps(matchedOrders))
 
-	assert.Equal(t, 0, len(ob.SellOrders[sellOrder.Price]))
-	assert.Equal(t, 0, len(ob.SellPricesHeap))
+	assert.Equal(t, 0, len(ob.SellLevels.Orders(sellOrder.Price)))
+	assert.Equal(t, 0, ob.SellLevels.Len())
 
-	assert.Equal(t, 0, len(ob.BuyOrders[sellOrder.Price]))
-	assert.Equal(t, 1, len(ob.BuyPricesHeap))
+	assert.Equal(t, 0, len(ob.BuyLevels.Orders(sellOrder.Price)))
+	assert.Equal(t, 1, ob.BuyLevels.Len())
 }
 
 func TestPlaceSellOrder_WhenTwoBuyOrdersAreMatched(t *testing.T) {
 	t.Parallel()
 	ob := NewOrderBook()
 
-	ob.BuyOrders = map[float64][]models.Order{
-		80.0: {
-			{
-				ID:     "550e8400-e29b-41d4-a716-666655442000",
-        		Action: models.Buy,
-       			Price:  80.0,
-       			Amount: 2.0,
-			},
-		},
-		100.0: {
-			{
-				ID:     "550e8400-e29b-41d4-a716-77755442000",
-        		Action: models.Buy,
-       			Price:  100.0,
-       			Amount: 2.0,
-			},
-			{
-				ID:     "550e8400-e29b-41d4-a716-77755442001",
-        		Action: models.Buy,
-       			Price:  100.0,
-       			Amount: 2.0,
-			},
-			{
-				ID:     "550e8400-e29b-41d4-a716-77755442002",
-        		Action: models.Buy,
-       			Price:  100.0,
-       			Amount: 3.0,
-			},
-		},
-	}
-
-	heap.Push(&ob.BuyPricesHeap, 80.0)
-	heap.Push(&ob.BuyPricesHeap, 100.0)
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-666655442000", Action: models.Buy, Price: decimal("80.0"), Amount: decimal("2.0")})
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442000", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("2.0")})
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442001", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("2.0")})
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442002", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("3.0")})
 
 	sellOrder := models.Order{
 		ID:     "550e8400-e29b-41d4-a716-446655440000",
         Action: models.Sell,
-        Price:  100.0,
-        Amount: 2.0,
+        Price:  decimal("100.0"),
+        Amount: decimal("4.0"),
 	}
 
 	matchedOrders := ob.PlaceOrder(&sellOrder)
 	
 	assert.Equal(t, 2, len(matchedOrders))
-	expectedMatchedOrders := []models.Order {
+	expectedTrades := []models.Trade {
 		{
-			ID:     "550e8400-e29b-41d4-a716-77755442000",
-			Action: models.Buy,
-			   Price:  100.0,
-			   Amount: 2.0,
+			ID:            1,
+			MakerOrderID:  "550e8400-e29b-41d4-a716-77755442000",
+			TakerOrderID:  "550e8400-e29b-41d4-a716-446655440000",
+			Price:         decimal("100.0"),
+			Amount:        decimal("2.0"),
+			AggressorSide: models.Sell,
+			Sequence:      5,
 		},
 		{
-			ID:     "550e8400-e29b-41d4-a716-77755442001",
-			Action: models.Buy,
-			   Price:  100.0,
-			   Amount: 2.0,
+			ID:            2,
+			MakerOrderID:  "550e8400-e29b-41d4-a716-77755442001",
+			TakerOrderID:  "550e8400-e29b-41d4-a716-446655440000",
+			Price:         decimal("100.0"),
+			Amount:        decimal("2.0"),
+			AggressorSide: models.Sell,
+			Sequence:      5,
 		},
 	}
-	assert.Equal(t, expectedMatchedOrders, matchedOrders)
+	assert.Equal(t, expectedTrades, withoutTimestamps(matchedOrders))
 
-	assert.Equal(t, 0, len(ob.SellOrders[sellOrder.Price]))
-	assert.Equal(t, 0, len(ob.SellPricesHeap))
+	assert.Equal(t, 0, len(ob.SellLevels.Orders(sellOrder.Price)))
+	assert.Equal(t, 0, ob.SellLevels.Len())
 }
 
 func TestPlaceBuyOrder_WhenSellHeapIsEmpty(t *testing.T) {
@@ -185,246 +135,1083 @@ func TestPlaceBuyOrder_WhenSellHeapIsEmpty(t *testing.T) {
 	buyOrder := models.Order{
 		ID:     "550e8400-e29b-41d4-a716-446655440000",
         Action: models.Buy,
-        Price:  100.0,
-        Amount: 2.0,
+        Price:  decimal("100.0"),
+        Amount: decimal("2.0"),
 	}
 
 	matchedOrder := ob.PlaceOrder(&buyOrder)
 
 	assert.Equal(t, 0, len(matchedOrder))
-	assert.Equal(t, 1, len(ob.BuyOrders[buyOrder.Price]))
-	assert.Equal(t, 1, len(ob.BuyOrders))
+	assert.Equal(t, 1, len(ob.BuyLevels.Orders(buyOrder.Price)))
+	assert.Equal(t, 1, ob.BuyLevels.Len())
 }
 
 func TestPlaceBuyOrder_WhenNoSellOrderIsMatched(t *testing.T) {
 	t.Parallel()
 	ob := NewOrderBook()
 
-	ob.SellOrders = map[float64][]models.Order{
-		120.0: {
-			{
-				ID:     "550e8400-e29b-41d4-a716-666655442000",
-        		Action: models.Sell,
-       			Price:  120.0,
-       			Amount: 2.0,
-			},
-		},
-	}
-
-	heap.Push(&ob.SellPricesHeap, 120.0)
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-666655442000", Action: models.Sell, Price: decimal("120.0"), Amount: decimal("2.0")})
 
 	buyOrder := models.Order{
 		ID:     "550e8400-e29b-41d4-a716-446655440000",
         Action: models.Buy,
-        Price:  100.0,
-        Amount: 2.0,
+        Price:  decimal("100.0"),
+        Amount: decimal("2.0"),
 	}
 
 	matchedOrder := ob.PlaceOrder(&buyOrder)
 
 	assert.Equal(t, 0, len(matchedOrder))
-	assert.Equal(t, 1, len(ob.BuyOrders[buyOrder.Price]))
-	assert.Equal(t, 1, len(ob.BuyPricesHeap))
+	assert.Equal(t, 1, len(ob.BuyLevels.Orders(buyOrder.Price)))
+	assert.Equal(t, 1, ob.BuyLevels.Len())
 }
 
 func TestPlaceBuyOrder_WhenOneSellOrderIsMatched(t *testing.T) {
 	t.Parallel()
 	ob := NewOrderBook()
 
-	ob.SellOrders = map[float64][]models.Order{
-		120.0: {
-			{
-				ID:     "550e8400-e29b-41d4-a716-666655442000",
-        		Action: models.Sell,
-       			Price:  120.0,
-       			Amount: 2.0,
-			},
-		},
-		100.0: {
-			{
-				ID:     "550e8400-e29b-41d4-a716-77755442000",
-        		Action: models.Sell,
-       			Price:  100.0,
-       			Amount: 2.0,
-			},
-		},
-	}
-
-	heap.Push(&ob.SellPricesHeap, 120.0)
-	heap.Push(&ob.SellPricesHeap, 100.0)
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-666655442000", Action: models.Sell, Price: decimal("120.0"), Amount: decimal("2.0")})
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442000", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("2.0")})
 
 	buyOrder := models.Order{
 		ID:     "550e8400-e29b-41d4-a716-446655440000",
         Action: models.Buy,
-        Price:  100.0,
-        Amount: 2.0,
+        Price:  decimal("100.0"),
+        Amount: decimal("2.0"),
 	}
 
 	matchedOrders := ob.PlaceOrder(&buyOrder)
 	
 	assert.Equal(t, 1, len(matchedOrders))
-	expectedMatchedOrders := []models.Order {
+	expectedTrades := []models.Trade {
 		{
-			ID:     "550e8400-e29b-41d4-a716-77755442000",
-			Action: models.Sell,
-			Price:  100.0,
-			Amount: 2.0,
+			ID:            1,
+			MakerOrderID:  "550e8400-e29b-41d4-a716-77755442000",
+			TakerOrderID:  "550e8400-e29b-41d4-a716-446655440000",
+			Price:         decimal("100.0"),
+			Amount:        decimal("2.0"),
+			AggressorSide: models.Buy,
+			Sequence:      3,
 		},
 	}
-	assert.Equal(t, expectedMatchedOrders, matchedOrders)
+	assert.Equal(t, expectedTrades, withoutTimestamps(matchedOrders))
 
-	assert.Equal(t, 0, len(ob.BuyOrders[buyOrder.Price]))
-	assert.Equal(t, 0, len(ob.BuyPricesHeap))
+	assert.Equal(t, 0, len(ob.BuyLevels.Orders(buyOrder.Price)))
+	assert.Equal(t, 0, ob.BuyLevels.Len())
 
-	assert.Equal(t, 0, len(ob.SellOrders[buyOrder.Price]))
-	assert.Equal(t, 1, len(ob.SellPricesHeap))
+	assert.Equal(t, 0, len(ob.SellLevels.Orders(buyOrder.Price)))
+	assert.Equal(t, 1, ob.SellLevels.Len())
 }
 
 func TestPlaceBuyOrder_WhenTwoSellOrdersAreMatched(t *testing.T) {
 	t.Parallel()
 	ob := NewOrderBook()
 
-	ob.SellOrders = map[float64][]models.Order{
-		120.0: {
-			{
-				ID:     "550e8400-e29b-41d4-a716-666655442000",
-        		Action: models.Sell,
-       			Price:  120.0,
-       			Amount: 2.0,
-			},
-		},
-		100.0: {
-			{
-				ID:     "550e8400-e29b-41d4-a716-77755442000",
-        		Action: models.Sell,
-       			Price:  100.0,
-       			Amount: 2.0,
-			},
-			{
-				ID:     "550e8400-e29b-41d4-a716-77755442001",
-        		Action: models.Sell,
-       			Price:  100.0,
-       			Amount: 2.0,
-			},
-			{
-				ID:     "550e8400-e29b-41d4-a716-77755442002",
-        		Action: models.Sell,
-       			Price:  100.0,
-       			Amount: 3.0,
-			},
-		},
-	}
-
-	heap.Push(&ob.SellPricesHeap, 120.0)
-	heap.Push(&ob.SellPricesHeap, 100.0)
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-666655442000", Action: models.Sell, Price: decimal("120.0"), Amount: decimal("2.0")})
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442000", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("2.0")})
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442001", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("2.0")})
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442002", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("3.0")})
 
 	buyOrder := models.Order{
 		ID:     "550e8400-e29b-41d4-a716-446655440000",
         Action: models.Buy,
-        Price:  100.0,
-        Amount: 2.0,
+        Price:  decimal("100.0"),
+        Amount: decimal("4.0"),
 	}
 
 	matchedOrders := ob.PlaceOrder(&buyOrder)
 	
 	assert.Equal(t, 2, len(matchedOrders))
-	expectedMatchedOrders := []models.Order {
+	expectedTrades := []models.Trade {
 		{
-			ID:     "550e8400-e29b-41d4-a716-77755442000",
-			Action: models.Sell,
-			   Price:  100.0,
-			   Amount: 2.0,
+			ID:            1,
+			MakerOrderID:  "550e8400-e29b-41d4-a716-77755442000",
+			TakerOrderID:  "550e8400-e29b-41d4-a716-446655440000",
+			Price:         decimal("100.0"),
+			Amount:        decimal("2.0"),
+			AggressorSide: models.Buy,
+			Sequence:      5,
 		},
 		{
-			ID:     "550e8400-e29b-41d4-a716-77755442001",
-			Action: models.Sell,
-			   Price:  100.0,
-			   Amount: 2.0,
+			ID:            2,
+			MakerOrderID:  "550e8400-e29b-41d4-a716-77755442001",
+			TakerOrderID:  "550e8400-e29b-41d4-a716-446655440000",
+			Price:         decimal("100.0"),
+			Amount:        decimal("2.0"),
+			AggressorSide: models.Buy,
+			Sequence:      5,
 		},
 	}
-	assert.Equal(t, expectedMatchedOrders, matchedOrders)
+	assert.Equal(t, expectedTrades, withoutTimestamps(matchedOrders))
 
-	assert.Equal(t, 0, len(ob.BuyOrders[buyOrder.Price]))
-	assert.Equal(t, 0, len(ob.BuyPricesHeap))
+	assert.Equal(t, 0, len(ob.BuyLevels.Orders(buyOrder.Price)))
+	assert.Equal(t, 0, ob.BuyLevels.Len())
 }
 
-func TestGetOrderBook(t *testing.T) {
+func TestPlaceBuyOrder_WhenSellOrderIsPartiallyFilled(t *testing.T) {
 	t.Parallel()
 	ob := NewOrderBook()
-	ob.SellOrders = map[float64][]models.Order{
-		120.0: {
-			{
-				ID:     "550e8400-e29b-41d4-a716-666655442000",
-        		Action: models.Sell,
-       			Price:  120.0,
-       			Amount: 2.0,
-			},
-		},
-		100.0: {
-			{
-				ID:     "550e8400-e29b-41d4-a716-77755442000",
-        		Action: models.Sell,
-       			Price:  100.0,
-       			Amount: 2.0,
-			},
-			{
-				ID:     "550e8400-e29b-41d4-a716-77755442001",
-        		Action: models.Sell,
-       			Price:  100.0,
-       			Amount: 2.0,
-			},
-		},
+
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442000", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("5.0")})
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442001", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("1.0")})
+
+	buyOrder := models.Order{
+		ID:     "550e8400-e29b-41d4-a716-446655440000",
+		Action: models.Buy,
+		Price:  decimal("100.0"),
+		Amount: decimal("2.0"),
 	}
 
-	heap.Push(&ob.BuyPricesHeap, 80.0)
-	heap.Push(&ob.BuyPricesHeap, 100.0)
-
-	ob.BuyOrders = map[float64][]models.Order{
-		80.0: {
-			{
-				ID:     "550e8400-e29b-41d4-a716-666655442000",
-        		Action: models.Buy,
-       			Price:  80.0,
-       			Amount: 2.0,
-			},
-		},
-		100.0: {
-			{
-				ID:     "550e8400-e29b-41d4-a716-77755442000",
-        		Action: models.Buy,
-       			Price:  100.0,
-       			Amount: 2.0,
-			},
-		},
+	trades := ob.PlaceOrder(&buyOrder)
+
+	assert.Equal(t, []models.Trade{
+		{ID: 1, MakerOrderID: "550e8400-e29b-41d4-a716-77755442000", TakerOrderID: "550e8400-e29b-41d4-a716-446655440000", Price: decimal("100.0"), Amount: decimal("2.0"), AggressorSide: models.Buy, Sequence: 3},
+	}, withoutTimestamps(trades))
+
+	// the partially filled order keeps its place at the front of the queue
+	assert.Equal(t, 2, len(ob.SellLevels.Orders(decimal("100.0"))))
+	assert.Equal(t, "550e8400-e29b-41d4-a716-77755442000", ob.SellLevels.Orders(decimal("100.0"))[0].ID)
+	assert.Equal(t, decimal("3.0"), ob.SellLevels.Orders(decimal("100.0"))[0].RemainingAmount)
+	assert.Equal(t, 0, ob.BuyLevels.Len())
+	assert.Equal(t, 0, ob.BuyLevels.Len())
+}
+
+func TestPlaceBuyOrder_WhenItSweepsSeveralPriceLevels(t *testing.T) {
+	t.Parallel()
+	ob := NewOrderBook()
+
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442000", Action: models.Sell, Price: decimal("102.0"), Amount: decimal("1.0")})
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442001", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("1.0")})
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442002", Action: models.Sell, Price: decimal("101.0"), Amount: decimal("1.0")})
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442003", Action: models.Sell, Price: decimal("105.0"), Amount: decimal("1.0")})
+
+	buyOrder := models.Order{
+		ID:     "550e8400-e29b-41d4-a716-446655440000",
+		Action: models.Buy,
+		Price:  decimal("102.0"),
+		Amount: decimal("4.0"),
+	}
+
+	trades := ob.PlaceOrder(&buyOrder)
+
+	assert.Equal(t, []models.Trade{
+		{ID: 1, MakerOrderID: "550e8400-e29b-41d4-a716-77755442001", TakerOrderID: "550e8400-e29b-41d4-a716-446655440000", Price: decimal("100.0"), Amount: decimal("1.0"), AggressorSide: models.Buy, Sequence: 5},
+		{ID: 2, MakerOrderID: "550e8400-e29b-41d4-a716-77755442002", TakerOrderID: "550e8400-e29b-41d4-a716-446655440000", Price: decimal("101.0"), Amount: decimal("1.0"), AggressorSide: models.Buy, Sequence: 5},
+		{ID: 3, MakerOrderID: "550e8400-e29b-41d4-a716-77755442000", TakerOrderID: "550e8400-e29b-41d4-a716-446655440000", Price: decimal("102.0"), Amount: decimal("1.0"), AggressorSide: models.Buy, Sequence: 5},
+	}, withoutTimestamps(trades))
+
+	// the unfilled remainder rests at the order's limit price
+	assert.Equal(t, 1, len(ob.BuyLevels.Orders(decimal("102.0"))))
+	assert.Equal(t, decimal("1.0"), ob.BuyLevels.Orders(decimal("102.0"))[0].RemainingAmount)
+	assert.Equal(t, 1, ob.BuyLevels.Len())
+	assert.Equal(t, 1, ob.SellLevels.Len())
+	assert.Equal(t, decimal("105.0"), ob.SellLevels.Best().Price)
+}
+
+func TestPlaceSellOrder_WhenItSweepsSeveralPriceLevels(t *testing.T) {
+	t.Parallel()
+	ob := NewOrderBook()
+
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442000", Action: models.Buy, Price: decimal("99.0"), Amount: decimal("2.0")})
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442001", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0")})
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442002", Action: models.Buy, Price: decimal("98.0"), Amount: decimal("1.0")})
+
+	sellOrder := models.Order{
+		ID:     "550e8400-e29b-41d4-a716-446655440000",
+		Action: models.Sell,
+		Price:  decimal("99.0"),
+		Amount: decimal("2.5"),
+	}
+
+	trades := ob.PlaceOrder(&sellOrder)
+
+	assert.Equal(t, []models.Trade{
+		{ID: 1, MakerOrderID: "550e8400-e29b-41d4-a716-77755442001", TakerOrderID: "550e8400-e29b-41d4-a716-446655440000", Price: decimal("100.0"), Amount: decimal("1.0"), AggressorSide: models.Sell, Sequence: 4},
+		{ID: 2, MakerOrderID: "550e8400-e29b-41d4-a716-77755442000", TakerOrderID: "550e8400-e29b-41d4-a716-446655440000", Price: decimal("99.0"), Amount: decimal("1.5"), AggressorSide: models.Sell, Sequence: 4},
+	}, withoutTimestamps(trades))
+
+	assert.Equal(t, decimal("0.5"), ob.BuyLevels.Orders(decimal("99.0"))[0].RemainingAmount)
+	assert.Equal(t, 0, ob.SellLevels.Len())
+	assert.Equal(t, decimal("99.0"), ob.BuyLevels.Best().Price)
+}
+
+func TestPlaceMarketOrder(t *testing.T) {
+	t.Parallel()
+
+	newOrderBook := func() *OrderBook {
+		ob := NewOrderBook()
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442000", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("1.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442001", Action: models.Sell, Price: decimal("101.0"), Amount: decimal("1.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442002", Action: models.Sell, Price: decimal("110.0"), Amount: decimal("1.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442003", Action: models.Buy, Price: decimal("99.0"), Amount: decimal("1.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442004", Action: models.Buy, Price: decimal("90.0"), Amount: decimal("1.0")})
+		return ob
+	}
+
+	t.Run("It sweeps the book from the best price and never rests", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		order := models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Kind: models.Market, Amount: decimal("2.5")}
+
+		trades := ob.PlaceOrder(&order)
+
+		assert.Equal(t, 3, len(trades))
+		assert.Equal(t, decimal("100.0"), trades[0].Price)
+		assert.Equal(t, decimal("101.0"), trades[1].Price)
+		assert.Equal(t, decimal("110.0"), trades[2].Price)
+		assert.Equal(t, decimal("0.5"), trades[2].Amount)
+		assert.Equal(t, models.StatusFilled, order.Status)
+		assert.Equal(t, 1, ob.SellLevels.Len())
+		assert.Equal(t, 2, ob.BuyLevels.Len())
+	})
+
+	t.Run("It cancels what is left when the book is exhausted", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		order := models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Sell, Kind: models.Market, Amount: decimal("5.0")}
+
+		trades := ob.PlaceOrder(&order)
+
+		assert.Equal(t, 2, len(trades))
+		assert.Equal(t, models.StatusCanceled, order.Status)
+		assert.Equal(t, decimal("2.0"), order.FilledAmount)
+		assert.Equal(t, decimal("3.0"), order.RemainingAmount)
+		assert.Equal(t, 0, ob.BuyLevels.Len())
+		assert.Equal(t, 3, ob.SellLevels.Len(), "the market sell does not rest")
+		_, exists := ob.orderIndex[order.ID]
+		assert.False(t, exists)
+	})
+
+	t.Run("It stops at the protection band", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		order := models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Kind: models.Market, Amount: decimal("3.0"), ProtectionBand: decimal("5.0")}
+
+		trades := ob.PlaceOrder(&order)
+
+		assert.Equal(t, 2, len(trades))
+		assert.Equal(t, decimal("101.0"), trades[1].Price)
+		assert.Equal(t, models.StatusCanceled, order.Status)
+		assert.Equal(t, decimal("1.0"), order.RemainingAmount)
+		assert.Equal(t, decimal("110.0"), ob.SellLevels.Best().Price)
+	})
+
+	t.Run("It is canceled straight away against an empty book", func(t *testing.T) {
+		t.Parallel()
+		ob := NewOrderBook()
+		order := models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Kind: models.Market, Amount: decimal("1.0")}
+
+		trades := ob.PlaceOrder(&order)
+
+		assert.Equal(t, 0, len(trades))
+		assert.Equal(t, models.StatusCanceled, order.Status)
+		assert.Equal(t, 0, ob.BuyLevels.Len())
+	})
+}
+
+func TestPlaceOrder_TimeInForce(t *testing.T) {
+	t.Parallel()
+
+	newOrderBook := func() *OrderBook {
+		ob := NewOrderBook()
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442000", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("1.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442001", Action: models.Sell, Price: decimal("101.0"), Amount: decimal("1.0")})
+		return ob
+	}
+
+	t.Run("It cancels the remainder of an IOC order", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		order := models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("2.0"), TimeInForce: models.ImmediateOrCancel}
+
+		trades := ob.PlaceOrder(&order)
+
+		assert.Equal(t, 1, len(trades))
+		assert.Equal(t, models.StatusCanceled, order.Status)
+		assert.Equal(t, decimal("1.0"), order.FilledAmount)
+		assert.Equal(t, 0, ob.BuyLevels.Len())
+	})
+
+	t.Run("It kills a FOK order that cannot be filled completely", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		order := models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("2.0"), TimeInForce: models.FillOrKill}
+
+		trades := ob.PlaceOrder(&order)
+
+		assert.Equal(t, 0, len(trades))
+		assert.Equal(t, models.StatusCanceled, order.Status)
+		assert.Equal(t, decimal("0.0"), order.FilledAmount)
+		assert.Equal(t, 2, ob.SellLevels.Len(), "the book is left untouched")
+		assert.Equal(t, 0, ob.BuyLevels.Len())
+	})
+
+	t.Run("It fills a FOK order that can be filled completely", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		order := models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Price: decimal("101.0"), Amount: decimal("2.0"), TimeInForce: models.FillOrKill}
+
+		trades := ob.PlaceOrder(&order)
+
+		assert.Equal(t, 2, len(trades))
+		assert.Equal(t, models.StatusFilled, order.Status)
+		assert.Equal(t, 0, ob.SellLevels.Len())
+	})
+
+	t.Run("It expires a GTD order once its time has come", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		expireAt := time.Now().UTC().Add(time.Hour)
+		order := models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Price: decimal("99.0"), Amount: decimal("2.0"), TimeInForce: models.GoodTillDate, ExpireAt: expireAt}
+		ob.PlaceOrder(&order)
+
+		assert.Equal(t, 0, len(ob.ExpireOrders(expireAt.Add(-time.Second))))
+		assert.Equal(t, models.StatusNew, order.Status)
+
+		expired := ob.ExpireOrders(expireAt)
+
+		assert.Equal(t, 1, len(expired))
+		assert.Equal(t, models.StatusExpired, expired[0].Status)
+		assert.Equal(t, 0, ob.BuyLevels.Len())
+		assert.Equal(t, 0, ob.BuyLevels.Len())
+		stored, _ := ob.GetOrder(order.ID)
+		assert.Equal(t, models.StatusExpired, stored.Status)
+	})
+
+	t.Run("It does not expire a GTD order that was canceled", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		expireAt := time.Now().UTC().Add(time.Hour)
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Price: decimal("99.0"), Amount: decimal("2.0"), TimeInForce: models.GoodTillDate, ExpireAt: expireAt})
+		ob.CancelOrder("550e8400-e29b-41d4-a716-446655440000")
+
+		assert.Equal(t, 0, len(ob.ExpireOrders(expireAt)))
+		stored, _ := ob.GetOrder("550e8400-e29b-41d4-a716-446655440000")
+		assert.Equal(t, models.StatusCanceled, stored.Status)
+	})
+
+	t.Run("It expires a DAY order at the session close", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		ob.SessionClose = 16 * time.Hour
+		order := models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Price: decimal("99.0"), Amount: decimal("2.0"), TimeInForce: models.Day}
+		ob.PlaceOrder(&order)
+
+		assert.Equal(t, 16, order.ExpireAt.Hour())
+		assert.True(t, order.ExpireAt.After(order.CreatedAt))
+		assert.True(t, order.ExpireAt.Sub(order.CreatedAt) <= 24*time.Hour)
+
+		expired := ob.ExpireOrders(order.ExpireAt)
+
+		assert.Equal(t, 1, len(expired))
+		assert.Equal(t, models.StatusExpired, expired[0].Status)
+	})
+}
+
+func TestPlaceIcebergOrder(t *testing.T) {
+	t.Parallel()
+
+	newOrderBook := func() *OrderBook {
+		ob := NewOrderBook()
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442000", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("5.0"), DisplayAmount: decimal("2.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442001", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("1.0")})
+		return ob
+	}
+
+	t.Run("It only shows the peak in the order book", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+
+		entries := ob.GetOrderBook(10)
+
+		assert.Equal(t, []models.OrderBookEntry{{Price: decimal("100.0"), Liquidity: decimal("3.0"), Type: models.Sell}}, entries)
+	})
+
+	t.Run("It refreshes the peak and goes to the back of the queue", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+
+		trades := ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("2.5")})
+
+		assert.Equal(t, 2, len(trades))
+		assert.Equal(t, "550e8400-e29b-41d4-a716-77755442000", trades[0].MakerOrderID)
+		assert.Equal(t, decimal("2.0"), trades[0].Amount)
+		assert.Equal(t, "550e8400-e29b-41d4-a716-77755442001", trades[1].MakerOrderID, "the refreshed peak lost its priority")
+		assert.Equal(t, decimal("0.5"), trades[1].Amount)
+
+		iceberg := ob.SellLevels.Orders(decimal("100.0"))[1]
+		assert.Equal(t, "550e8400-e29b-41d4-a716-77755442000", iceberg.ID)
+		assert.Equal(t, decimal("3.0"), iceberg.RemainingAmount)
+		assert.Equal(t, decimal("2.0"), iceberg.VisibleAmount)
+	})
+
+	t.Run("It trades through the reserve of an iceberg order", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+
+		order := models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("6.0"), TimeInForce: models.FillOrKill}
+		trades := ob.PlaceOrder(&order)
+
+		assert.Equal(t, models.StatusFilled, order.Status)
+		assert.Equal(t, []models.Decimal{decimal("2.0"), decimal("1.0"), decimal("2.0"), decimal("1.0")}, []models.Decimal{trades[0].Amount, trades[1].Amount, trades[2].Amount, trades[3].Amount})
+		assert.Equal(t, 0, ob.SellLevels.Len())
+	})
+
+	t.Run("It rests only a peak of what is left after matching", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+
+		order := models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("10.0"), DisplayAmount: decimal("1.5")}
+		ob.PlaceOrder(&order)
+
+		assert.Equal(t, decimal("4.0"), order.RemainingAmount)
+		assert.Equal(t, decimal("1.5"), order.VisibleAmount)
+		assert.Equal(t, []models.OrderBookEntry{{Price: decimal("100.0"), Liquidity: decimal("1.5"), Type: models.Buy}}, ob.GetOrderBook(10))
+	})
+}
+
+func TestPlacePostOnlyOrder(t *testing.T) {
+	t.Parallel()
+
+	newOrderBook := func() *OrderBook {
+		ob := NewOrderBook()
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442000", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("1.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442001", Action: models.Buy, Price: decimal("98.0"), Amount: decimal("1.0")})
+		return ob
+	}
+
+	t.Run("It rests when it does not cross", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		order := models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Price: decimal("99.0"), Amount: decimal("1.0"), PostOnly: models.PostOnlyReject}
+
+		trades := ob.PlaceOrder(&order)
+
+		assert.Equal(t, 0, len(trades))
+		assert.Equal(t, models.StatusNew, order.Status)
+		assert.Equal(t, 1, len(ob.BuyLevels.Orders(decimal("99.0"))))
+	})
+
+	t.Run("It is rejected when it would cross", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		order := models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0"), PostOnly: models.PostOnlyReject}
+
+		trades := ob.PlaceOrder(&order)
+
+		assert.Equal(t, 0, len(trades))
+		assert.Equal(t, models.StatusRejected, order.Status)
+		assert.Equal(t, 1, len(ob.SellLevels.Orders(decimal("100.0"))))
+		assert.Equal(t, 1, ob.BuyLevels.Len())
+	})
+
+	t.Run("It is repriced one tick behind the best opposite price when it would cross", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		order := models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Sell, Price: decimal("97.0"), Amount: decimal("1.0"), PostOnly: models.PostOnlyReprice}
+
+		trades := ob.PlaceOrder(&order)
+
+		assert.Equal(t, 0, len(trades))
+		assert.Equal(t, models.StatusNew, order.Status)
+		assert.Equal(t, decimal("98.01"), order.Price)
+		assert.Equal(t, 1, len(ob.SellLevels.Orders(decimal("98.01"))))
+	})
+
+	t.Run("It is rejected when there is no positive price to reprice it to", func(t *testing.T) {
+		t.Parallel()
+		for _, test := range []struct {
+			resting models.Order
+			order models.Order
+		}{
+			{models.Order{ID: "550e8400-e29b-41d4-a716-77755442000", Action: models.Sell, Price: decimal("0.01"), Amount: decimal("1.0")}, models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Price: decimal("0.01"), Amount: decimal("1.0"), PostOnly: models.PostOnlyReprice}},
+			{models.Order{ID: "550e8400-e29b-41d4-a716-77755442000", Action: models.Buy, Price: models.MaxDecimal, Amount: decimal("1.0")}, models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Sell, Price: models.MaxDecimal, Amount: decimal("1.0"), PostOnly: models.PostOnlyReprice}},
+		} {
+			ob := NewOrderBook()
+			ob.PlaceOrder(&test.resting)
+			order := test.order
+
+			trades := ob.PlaceOrder(&order)
+
+			assert.Equal(t, 0, len(trades))
+			assert.Equal(t, models.StatusRejected, order.Status)
+			assert.Equal(t, ReasonPostOnly, order.RejectReason)
+			assert.Equal(t, 1, ob.BuyLevels.Len()+ob.SellLevels.Len(), "only the resting order is in the book")
+		}
+	})
+}
+
+func TestSelfTradePrevention(t *testing.T) {
+	t.Parallel()
+
+	newOrderBook := func() *OrderBook {
+		ob := NewOrderBook()
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442000", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("1.0"), AccountID: "account-1"})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442001", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("1.0"), AccountID: "account-2"})
+		return ob
+	}
+
+	newOrder := func(amount string, mode models.SelfTradePrevention) models.Order {
+		return models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Price: decimal("100.0"), Amount: decimal(amount), AccountID: "account-1", SelfTradePrevention: mode}
 	}
 
-	heap.Push(&ob.SellPricesHeap, 120.0)
-	heap.Push(&ob.SellPricesHeap, 100.0)
+	t.Run("It cancels the incoming order by default", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		order := newOrder("2.0", "")
+
+		trades := ob.PlaceOrder(&order)
+
+		assert.Equal(t, 0, len(trades))
+		assert.Equal(t, models.CancelNewest, order.SelfTradePrevention)
+		assert.Equal(t, models.StatusCanceled, order.Status)
+		resting, _ := ob.GetOrder("550e8400-e29b-41d4-a716-77755442000")
+		assert.Equal(t, models.StatusNew, resting.Status)
+	})
+
+	t.Run("It cancels the resting order and keeps matching", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		order := newOrder("2.0", models.CancelOldest)
+
+		trades := ob.PlaceOrder(&order)
+
+		assert.Equal(t, 1, len(trades))
+		assert.Equal(t, "550e8400-e29b-41d4-a716-77755442001", trades[0].MakerOrderID)
+		assert.Equal(t, models.StatusPartiallyFilled, order.Status)
+		assert.Equal(t, 1, len(ob.BuyLevels.Orders(decimal("100.0"))), "the remainder rests")
+		resting, _ := ob.GetOrder("550e8400-e29b-41d4-a716-77755442000")
+		assert.Equal(t, models.StatusCanceled, resting.Status)
+	})
+
+	t.Run("It cancels both orders", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		order := newOrder("2.0", models.CancelBoth)
+
+		trades := ob.PlaceOrder(&order)
+
+		assert.Equal(t, 0, len(trades))
+		assert.Equal(t, models.StatusCanceled, order.Status)
+		resting, _ := ob.GetOrder("550e8400-e29b-41d4-a716-77755442000")
+		assert.Equal(t, models.StatusCanceled, resting.Status)
+		assert.Equal(t, 1, len(ob.SellLevels.Orders(decimal("100.0"))))
+	})
+
+	t.Run("It decrements both orders by the smaller amount", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		order := newOrder("1.5", models.Decrement)
+
+		trades := ob.PlaceOrder(&order)
+
+		assert.Equal(t, 1, len(trades))
+		assert.Equal(t, decimal("0.5"), trades[0].Amount)
+		assert.Equal(t, models.StatusFilled, order.Status)
+		assert.Equal(t, decimal("0.5"), order.Amount)
+		resting, _ := ob.GetOrder("550e8400-e29b-41d4-a716-77755442000")
+		assert.Equal(t, models.StatusCanceled, resting.Status)
+	})
+
+	t.Run("It leaves orders of other accounts alone", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		order := models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("2.0"), AccountID: "account-3"}
+
+		trades := ob.PlaceOrder(&order)
+
+		assert.Equal(t, 2, len(trades))
+		assert.Equal(t, models.StatusFilled, order.Status)
+	})
+}
+
+func TestNextSessionClose(t *testing.T) {
+	t.Parallel()
+	sessionClose := 16 * time.Hour
+
+	morning := time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 3, 1, 16, 0, 0, 0, time.UTC), nextSessionClose(morning, sessionClose))
+
+	evening := time.Date(2025, 3, 1, 16, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 3, 2, 16, 0, 0, 0, time.UTC), nextSessionClose(evening, sessionClose))
+}
+
+func TestPlaceStopOrder(t *testing.T) {
+	t.Parallel()
+
+	newOrderBook := func() *OrderBook {
+		ob := NewOrderBook()
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442000", Action: models.Sell, Price: decimal("101.0"), Amount: decimal("1.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442001", Action: models.Sell, Price: decimal("105.0"), Amount: decimal("1.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442002", Action: models.Sell, Price: decimal("110.0"), Amount: decimal("1.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442003", Action: models.Buy, Price: decimal("99.0"), Amount: decimal("1.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442004", Action: models.Buy, Price: decimal("90.0"), Amount: decimal("1.0")})
+		return ob
+	}
+
+	t.Run("It waits in the trigger book until the stop price is reached", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		order := models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Kind: models.Stop, StopPrice: decimal("101.0"), Amount: decimal("1.0"), TriggeredAt: time.Now().UTC()}
+
+		trades := ob.PlaceOrder(&order)
+
+		assert.Equal(t, 0, len(trades))
+		assert.Equal(t, models.StatusNew, order.Status)
+		assert.True(t, order.TriggeredAt.IsZero(), "a trigger time sent with the order is ignored")
+		assert.Equal(t, 2, ob.BuyLevels.Len(), "a pending stop order is not in the book")
+	})
+
+	t.Run("It trades as a market order once triggered", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Kind: models.Stop, StopPrice: decimal("101.0"), Amount: decimal("1.0")})
+
+		trades := ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Action: models.Buy, Price: decimal("101.0"), Amount: decimal("1.0")})
+
+		assert.Equal(t, 2, len(trades))
+		assert.Equal(t, "550e8400-e29b-41d4-a716-446655440001", trades[0].TakerOrderID)
+		assert.Equal(t, "550e8400-e29b-41d4-a716-446655440000", trades[1].TakerOrderID)
+		assert.Equal(t, decimal("105.0"), trades[1].Price)
+
+		stop, _ := ob.GetOrder("550e8400-e29b-41d4-a716-446655440000")
+		assert.Equal(t, models.StatusFilled, stop.Status)
+		assert.False(t, stop.TriggeredAt.IsZero())
+	})
+
+	t.Run("It rests at its limit price once a stop limit order is triggered", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Sell, Kind: models.StopLimit, StopPrice: decimal("99.0"), Price: decimal("95.0"), Amount: decimal("2.0")})
+
+		trades := ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Action: models.Sell, Price: decimal("99.0"), Amount: decimal("1.0")})
+
+		assert.Equal(t, 1, len(trades), "the triggered order does not cross the bid at 90")
+		stop, _ := ob.GetOrder("550e8400-e29b-41d4-a716-446655440000")
+		assert.Equal(t, models.StatusNew, stop.Status)
+		assert.Equal(t, 1, len(ob.SellLevels.Orders(decimal("95.0"))))
+	})
+
+	t.Run("It fires stops triggered by the same trade in the order they were placed", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Kind: models.Stop, StopPrice: decimal("101.0"), Amount: decimal("1.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Action: models.Buy, Kind: models.Stop, StopPrice: decimal("100.0"), Amount: decimal("1.0")})
+
+		trades := ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440002", Action: models.Buy, Price: decimal("101.0"), Amount: decimal("1.0")})
+
+		assert.Equal(t, 3, len(trades))
+		assert.Equal(t, "550e8400-e29b-41d4-a716-446655440000", trades[1].TakerOrderID)
+		assert.Equal(t, decimal("105.0"), trades[1].Price)
+		assert.Equal(t, "550e8400-e29b-41d4-a716-446655440001", trades[2].TakerOrderID)
+		assert.Equal(t, decimal("110.0"), trades[2].Price)
+	})
+
+	t.Run("It fires stops triggered by the trades of other stops", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Kind: models.Stop, StopPrice: decimal("105.0"), Amount: decimal("1.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Action: models.Buy, Kind: models.Stop, StopPrice: decimal("101.0"), Amount: decimal("1.0")})
+
+		trades := ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440002", Action: models.Buy, Price: decimal("101.0"), Amount: decimal("1.0")})
+
+		assert.Equal(t, 3, len(trades))
+		assert.Equal(t, "550e8400-e29b-41d4-a716-446655440001", trades[1].TakerOrderID)
+		assert.Equal(t, "550e8400-e29b-41d4-a716-446655440000", trades[2].TakerOrderID)
+		assert.Equal(t, decimal("110.0"), trades[2].Price)
+	})
+
+	t.Run("It triggers straight away when the last trade price is already past the stop price", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Price: decimal("101.0"), Amount: decimal("1.0")})
+		order := models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Action: models.Buy, Kind: models.Stop, StopPrice: decimal("100.0"), Amount: decimal("1.0")}
+
+		trades := ob.PlaceOrder(&order)
+
+		assert.Equal(t, 1, len(trades))
+		assert.Equal(t, models.StatusFilled, order.Status)
+	})
+
+	t.Run("It cancels a pending stop order but does not amend it", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Kind: models.Stop, StopPrice: decimal("101.0"), Amount: decimal("1.0")})
+		amount := decimal("2.0")
+
+		_, _, err := ob.AmendOrder("550e8400-e29b-41d4-a716-446655440000", models.OrderAmendment{Amount: &amount})
+		assert.ErrorIs(t, err, ErrOrderPendingTrigger)
+
+		canceled, err := ob.CancelOrder("550e8400-e29b-41d4-a716-446655440000")
+		assert.Nil(t, err)
+		assert.Equal(t, models.StatusCanceled, canceled.Status)
+
+		trades := ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Action: models.Buy, Price: decimal("101.0"), Amount: decimal("1.0")})
+		assert.Equal(t, 1, len(trades), "a canceled stop order is never triggered")
+	})
+}
+
+func TestHaltOrderBook(t *testing.T) {
+	t.Parallel()
+
+	newOrderBook := func() *OrderBook {
+		ob := NewOrderBook()
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442000", Action: models.Sell, Price: decimal("101.0"), Amount: decimal("1.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442001", Action: models.Sell, Price: decimal("105.0"), Amount: decimal("1.0")})
+		ob.Halt()
+		return ob
+	}
+
+	t.Run("It queues orders without matching them", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		order := models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Price: decimal("101.0"), Amount: decimal("1.0")}
+
+		trades := ob.PlaceOrder(&order)
+
+		assert.Equal(t, 0, len(trades))
+		assert.Equal(t, models.StatusNew, order.Status)
+		assert.Equal(t, 0, ob.BuyLevels.Len(), "a queued order is not in the book")
+		_, _, err := ob.AmendOrder(order.ID, models.OrderAmendment{Price: &order.Price})
+		assert.Equal(t, ErrOrderQueued, err)
+	})
+
+	t.Run("It matches the queued orders in the order they were placed once resumed", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Kind: models.Stop, StopPrice: decimal("101.0"), Amount: decimal("1.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Action: models.Buy, Price: decimal("101.0"), Amount: decimal("1.0")})
+
+		trades := ob.ResumeAt(time.Now().UTC())
+
+		assert.False(t, ob.Halted())
+		assert.Equal(t, 2, len(trades))
+		assert.Equal(t, "550e8400-e29b-41d4-a716-446655440001", trades[0].TakerOrderID)
+		assert.Equal(t, "550e8400-e29b-41d4-a716-446655440000", trades[1].TakerOrderID, "the stop order was queued before its stop price was reached")
+		assert.Equal(t, decimal("105.0"), trades[1].Price)
+	})
+
+	t.Run("It uncrosses the queued orders at the price that trades the most", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Price: decimal("106.0"), Amount: decimal("2.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Action: models.Sell, Price: decimal("103.0"), Amount: decimal("1.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440002", Action: models.Buy, Price: decimal("102.0"), Amount: decimal("1.0")})
+
+		trades := ob.ResumeAt(time.Now().UTC())
+
+		assert.Equal(t, 2, len(trades))
+		for _, trade := range trades {
+			assert.Equal(t, decimal("103.0"), trade.Price, "2.0 trades at 103, 105 and 106; 103 leaves no surplus")
+			assert.Equal(t, "550e8400-e29b-41d4-a716-446655440000", trade.TakerOrderID)
+		}
+		assert.Equal(t, "550e8400-e29b-41d4-a716-77755442000", trades[0].MakerOrderID, "the cheapest ask trades first")
+		assert.Equal(t, "550e8400-e29b-41d4-a716-446655440001", trades[1].MakerOrderID)
+		assert.Equal(t, decimal("102.0"), ob.BuyLevels.Best().Price)
+		assert.Equal(t, decimal("105.0"), ob.SellLevels.Best().Price)
+	})
+
+	t.Run("It keeps the priority of resting bids that trade in the uncross", func(t *testing.T) {
+		t.Parallel()
+		ob := NewOrderBook()
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442000", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442001", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0")})
+		ob.Halt()
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Action: models.Sell, Price: decimal("99.0"), Amount: decimal("1.5")})
+
+		trades := ob.ResumeAt(time.Now().UTC())
+
+		assert.Equal(t, 2, len(trades))
+		assert.Equal(t, decimal("100.0"), trades[0].Price)
+		assert.Equal(t, "550e8400-e29b-41d4-a716-77755442000", trades[0].TakerOrderID)
+		assert.Equal(t, "550e8400-e29b-41d4-a716-77755442001", trades[1].TakerOrderID)
+		assert.Equal(t, decimal("0.5"), trades[1].Amount)
+		orders := ob.BuyLevels.Orders(decimal("100.0"))
+		assert.Equal(t, 2, len(orders))
+		assert.Equal(t, "550e8400-e29b-41d4-a716-77755442001", orders[0].ID, "the partly filled bid keeps its place")
+		assert.Equal(t, "550e8400-e29b-41d4-a716-446655440000", orders[1].ID)
+		assert.Equal(t, 0, ob.SellLevels.Len())
+	})
+
+	t.Run("It leaves no crossed book when self-trade prevention takes orders out of the uncross", func(t *testing.T) {
+		t.Parallel()
+		ob := NewOrderBook()
+		ob.Halt()
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", AccountID: "a", Action: models.Sell, Price: decimal("99.0"), Amount: decimal("2.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", AccountID: "b", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("2.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440002", AccountID: "c", Action: models.Sell, Price: decimal("102.0"), Amount: decimal("1.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440003", AccountID: "a", Action: models.Buy, Price: decimal("102.0"), Amount: decimal("3.0"), SelfTradePrevention: models.CancelOldest})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440004", AccountID: "d", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("3.0")})
+
+		trades := ob.ResumeAt(time.Now().UTC())
+
+		assert.Equal(t, 2, len(trades))
+		assert.Equal(t, "550e8400-e29b-41d4-a716-446655440001", trades[0].MakerOrderID)
+		assert.Equal(t, decimal("100.0"), trades[0].Price, "the uncross price counted the canceled ask")
+		assert.Equal(t, "550e8400-e29b-41d4-a716-446655440002", trades[1].MakerOrderID)
+		assert.Equal(t, decimal("102.0"), trades[1].Price)
+		order, _ := ob.GetOrder("550e8400-e29b-41d4-a716-446655440000")
+		assert.Equal(t, models.StatusCanceled, order.Status)
+		assert.Equal(t, 0, ob.SellLevels.Len())
+		assert.Equal(t, decimal("100.0"), ob.BuyLevels.Best().Price)
+	})
+
+	t.Run("It expires and cancels queued orders", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+		now := time.Now().UTC()
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Price: decimal("101.0"), Amount: decimal("1.0"), TimeInForce: models.GoodTillDate, ExpireAt: now.Add(time.Minute)})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Action: models.Buy, Price: decimal("101.0"), Amount: decimal("1.0")})
+
+		expired := ob.ExpireOrders(now.Add(time.Minute))
+		_, err := ob.CancelOrder("550e8400-e29b-41d4-a716-446655440001")
+
+		assert.Equal(t, 1, len(expired))
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(ob.ResumeAt(now.Add(time.Minute))))
+	})
+}
+
+func TestCancelOrder(t *testing.T) {
+	t.Parallel()
+
+	t.Run("It removes the order and keeps the rest of the level", func(t *testing.T) {
+		t.Parallel()
+		ob := NewOrderBook()
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442000", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("1.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442001", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("2.0")})
+
+		canceled, err := ob.CancelOrder("550e8400-e29b-41d4-a716-77755442000")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "550e8400-e29b-41d4-a716-77755442000", canceled.ID)
+		assert.Equal(t, 1, len(ob.SellLevels.Orders(decimal("100.0"))))
+		assert.Equal(t, "550e8400-e29b-41d4-a716-77755442001", ob.SellLevels.Orders(decimal("100.0"))[0].ID)
+		assert.Equal(t, 1, ob.SellLevels.Len())
+	})
+
+	t.Run("It removes the price from the heap when the level empties", func(t *testing.T) {
+		t.Parallel()
+		ob := NewOrderBook()
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442000", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442001", Action: models.Buy, Price: decimal("99.0"), Amount: decimal("1.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442002", Action: models.Buy, Price: decimal("98.0"), Amount: decimal("1.0")})
+
+		_, err := ob.CancelOrder("550e8400-e29b-41d4-a716-77755442000")
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, ob.BuyLevels.Len())
+		assert.Equal(t, 2, ob.BuyLevels.Len())
+		assert.Equal(t, decimal("99.0"), ob.BuyLevels.Best().Price)
+	})
+
+	t.Run("It returns an error for an unknown order", func(t *testing.T) {
+		t.Parallel()
+		ob := NewOrderBook()
+
+		_, err := ob.CancelOrder("550e8400-e29b-41d4-a716-77755442000")
+
+		assert.Equal(t, ErrOrderNotFound, err)
+	})
+}
+
+func TestAmendOrder(t *testing.T) {
+	t.Parallel()
+
+	newOrderBook := func() *OrderBook {
+		ob := NewOrderBook()
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442000", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("3.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442001", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("2.0")})
+		ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442002", Action: models.Buy, Price: decimal("95.0"), Amount: decimal("2.0")})
+		return ob
+	}
+	price := func(value string) *models.Decimal {
+		d := decimal(value)
+		return &d
+	}
+	amount := price
+
+	t.Run("It keeps queue priority when the amount is reduced", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+
+		order, trades, err := ob.AmendOrder("550e8400-e29b-41d4-a716-77755442000", models.OrderAmendment{Amount: amount("1.0")})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(trades))
+		assert.Equal(t, models.StatusNew, order.Status)
+		assert.Equal(t, decimal("1.0"), order.Amount)
+		assert.Equal(t, "550e8400-e29b-41d4-a716-77755442000", ob.SellLevels.Orders(decimal("100.0"))[0].ID)
+		assert.Equal(t, decimal("1.0"), ob.SellLevels.Orders(decimal("100.0"))[0].RemainingAmount)
+	})
+
+	t.Run("It loses queue priority when the amount is increased", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+
+		_, _, err := ob.AmendOrder("550e8400-e29b-41d4-a716-77755442000", models.OrderAmendment{Amount: amount("4.0")})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(ob.SellLevels.Orders(decimal("100.0"))))
+		assert.Equal(t, "550e8400-e29b-41d4-a716-77755442001", ob.SellLevels.Orders(decimal("100.0"))[0].ID)
+		assert.Equal(t, "550e8400-e29b-41d4-a716-77755442000", ob.SellLevels.Orders(decimal("100.0"))[1].ID)
+		assert.Equal(t, decimal("4.0"), ob.SellLevels.Orders(decimal("100.0"))[1].RemainingAmount)
+		assert.Equal(t, 1, ob.SellLevels.Len())
+	})
+
+	t.Run("It moves the order to the new price level", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+
+		_, _, err := ob.AmendOrder("550e8400-e29b-41d4-a716-77755442002", models.OrderAmendment{Price: price("96.0")})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(ob.BuyLevels.Orders(decimal("95.0"))))
+		assert.Equal(t, 1, len(ob.BuyLevels.Orders(decimal("96.0"))))
+		assert.Equal(t, 1, ob.BuyLevels.Len())
+		assert.Equal(t, decimal("96.0"), ob.BuyLevels.Best().Price)
+	})
+
+	t.Run("It matches an order repriced across the spread", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+
+		order, trades, err := ob.AmendOrder("550e8400-e29b-41d4-a716-77755442002", models.OrderAmendment{Price: price("100.0"), Amount: amount("4.0")})
+
+		assert.Nil(t, err)
+		assert.Equal(t, models.StatusFilled, order.Status)
+		assert.Equal(t, decimal("4.0"), order.FilledAmount)
+		assert.Equal(t, []models.Trade{
+			{ID: 1, MakerOrderID: "550e8400-e29b-41d4-a716-77755442000", TakerOrderID: "550e8400-e29b-41d4-a716-77755442002", Price: decimal("100.0"), Amount: decimal("3.0"), AggressorSide: models.Buy, Sequence: 4},
+			{ID: 2, MakerOrderID: "550e8400-e29b-41d4-a716-77755442001", TakerOrderID: "550e8400-e29b-41d4-a716-77755442002", Price: decimal("100.0"), Amount: decimal("1.0"), AggressorSide: models.Buy, Sequence: 4},
+		}, withoutTimestamps(trades))
+		assert.Equal(t, 0, ob.BuyLevels.Len())
+		assert.Equal(t, 0, ob.BuyLevels.Len())
+		assert.Equal(t, decimal("1.0"), ob.SellLevels.Orders(decimal("100.0"))[0].RemainingAmount)
+	})
+
+	t.Run("It returns an error for an unknown order", func(t *testing.T) {
+		t.Parallel()
+		ob := newOrderBook()
+
+		_, _, err := ob.AmendOrder("550e8400-e29b-41d4-a716-77755442009", models.OrderAmendment{Amount: amount("1.0")})
+
+		assert.Equal(t, ErrOrderNotFound, err)
+	})
+}
+
+func TestGetOrder(t *testing.T) {
+	t.Parallel()
+	ob := NewOrderBook()
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442000", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("2.0")})
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442001", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("2.0")})
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442002", Action: models.Sell, Price: decimal("101.0"), Amount: decimal("2.0")})
+
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("3.0")})
+	ob.CancelOrder("550e8400-e29b-41d4-a716-77755442002")
+
+	order, exists := ob.GetOrder("550e8400-e29b-41d4-a716-77755442000")
+	assert.True(t, exists, "orders that left the book can still be looked up")
+	assert.Equal(t, models.StatusFilled, order.Status)
+
+	order, _ = ob.GetOrder("550e8400-e29b-41d4-a716-77755442001")
+	assert.Equal(t, models.StatusPartiallyFilled, order.Status)
+	assert.Equal(t, decimal("2.0"), order.Amount)
+	assert.Equal(t, decimal("1.0"), order.FilledAmount)
+	assert.Equal(t, decimal("1.0"), order.RemainingAmount)
+
+	order, _ = ob.GetOrder("550e8400-e29b-41d4-a716-77755442002")
+	assert.Equal(t, models.StatusCanceled, order.Status)
+	assert.Equal(t, decimal("2.0"), order.RemainingAmount)
+
+	order, _ = ob.GetOrder("550e8400-e29b-41d4-a716-446655440000")
+	assert.Equal(t, models.StatusFilled, order.Status)
+	assert.Equal(t, decimal("3.0"), order.FilledAmount)
+
+	_, exists = ob.GetOrder("550e8400-e29b-41d4-a716-999955440000")
+	assert.False(t, exists)
+}
+
+func TestGetOrderList(t *testing.T) {
+	t.Parallel()
+	ob := NewOrderBook()
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442000", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("2.0")})
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442001", Action: models.Sell, Price: decimal("101.0"), Amount: decimal("2.0")})
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("2.0")})
+
+	orders := ob.GetOrderList(1, 10)
+
+	assert.Equal(t, 3, len(orders))
+	assert.Equal(t, "550e8400-e29b-41d4-a716-77755442000", orders[0].ID)
+	assert.Equal(t, models.StatusFilled, orders[0].Status)
+	assert.Equal(t, models.StatusNew, orders[1].Status)
+	assert.Equal(t, models.StatusFilled, orders[2].Status)
+
+	assert.Equal(t, 1, len(ob.GetOrderList(2, 2)))
+	assert.Equal(t, 0, len(ob.GetOrderList(3, 2)))
+}
+
+func TestCancelOrder_WhenOrderIsNoLongerOpen(t *testing.T) {
+	t.Parallel()
+	ob := NewOrderBook()
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442000", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("2.0")})
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("2.0")})
+
+	_, err := ob.CancelOrder("550e8400-e29b-41d4-a716-77755442000")
+
+	assert.Equal(t, ErrOrderNotOpen, err)
+}
+
+func TestGetOrderBook(t *testing.T) {
+	t.Parallel()
+	ob := NewOrderBook()
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-666655442000", Action: models.Sell, Price: decimal("120.0"), Amount: decimal("2.0")})
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442000", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("2.0")})
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755442001", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("2.0")})
+
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-666655443000", Action: models.Buy, Price: decimal("80.0"), Amount: decimal("2.0")})
+	ob.PlaceOrder(&models.Order{ID: "550e8400-e29b-41d4-a716-77755443000", Action: models.Buy, Price: decimal("90.0"), Amount: decimal("2.0")})
 
 	orderbook := ob.GetOrderBook(2)
 
 	expected := []models.OrderBookEntry{
 		{
-			Price: 120.0,
+			Price: decimal("120.0"),
 			Type: models.Sell,
-			Liquidity: 2.0,
+			Liquidity: decimal("2.0"),
 		},
 		{
-			Price: 100.0,
+			Price: decimal("100.0"),
 			Type: models.Sell,
-			Liquidity: 4.0,
+			Liquidity: decimal("4.0"),
 		},
 		{
-			Price: 100.0,
+			Price: decimal("90.0"),
 			Type: models.Buy,
-			Liquidity: 2.0,
+			Liquidity: decimal("2.0"),
 		},
 		{
-			Price: 80.0,
+			Price: decimal("80.0"),
 			Type: models.Buy,
-			Liquidity: 2.0,
+			Liquidity: decimal("2.0"),
 		},
 	}
 
 	assert.Equal(t, expected, orderbook)
-}
\ No newline at end of file
+}
+
+// withoutTimestamps zeroes the wall-clock timestamps of trades so they can be
+// compared as plain values.
+func withoutTimestamps(trades []models.Trade) []models.Trade {
+	for i := range trades {
+		trades[i].Timestamp = time.Time{}
+	}
+
+	return trades
+}
+
+// decimal keeps the test fixtures readable.
+func decimal(value string) models.Decimal {
+	return models.MustParseDecimal(value)
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"order-matching/models"
+)
+
+// Reasons given with the orders the risk checks refused, as the reject reason
+// of the order and the reason of its ORDER_REJECTED event.
+const ReasonMaxQuantity = "MAX_QUANTITY"
+const ReasonMaxNotional = "MAX_NOTIONAL"
+const ReasonFatFinger = "FAT_FINGER"
+const ReasonPriceCollar = "PRICE_COLLAR"
+const ReasonMaxOpenOrders = "MAX_OPEN_ORDERS"
+const ReasonMaxPosition = "MAX_POSITION"
+
+// RiskError is returned for an order or an amendment the risk checks refused.
+type RiskError struct {
+	Reason string // one of the Reason codes of the risk checks
+}
+
+func (e *RiskError) Error() string {
+	return "refused by the risk checks: " + e.Reason
+}
+
+// RiskLimits are the limits every order is checked against before it reaches
+// the order book. A limit left at zero is not checked.
+type RiskLimits struct {
+	MaxOrderQuantity models.Decimal // the largest amount of an order
+	MaxOrderNotional models.Decimal // the largest amount of an order times its price, in the quote asset
+	// How far an order may trade through the best opposite price, as a
+	// fraction of it, e.g. 0.05 for 5%.
+	FatFinger models.Decimal
+	// How far the price of an order may be from the last trade price, or the
+	// mid price before the first trade, as a fraction of it.
+	PriceCollar models.Decimal
+	MaxOpenOrders int // the most open orders of an account, across markets
+	// The largest net amount an account may have bought, or sold, in a
+	// market, counting its open orders on the side of the order as filled.
+	MaxPosition models.Decimal
+}
+
+// Risk checks orders against the risk limits, and keeps what the checks need
+// to know about the accounts: their net position in every market, from the
+// trades of their orders, and their open orders. Orders without an account are
+// only checked against the limits of the order itself.
+//
+// Only the sequencer uses it, as part of the commands it applies.
+type Risk struct {
+	limits RiskLimits
+	positions map[string]map[string]models.Decimal // net amount bought, by account ID then symbol
+	open map[string]openOrder // every open order of an account, by order ID
+	openOrders map[string]int // the number of open orders, by account ID
+	exposure map[exposureKey]models.Decimal // the remaining amount of the open orders of an account on a side of a market
+}
+
+type openOrder struct {
+	accountID string
+	symbol string
+	action models.OrderType
+	remaining models.Decimal
+}
+
+type exposureKey struct {
+	accountID string
+	symbol string
+	action models.OrderType
+}
+
+func NewRisk() *Risk {
+	return &Risk{
+		positions: make(map[string]map[string]models.Decimal),
+		open: make(map[string]openOrder),
+		openOrders: make(map[string]int),
+		exposure: make(map[exposureKey]models.Decimal),
+	}
+}
+
+// check returns the reason of the first risk check an order of the market
+// fails, or an empty string if it passes them all. remaining is what is left
+// of the order to trade: all of a new order, or the new remaining amount of an
+// amended one, which stands in for the one it had.
+func (r *Risk) check(symbol string, book *OrderBook, order models.Order, remaining models.Decimal) string {
+	limits := r.limits
+
+	if limits.MaxOrderQuantity > 0 && order.Amount > limits.MaxOrderQuantity {
+		return ReasonMaxQuantity
+	}
+
+	// the furthest price the order would trade at right now; stop orders do
+	// not trade before they are triggered
+	var reached models.Decimal
+	if !order.IsStop() {
+		reached = book.reachPrice(&order, remaining)
+	}
+
+	if limits.MaxOrderNotional > 0 {
+		price := order.Price
+		if price == 0 {
+			price = max(order.StopPrice, reached)
+		}
+		if notional, err := price.Mul(order.Amount); err != nil || notional > limits.MaxOrderNotional {
+			return ReasonMaxNotional
+		}
+	}
+
+	if limits.FatFinger > 0 && reached != 0 {
+		best := book.oppositeLevels(order.Action).Best().Price
+		if beyond(reached, best, limits.FatFinger) {
+			return ReasonFatFinger
+		}
+	}
+
+	if limits.PriceCollar > 0 && order.Price != 0 {
+		if reference := referencePrice(book); reference != 0 && beyond(order.Price, reference, limits.PriceCollar) {
+			return ReasonPriceCollar
+		}
+	}
+
+	if order.AccountID == "" {
+		return ""
+	}
+	current, amended := r.open[order.ID]
+
+	if limits.MaxOpenOrders > 0 && !amended && r.openOrders[order.AccountID] >= limits.MaxOpenOrders {
+		return ReasonMaxOpenOrders
+	}
+
+	if limits.MaxPosition > 0 {
+		pending := r.exposure[exposureKey{accountID: order.AccountID, symbol: symbol, action: order.Action}] + remaining
+		if amended {
+			pending -= current.remaining
+		}
+		position := r.positions[order.AccountID][symbol]
+		if order.Action == models.Sell {
+			position = -position
+		}
+		if position > models.MaxDecimal-pending || position+pending > limits.MaxPosition {
+			return ReasonMaxPosition
+		}
+	}
+
+	return ""
+}
+
+// referencePrice is the price the collar is measured from: the last trade
+// price, or the mid price until the market trades. It is 0 while the book is
+// empty on either side and nothing has traded.
+func referencePrice(book *OrderBook) models.Decimal {
+	if book.lastTradePrice != 0 {
+		return book.lastTradePrice
+	}
+
+	bid, ask := book.BuyLevels.Best(), book.SellLevels.Best()
+	if bid == nil || ask == nil {
+		return 0
+	}
+
+	return bid.Price + (ask.Price-bid.Price)/2
+}
+
+// beyond reports whether price is further from reference than the fraction
+// of it.
+func beyond(price models.Decimal, reference models.Decimal, fraction models.Decimal) bool {
+	allowed, err := reference.Mul(fraction)
+	if err != nil {
+		return false
+	}
+
+	return price > reference+allowed || price < reference-allowed
+}
+
+// record moves the positions of the accounts by the trades of a command in
+// a market, and keeps track of the open orders among the orders it changed.
+// Restoring a market records all its trades and orders at once.
+func (r *Risk) record(symbol string, trades []models.Trade, changes []models.Order) {
+	accounts := make(map[string]string, len(changes))
+	for _, order := range changes {
+		accounts[order.ID] = order.AccountID
+	}
+
+	for _, trade := range trades {
+		buyer, seller := accounts[trade.TakerOrderID], accounts[trade.MakerOrderID]
+		if trade.AggressorSide == models.Sell {
+			buyer, seller = seller, buyer
+		}
+		r.move(buyer, symbol, trade.Amount)
+		r.move(seller, symbol, -trade.Amount)
+	}
+
+	for _, order := range changes {
+		r.track(symbol, order)
+	}
+}
+
+// move changes the net position of an account in a market.
+func (r *Risk) move(accountID string, symbol string, amount models.Decimal) {
+	if accountID == "" {
+		return
+	}
+
+	positions, exists := r.positions[accountID]
+	if !exists {
+		positions = make(map[string]models.Decimal)
+		r.positions[accountID] = positions
+	}
+	positions[symbol] += amount
+}
+
+// track updates the open orders of an account with an order as it is now.
+func (r *Risk) track(symbol string, order models.Order) {
+	if order.AccountID == "" {
+		return
+	}
+
+	if current, exists := r.open[order.ID]; exists {
+		key := exposureKey{accountID: current.accountID, symbol: current.symbol, action: current.action}
+		r.exposure[key] -= current.remaining
+		if r.exposure[key] == 0 {
+			delete(r.exposure, key)
+		}
+		r.openOrders[current.accountID]--
+		if r.openOrders[current.accountID] == 0 {
+			delete(r.openOrders, current.accountID)
+		}
+		delete(r.open, order.ID)
+	}
+
+	if !order.IsOpen() {
+		return
+	}
+	r.open[order.ID] = openOrder{accountID: order.AccountID, symbol: symbol, action: order.Action, remaining: order.RemainingAmount}
+	r.openOrders[order.AccountID]++
+	r.exposure[exposureKey{accountID: order.AccountID, symbol: symbol, action: order.Action}] += order.RemainingAmount
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"order-matching/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRisk(t *testing.T) {
+	t.Parallel()
+
+	// newMarket lists BTC-USD with a bid at 99 and an ask at 101, from the
+	// account "market-maker", under the limits
+	newMarket := func(limits RiskLimits) (*BookManager, *Market) {
+		bm := NewBookManager(0)
+		bm.CreateInstrument(models.Instrument{Symbol: "BTC-USD", BaseAsset: "BTC", QuoteAsset: "USD", TickSize: decimal("0.01"), LotSize: decimal("0.01")})
+		for _, accountID := range []string{"market-maker", "trader"} {
+			bm.Deposit(models.Transfer{AccountID: accountID, Asset: "BTC", Amount: decimal("100.0")})
+			bm.Deposit(models.Transfer{AccountID: accountID, Asset: "USD", Amount: decimal("100000.0")})
+		}
+		market, _ := bm.Market("BTC-USD")
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", AccountID: "market-maker", Action: models.Buy, Price: decimal("99.0"), Amount: decimal("5.0")})
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", AccountID: "market-maker", Action: models.Sell, Price: decimal("101.0"), Amount: decimal("1.0")})
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440002", AccountID: "market-maker", Action: models.Sell, Price: decimal("120.0"), Amount: decimal("5.0")})
+		bm.SetRiskLimits(limits)
+		return bm, market
+	}
+
+	tests := []struct {
+		name string
+		limits RiskLimits
+		order models.Order
+		reason string
+	}{
+		{"It rejects an order above the max quantity", RiskLimits{MaxOrderQuantity: decimal("10.0")}, models.Order{Action: models.Buy, Price: decimal("100.0"), Amount: decimal("10.01")}, ReasonMaxQuantity},
+		{"It rejects an order above the max notional", RiskLimits{MaxOrderNotional: decimal("1000.0")}, models.Order{Action: models.Buy, Price: decimal("100.0"), Amount: decimal("10.01")}, ReasonMaxNotional},
+		{"It rejects a market order above the max notional at the price it reaches", RiskLimits{MaxOrderNotional: decimal("1000.0")}, models.Order{Action: models.Buy, Kind: models.Market, Amount: decimal("9.0")}, ReasonMaxNotional},
+		{"It rejects an order that trades too far through the best opposite price", RiskLimits{FatFinger: decimal("0.1")}, models.Order{Action: models.Buy, Kind: models.Market, Amount: decimal("2.0")}, ReasonFatFinger},
+		{"It rejects an order priced outside the collar around the mid price", RiskLimits{PriceCollar: decimal("0.1")}, models.Order{Action: models.Buy, Price: decimal("89.0"), Amount: decimal("1.0")}, ReasonPriceCollar},
+		{"It rejects an order beyond the max position", RiskLimits{MaxPosition: decimal("3.0")}, models.Order{Action: models.Sell, Price: decimal("110.0"), Amount: decimal("3.01")}, ReasonMaxPosition},
+		{"It accepts an order within every limit", RiskLimits{MaxOrderQuantity: decimal("10.0"), MaxOrderNotional: decimal("1000.0"), FatFinger: decimal("0.1"), PriceCollar: decimal("0.1"), MaxOpenOrders: 1, MaxPosition: decimal("3.0")}, models.Order{Action: models.Buy, Kind: models.Market, Amount: decimal("1.0")}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			bm, market := newMarket(test.limits)
+			order := test.order
+			order.ID = "550e8400-e29b-41d4-a716-446655440010"
+			order.AccountID = "trader"
+
+			trades, err := bm.PlaceOrder(market, &order)
+
+			if test.reason == "" {
+				assert.Nil(t, err)
+				assert.Equal(t, 1, len(trades))
+				return
+			}
+			assert.Equal(t, &RiskError{Reason: test.reason}, err)
+			assert.Nil(t, trades)
+			recorded, _ := market.OrderHistory.GetOrder(order.ID)
+			assert.Equal(t, models.StatusRejected, recorded.Status)
+			assert.Equal(t, test.reason, recorded.RejectReason)
+			assert.Equal(t, models.Balance{Asset: "USD", Available: decimal("100000.0")}, bm.Accounts().Balances("trader")[1], "a rejected order holds nothing")
+		})
+	}
+
+	t.Run("It measures the collar from the last trade price", func(t *testing.T) {
+		t.Parallel()
+		bm, market := newMarket(RiskLimits{PriceCollar: decimal("0.1")})
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440010", AccountID: "trader", Action: models.Sell, Price: decimal("99.0"), Amount: decimal("1.0")})
+
+		_, err := bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440011", AccountID: "trader", Action: models.Sell, Price: decimal("109.0"), Amount: decimal("1.0")})
+		assert.Equal(t, &RiskError{Reason: ReasonPriceCollar}, err)
+		_, err = bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440012", AccountID: "trader", Action: models.Sell, Price: decimal("108.9"), Amount: decimal("1.0")})
+		assert.Nil(t, err)
+	})
+
+	t.Run("It counts the open orders of an account", func(t *testing.T) {
+		t.Parallel()
+		bm, market := newMarket(RiskLimits{MaxOpenOrders: 3})
+
+		_, err := bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440010", AccountID: "market-maker", Action: models.Buy, Price: decimal("98.0"), Amount: decimal("1.0")})
+		assert.Equal(t, &RiskError{Reason: ReasonMaxOpenOrders}, err)
+
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440011", AccountID: "trader", Action: models.Buy, Price: decimal("101.0"), Amount: decimal("1.0")})
+		_, err = bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440012", AccountID: "market-maker", Action: models.Buy, Price: decimal("98.0"), Amount: decimal("1.0")})
+		assert.Nil(t, err, "the filled order is no longer open")
+	})
+
+	t.Run("It counts the fills and open orders of an account in its position", func(t *testing.T) {
+		t.Parallel()
+		bm, market := newMarket(RiskLimits{MaxPosition: decimal("3.0")})
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440010", AccountID: "trader", Action: models.Buy, Price: decimal("101.0"), Amount: decimal("1.0")})
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440011", AccountID: "trader", Action: models.Buy, Price: decimal("98.0"), Amount: decimal("1.0")})
+
+		_, err := bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440012", AccountID: "trader", Action: models.Buy, Price: decimal("98.0"), Amount: decimal("1.01")})
+		assert.Equal(t, &RiskError{Reason: ReasonMaxPosition}, err)
+		_, err = bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440013", AccountID: "trader", Action: models.Sell, Price: decimal("110.0"), Amount: decimal("4.0")})
+		assert.Nil(t, err, "selling reduces the long position")
+	})
+
+	t.Run("It refuses an amendment that fails the risk checks and leaves the order as it was", func(t *testing.T) {
+		t.Parallel()
+		bm, market := newMarket(RiskLimits{MaxOrderQuantity: decimal("5.0")})
+		amount := decimal("5.01")
+
+		_, _, err := bm.AmendOrder(market, "550e8400-e29b-41d4-a716-446655440000", models.OrderAmendment{Amount: &amount})
+
+		assert.Equal(t, &RiskError{Reason: ReasonMaxQuantity}, err)
+		order, _ := market.OrderBook.GetOrder("550e8400-e29b-41d4-a716-446655440000")
+		assert.Equal(t, decimal("5.0"), order.RemainingAmount)
+	})
+
+	t.Run("It keeps journaled rejections whatever the limits on replay", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		journal, entries, _ := OpenJournal(dir, 0)
+		bm := NewBookManager(0)
+		bm.Recover(journal, entries)
+		bm.CreateInstrument(models.Instrument{Symbol: "BTC-USD", BaseAsset: "BTC", QuoteAsset: "USD", TickSize: decimal("0.01"), LotSize: decimal("0.01")})
+		market, _ := bm.Market("BTC-USD")
+		bm.SetRiskLimits(RiskLimits{MaxOrderQuantity: decimal("1.0")})
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("2.0")})
+		journal.Close()
+
+		journal, entries, _ = OpenJournal(dir, 0)
+		defer journal.Close()
+		recovered := NewBookManager(0)
+		assert.Nil(t, recovered.Recover(journal, entries))
+		recoveredMarket, _ := recovered.Market("BTC-USD")
+
+		order, _ := recoveredMarket.OrderHistory.GetOrder("550e8400-e29b-41d4-a716-446655440000")
+		assert.Equal(t, models.StatusRejected, order.Status)
+		assert.Equal(t, ReasonMaxQuantity, order.RejectReason)
+		assert.Equal(t, 0, recoveredMarket.OrderBook.BuyLevels.Len())
+	})
+
+	t.Run("It rebuilds positions and open orders from a snapshot", func(t *testing.T) {
+		t.Parallel()
+		bm, market := newMarket(RiskLimits{})
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440010", AccountID: "trader", Action: models.Buy, Price: decimal("101.0"), Amount: decimal("1.0")})
+
+		restored := NewBookManager(0)
+		assert.Nil(t, restored.Restore(bm.Snapshot()))
+
+		assert.Equal(t, bm.risk.positions, restored.risk.positions)
+		assert.Equal(t, bm.risk.open, restored.risk.open)
+		assert.Equal(t, bm.risk.openOrders, restored.risk.openOrders)
+		assert.Equal(t, bm.risk.exposure, restored.risk.exposure)
+	})
+}
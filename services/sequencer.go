@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"order-matching/models"
+	"time"
+)
+
+var (
+	ErrSequencerBusy = errors.New("too many commands are waiting to be applied")
+	ErrSequencerStopped = errors.New("sequencer has stopped")
+)
+
+// maxBatch bounds the number of commands applied before the depth of the
+// order books is published and their callers are answered.
+const maxBatch = 256
+
+// Sequencer is the single writer of the markets. One goroutine takes commands
+// from a bounded queue and applies them one after the other, so the order
+// books need no locks and every command sees the result of the one before.
+// Once the commands waiting in the queue, up to maxBatch, are applied, it
+// publishes the depth of the order books they changed and only then answers
+// their callers, so a client always reads its own writes.
+//
+// Readers never touch the order books: they use the order and trade histories
+// of a market and its published depth.
+type Sequencer struct {
+	markets *BookManager
+	commands chan sequencedCommand
+	stopped chan struct{}
+}
+
+type sequencedCommand struct {
+	run func()
+	done chan struct{}
+}
+
+// NewSequencer returns a sequencer for the markets whose queue holds up to
+// queueSize commands. Until Run is called, nothing is applied.
+func NewSequencer(markets *BookManager, queueSize int) *Sequencer {
+	return &Sequencer{
+		markets: markets,
+		commands: make(chan sequencedCommand, queueSize),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Markets returns the markets the sequencer writes to, for reading.
+func (s *Sequencer) Markets() *BookManager {
+	return s.markets
+}
+
+// Done is closed once Run has returned.
+func (s *Sequencer) Done() <-chan struct{} {
+	return s.stopped
+}
+
+// Run applies commands until ctx is done. Commands still in the queue then
+// fail with ErrSequencerStopped.
+func (s *Sequencer) Run(ctx context.Context) {
+	defer close(s.stopped)
+
+	batch := make([]sequencedCommand, 0, maxBatch)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case command := <-s.commands:
+			batch = append(batch[:0], command)
+		}
+
+	drain:
+		for len(batch) < maxBatch {
+			select {
+			case command := <-s.commands:
+				batch = append(batch, command)
+			default:
+				break drain
+			}
+		}
+
+		for _, command := range batch {
+			command.run()
+		}
+		s.markets.publishDepth()
+		for _, command := range batch {
+			close(command.done)
+		}
+	}
+}
+
+// Execute runs fn on the sequencer's goroutine, with the order books to
+// itself, and waits until it has run and the depth it changed is published. A
+// full queue fails the command with ErrSequencerBusy rather than holding up
+// the caller.
+func (s *Sequencer) Execute(fn func()) error {
+	command := sequencedCommand{run: fn, done: make(chan struct{})}
+
+	select {
+	case <-s.stopped:
+		return ErrSequencerStopped
+	case s.commands <- command:
+	default:
+		return ErrSequencerBusy
+	}
+
+	select {
+	case <-command.done:
+		return nil
+	case <-s.stopped:
+		select {
+		case <-command.done:
+			return nil
+		default:
+			return ErrSequencerStopped
+		}
+	}
+}
+
+// CreateInstrument lists a new instrument. See BookManager.CreateInstrument.
+func (s *Sequencer) CreateInstrument(instrument models.Instrument) (created models.Instrument, err error) {
+	if executeErr := s.Execute(func() {
+		created, err = s.markets.CreateInstrument(instrument)
+	}); executeErr != nil {
+		return models.Instrument{}, executeErr
+	}
+
+	return created, err
+}
+
+// PlaceOrder places an order in the order book of the market. See
+// BookManager.PlaceOrder.
+func (s *Sequencer) PlaceOrder(market *Market, order *models.Order) (trades []models.Trade, err error) {
+	if executeErr := s.Execute(func() {
+		trades, err = s.markets.PlaceOrder(market, order)
+	}); executeErr != nil {
+		return nil, executeErr
+	}
+
+	return trades, err
+}
+
+// CancelOrder cancels an order in the order book of the market. See
+// BookManager.CancelOrder.
+func (s *Sequencer) CancelOrder(market *Market, id string) (order models.Order, err error) {
+	if executeErr := s.Execute(func() {
+		order, err = s.markets.CancelOrder(market, id)
+	}); executeErr != nil {
+		return models.Order{}, executeErr
+	}
+
+	return order, err
+}
+
+// AmendOrder amends an order in the order book of the market. See
+// BookManager.AmendOrder.
+func (s *Sequencer) AmendOrder(market *Market, id string, amendment models.OrderAmendment) (order models.Order, trades []models.Trade, err error) {
+	if executeErr := s.Execute(func() {
+		order, trades, err = s.markets.AmendOrder(market, id, amendment)
+	}); executeErr != nil {
+		return models.Order{}, nil, executeErr
+	}
+
+	return order, trades, err
+}
+
+// Deposit adds funds to an account. See BookManager.Deposit.
+func (s *Sequencer) Deposit(transfer models.Transfer) (balance models.Balance, err error) {
+	if executeErr := s.Execute(func() {
+		balance, err = s.markets.Deposit(transfer)
+	}); executeErr != nil {
+		return models.Balance{}, executeErr
+	}
+
+	return balance, err
+}
+
+// Withdraw takes funds out of an account. See BookManager.Withdraw.
+func (s *Sequencer) Withdraw(transfer models.Transfer) (balance models.Balance, err error) {
+	if executeErr := s.Execute(func() {
+		balance, err = s.markets.Withdraw(transfer)
+	}); executeErr != nil {
+		return models.Balance{}, executeErr
+	}
+
+	return balance, err
+}
+
+// Halt stops matching in the market. See BookManager.Halt.
+func (s *Sequencer) Halt(market *Market) (state models.MarketState, err error) {
+	if executeErr := s.Execute(func() {
+		state, err = s.markets.Halt(market)
+	}); executeErr != nil {
+		return models.MarketState{}, executeErr
+	}
+
+	return state, err
+}
+
+// Resume reopens the market. See BookManager.Resume.
+func (s *Sequencer) Resume(market *Market) (state models.MarketState, err error) {
+	if executeErr := s.Execute(func() {
+		state, err = s.markets.Resume(market)
+	}); executeErr != nil {
+		return models.MarketState{}, executeErr
+	}
+
+	return state, err
+}
+
+// Close stops the market taking new orders. See BookManager.Close.
+func (s *Sequencer) Close(market *Market) (state models.MarketState, err error) {
+	if executeErr := s.Execute(func() {
+		state, err = s.markets.Close(market)
+	}); executeErr != nil {
+		return models.MarketState{}, executeErr
+	}
+
+	return state, err
+}
+
+// ReopenMarkets moves on the markets whose halt or auction is over. See
+// BookManager.ReopenMarkets.
+func (s *Sequencer) ReopenMarkets(now time.Time) error {
+	return s.Execute(func() {
+		s.markets.ReopenMarkets(now)
+	})
+}
+
+// ExpireOrders expires the due orders of every market. See
+// BookManager.ExpireOrders.
+func (s *Sequencer) ExpireOrders(now time.Time) (expired []models.Order, err error) {
+	err = s.Execute(func() {
+		expired = s.markets.ExpireOrders(now)
+	})
+
+	return expired, err
+}
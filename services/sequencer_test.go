@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"order-matching/models"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// startSequencer runs a sequencer for the markets until the test ends.
+func startSequencer(t *testing.T, markets *BookManager) *Sequencer {
+	ctx, cancel := context.WithCancel(context.Background())
+	sequencer := NewSequencer(markets, 1024)
+	go sequencer.Run(ctx)
+	t.Cleanup(func() {
+		cancel()
+		<-sequencer.Done()
+	})
+
+	return sequencer
+}
+
+func TestSequencer(t *testing.T) {
+	t.Parallel()
+
+	newMarkets := func() *BookManager {
+		markets := NewBookManager(0)
+		markets.CreateInstrument(models.Instrument{Symbol: "BTC-USD", BaseAsset: "BTC", QuoteAsset: "USD", TickSize: decimal("0.01"), LotSize: decimal("0.01")})
+		return markets
+	}
+
+	t.Run("It publishes the depth before answering", func(t *testing.T) {
+		t.Parallel()
+		sequencer := startSequencer(t, newMarkets())
+		market, _ := sequencer.Markets().Market("BTC-USD")
+
+		_, err := sequencer.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Symbol: "BTC-USD", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("1.0")})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []models.OrderBookEntry{{Price: decimal("100.0"), Liquidity: decimal("1.0"), Type: models.Sell}}, market.GetOrderBook(10))
+		order, found := market.OrderHistory.GetOrder("550e8400-e29b-41d4-a716-446655440000")
+		assert.True(t, found)
+		assert.Equal(t, models.StatusNew, order.Status)
+	})
+
+	t.Run("It refuses commands when the queue is full", func(t *testing.T) {
+		t.Parallel()
+		sequencer := NewSequencer(newMarkets(), 1)
+		market, _ := sequencer.Markets().Market("BTC-USD")
+
+		// nothing runs the queue, so this command waits in it for good
+		sequencer.commands <- sequencedCommand{run: func() {}, done: make(chan struct{})}
+
+		_, err := sequencer.CancelOrder(market, "550e8400-e29b-41d4-a716-446655440000")
+
+		assert.ErrorIs(t, err, ErrSequencerBusy)
+	})
+
+	t.Run("It refuses commands once it has stopped", func(t *testing.T) {
+		t.Parallel()
+		sequencer := NewSequencer(newMarkets(), 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		sequencer.Run(ctx)
+		market, _ := sequencer.Markets().Market("BTC-USD")
+
+		_, err := sequencer.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Symbol: "BTC-USD", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("1.0")})
+
+		assert.ErrorIs(t, err, ErrSequencerStopped)
+	})
+
+	t.Run("It keeps the order book consistent under concurrent writers and readers", func(t *testing.T) {
+		t.Parallel()
+		markets := newMarkets()
+		sequencer := startSequencer(t, markets)
+		market, _ := markets.Market("BTC-USD")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go NewExpirySweeper(sequencer, time.Millisecond).Run(ctx)
+
+		var readers sync.WaitGroup
+		for reader := 0; reader < 4; reader++ {
+			readers.Add(1)
+			go func() {
+				defer readers.Done()
+				for ctx.Err() == nil {
+					for _, entry := range market.GetOrderBook(10) {
+						assert.Greater(t, entry.Liquidity, models.Decimal(0))
+					}
+					market.OrderHistory.GetOrderList(1, 50)
+					market.TradeHistory.GetTradeList(time.Time{}, time.Time{}, 1, 50)
+				}
+			}()
+		}
+
+		var writers sync.WaitGroup
+		for writer := 0; writer < 8; writer++ {
+			writers.Add(1)
+			go func() {
+				defer writers.Done()
+				for index := 0; index < 100; index++ {
+					id := fmt.Sprintf("00000000-0000-4000-8000-%04d%08d", writer, index)
+					order := &models.Order{
+						ID:     id,
+						Symbol: "BTC-USD",
+						Action: []models.OrderType{models.Buy, models.Sell}[index%2],
+						Price:  models.Decimal(int64(99+index%3) * 1e8),
+						Amount: decimal("1.0"),
+					}
+					if index%5 == 0 {
+						order.TimeInForce = models.GoodTillDate
+						order.ExpireAt = time.Now().UTC().Add(time.Millisecond)
+					}
+					_, err := sequencer.PlaceOrder(market, order)
+					assert.NoError(t, err)
+
+					switch index % 4 {
+					case 1:
+						sequencer.CancelOrder(market, id)
+					case 2:
+						amount := decimal("0.5")
+						sequencer.AmendOrder(market, id, models.OrderAmendment{Amount: &amount})
+					}
+				}
+			}()
+		}
+		writers.Wait()
+		cancel()
+		readers.Wait()
+
+		// a last command settles the book and publishes its depth
+		err := sequencer.Execute(func() {
+			bid, ask := market.OrderBook.BuyLevels.Best(), market.OrderBook.SellLevels.Best()
+			if bid != nil && ask != nil {
+				assert.Less(t, bid.Price, ask.Price)
+			}
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 800, len(market.OrderHistory.GetOrderList(1, 1000)))
+		for _, order := range market.OrderHistory.GetOrderList(1, 1000) {
+			assert.LessOrEqual(t, order.FilledAmount, order.Amount)
+		}
+	})
+}
@@ -0,0 +1,408 @@
+package services
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"order-matching/models"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrSnapshotInvalid = errors.New("snapshot is invalid")
+
+// snapshotMagic and snapshotVersion open every snapshot file. The version is
+// raised whenever the encoded state changes shape; older versions are refused,
+// since gob would quietly leave the fields they lack empty. The versions so
+// far:
+//
+//	1 the order books, trades and instruments
+//	2 the event sequence of every order book
+//	3 the sequence of the last execution report of every account
+//	4 the balances and holds of the accounts
+//	5 the trading state of every market and the orders queued while halted
+//	6 instruments without a price and quantity scale
+//	7 the trades in the circuit breaker window of every market
+var snapshotMagic = [6]byte{'O', 'M', 'S', 'N', 'A', 'P'}
+
+const snapshotVersion uint16 = 7
+
+// snapshotHeaderSize covers the magic, the version, the journal sequence the
+// snapshot covers, and the length and CRC-32C checksum of the encoded state.
+const snapshotHeaderSize = 6 + 2 + 8 + 4 + 4
+
+// snapshotsKept is how many snapshots are kept on disk. The journal is only
+// compacted up to the oldest of them, so a damaged latest snapshot can still
+// be recovered from the one before it.
+const snapshotsKept = 2
+
+// Snapshot is the full state of every market after the journal entry with
+// the given sequence number was applied.
+type Snapshot struct {
+	Sequence uint64
+	Markets []MarketSnapshot
+	Executions map[string]uint64 // sequence of the last execution report of every account
+	Accounts AccountsSnapshot
+}
+
+type MarketSnapshot struct {
+	Instrument models.Instrument
+	OrderBook OrderBookSnapshot
+	Trades []models.Trade
+	State models.MarketState
+	Window []models.Trade // trades the circuit breaker compares the next ones with
+}
+
+// OrderBookSnapshot is the state of an order book. Orders are stored once, in
+// the order they were accepted; the price levels and the trigger book refer
+// to them by ID.
+type OrderBookSnapshot struct {
+	Orders []models.Order
+	Bids [][]string // the queue of every bid level, best price first
+	Asks [][]string // the queue of every ask level, best price first
+	Stops []string // pending stop orders
+	Halted bool
+	Queued []string // orders waiting for matching to restart, oldest first
+	Sequence uint64
+	LastTradeID uint64
+	LastTradePrice models.Decimal
+	EventSequence uint64
+	SessionClose time.Duration
+	TickSize models.Decimal
+}
+
+// Snapshot captures the state of the order book.
+func (ob *OrderBook) Snapshot() OrderBookSnapshot {
+	snapshot := OrderBookSnapshot{
+		Orders: make([]models.Order, 0, len(ob.acceptedOrders)),
+		Stops: make([]string, 0, len(ob.stopIndex)),
+		Halted: ob.halted,
+		Queued: make([]string, 0, len(ob.queued)),
+		Sequence: ob.sequence,
+		LastTradeID: ob.lastTradeID,
+		LastTradePrice: ob.lastTradePrice,
+		EventSequence: ob.eventSequence,
+		SessionClose: ob.SessionClose,
+		TickSize: ob.TickSize,
+	}
+
+	for _, order := range ob.acceptedOrders {
+		snapshot.Orders = append(snapshot.Orders, *order)
+		if _, pending := ob.stopIndex[order.ID]; pending {
+			snapshot.Stops = append(snapshot.Stops, order.ID)
+		}
+	}
+	for _, order := range ob.queued {
+		snapshot.Queued = append(snapshot.Queued, order.ID)
+	}
+	snapshot.Bids = levelQueues(ob.BuyLevels)
+	snapshot.Asks = levelQueues(ob.SellLevels)
+
+	return snapshot
+}
+
+func levelQueues(levels *models.PriceLevels) [][]string {
+	queues := make([][]string, 0, levels.Len())
+	for level := range levels.All() {
+		queue := make([]string, 0, level.Len())
+		for _, order := range level.Orders() {
+			queue = append(queue, order.ID)
+		}
+		queues = append(queues, queue)
+	}
+
+	return queues
+}
+
+// RestoreOrderBook rebuilds an order book from a snapshot.
+func RestoreOrderBook(snapshot OrderBookSnapshot) (*OrderBook, error) {
+	ob := NewOrderBook()
+	ob.sequence = snapshot.Sequence
+	ob.lastTradeID = snapshot.LastTradeID
+	ob.lastTradePrice = snapshot.LastTradePrice
+	ob.eventSequence = snapshot.EventSequence
+	ob.SessionClose = snapshot.SessionClose
+	ob.TickSize = snapshot.TickSize
+	ob.halted = snapshot.Halted
+
+	for _, order := range snapshot.Orders {
+		order := order
+		ob.orders[order.ID] = &order
+		ob.acceptedOrders = append(ob.acceptedOrders, &order)
+	}
+
+	for _, queues := range [][][]string{snapshot.Bids, snapshot.Asks} {
+		for _, queue := range queues {
+			for _, id := range queue {
+				order, exists := ob.orders[id]
+				if !exists {
+					return nil, fmt.Errorf("%w: resting order %s is unknown", ErrSnapshotInvalid, id)
+				}
+				// restOrder would show a fresh iceberg peak; the snapshot keeps the current one
+				ob.levelsFor(order.Action).GetOrCreate(order.Price).Push(order)
+				ob.orderIndex[id] = order
+			}
+		}
+	}
+
+	for _, id := range snapshot.Stops {
+		order, exists := ob.orders[id]
+		if !exists {
+			return nil, fmt.Errorf("%w: stop order %s is unknown", ErrSnapshotInvalid, id)
+		}
+		ob.addStop(order)
+	}
+
+	for _, id := range snapshot.Queued {
+		order, exists := ob.orders[id]
+		if !exists {
+			return nil, fmt.Errorf("%w: queued order %s is unknown", ErrSnapshotInvalid, id)
+		}
+		ob.queued = append(ob.queued, order)
+		ob.queueIndex[id] = order
+	}
+
+	for _, order := range ob.acceptedOrders {
+		if order.IsOpen() && !order.ExpireAt.IsZero() {
+			heap.Push(&ob.expiryHeap, order)
+		}
+	}
+
+	return ob, nil
+}
+
+// Snapshot captures the state of every market, together with the sequence
+// number of the last journal entry applied to it. Once the sequencer runs, only
+// a command it executes may take a snapshot, so that no command is between
+// being journaled and being applied.
+func (bm *BookManager) Snapshot() *Snapshot {
+	bm.mutex.RLock()
+	defer bm.mutex.RUnlock()
+
+	snapshot := &Snapshot{Markets: make([]MarketSnapshot, 0, len(bm.symbols)), Executions: bm.executions.sequences(), Accounts: bm.accounts.Snapshot()}
+	if bm.journal != nil {
+		snapshot.Sequence = bm.journal.Sequence()
+	}
+
+	for _, symbol := range bm.symbols {
+		market := bm.markets[symbol]
+		snapshot.Markets = append(snapshot.Markets, MarketSnapshot{
+			Instrument: market.Instrument,
+			OrderBook: market.OrderBook.Snapshot(),
+			Trades: market.TradeHistory.all(),
+			State: market.State(),
+			Window: slices.Clone(market.window),
+		})
+	}
+
+	return snapshot
+}
+
+// Restore loads a snapshot into a manager that has no markets yet. Recover
+// then skips the journal entries the snapshot covers.
+func (bm *BookManager) Restore(snapshot *Snapshot) error {
+	bm.mutex.Lock()
+	defer bm.mutex.Unlock()
+
+	if len(bm.markets) > 0 {
+		return errors.New("snapshots can only be restored into an empty book manager")
+	}
+
+	for _, marketSnapshot := range snapshot.Markets {
+		orderBook, err := RestoreOrderBook(marketSnapshot.OrderBook)
+		if err != nil {
+			return err
+		}
+
+		orderHistory := NewOrderHistory()
+		orderHistory.Record(marketSnapshot.OrderBook.Orders...)
+		tradeHistory := NewTradeHistory()
+		tradeHistory.Record(marketSnapshot.Trades...)
+
+		symbol := marketSnapshot.Instrument.Symbol
+		market := &Market{
+			Instrument: marketSnapshot.Instrument,
+			OrderBook: orderBook,
+			OrderHistory: orderHistory,
+			TradeHistory: tradeHistory,
+			Feed: NewMarketFeed(symbol, orderBook.depth()),
+			repository: bm.repository,
+			executions: bm.executions,
+			accounts: bm.accounts,
+			risk: bm.risk,
+			publish: bm.publish,
+			state: marketSnapshot.State,
+			window: marketSnapshot.Window,
+		}
+		bm.markets[symbol] = market
+		bm.symbols = append(bm.symbols, symbol)
+		for _, order := range marketSnapshot.OrderBook.Orders {
+			bm.orderIDs[order.ID] = struct{}{}
+		}
+		bm.risk.record(symbol, marketSnapshot.Trades, marketSnapshot.OrderBook.Orders)
+
+		// the repository may have missed writes, or be new; saving is idempotent
+		err = bm.repository.SaveInstrument(marketSnapshot.Instrument)
+		if err == nil {
+			err = bm.repository.SaveOrders(marketSnapshot.OrderBook.Orders...)
+		}
+		if err == nil {
+			err = bm.repository.SaveTrades(marketSnapshot.Trades...)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	sort.Strings(bm.symbols)
+	bm.executions.restore(snapshot.Executions)
+	bm.accounts.restore(snapshot.Accounts)
+	bm.restored = snapshot.Sequence
+
+	return nil
+}
+
+// WriteSnapshot stores a snapshot in dir. The file is written under a
+// temporary name and renamed once it is on disk, so a crash never leaves a
+// half-written snapshot behind under its final name.
+func WriteSnapshot(dir string, snapshot *Snapshot) error {
+	var body bytes.Buffer
+	if err := gob.NewEncoder(&body).Encode(snapshot); err != nil {
+		return err
+	}
+
+	header := make([]byte, snapshotHeaderSize)
+	copy(header[0:6], snapshotMagic[:])
+	binary.BigEndian.PutUint16(header[6:8], snapshotVersion)
+	binary.BigEndian.PutUint64(header[8:16], snapshot.Sequence)
+	binary.BigEndian.PutUint32(header[16:20], uint32(body.Len()))
+	binary.BigEndian.PutUint32(header[20:24], crc32.Checksum(body.Bytes(), journalChecksum))
+
+	path := snapshotPath(dir, snapshot.Sequence)
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(append(header, body.Bytes()...))
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+	if err != nil {
+		os.Remove(path + ".tmp")
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+// LoadSnapshot reads the latest valid snapshot in dir. Snapshots that cannot
+// be read are skipped in favour of older ones; nil is returned when there is
+// none at all.
+func LoadSnapshot(dir string) (*Snapshot, error) {
+	sequences, err := snapshotSequences(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for index := len(sequences) - 1; index >= 0; index-- {
+		snapshot, err := readSnapshot(snapshotPath(dir, sequences[index]))
+		if err != nil {
+			fmt.Println(err.Error())
+			continue
+		}
+
+		return snapshot, nil
+	}
+
+	return nil, nil
+}
+
+func readSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < snapshotHeaderSize || !bytes.Equal(data[0:6], snapshotMagic[:]) {
+		return nil, fmt.Errorf("%w: %s is not a snapshot", ErrSnapshotInvalid, path)
+	}
+	if version := binary.BigEndian.Uint16(data[6:8]); version != snapshotVersion {
+		return nil, fmt.Errorf("%w: %s has unsupported version %d", ErrSnapshotInvalid, path, version)
+	}
+
+	body := data[snapshotHeaderSize:]
+	if int(binary.BigEndian.Uint32(data[16:20])) != len(body) || crc32.Checksum(body, journalChecksum) != binary.BigEndian.Uint32(data[20:24]) {
+		return nil, fmt.Errorf("%w: %s fails its checksum", ErrSnapshotInvalid, path)
+	}
+
+	snapshot := new(Snapshot)
+	if err := gob.NewDecoder(bytes.NewReader(body)).Decode(snapshot); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrSnapshotInvalid, path, err)
+	}
+	if snapshot.Sequence != binary.BigEndian.Uint64(data[8:16]) {
+		return nil, fmt.Errorf("%w: %s has a mismatched sequence", ErrSnapshotInvalid, path)
+	}
+
+	return snapshot, nil
+}
+
+// PruneSnapshots deletes all but the latest snapshots and returns the sequence
+// number of the oldest one kept: every journal entry up to it is covered.
+func PruneSnapshots(dir string) (uint64, error) {
+	sequences, err := snapshotSequences(dir)
+	if err != nil || len(sequences) == 0 {
+		return 0, err
+	}
+
+	for len(sequences) > snapshotsKept {
+		if err := os.Remove(snapshotPath(dir, sequences[0])); err != nil {
+			return 0, err
+		}
+		sequences = sequences[1:]
+	}
+
+	return sequences[0], syncDir(dir)
+}
+
+// snapshotSequences returns the journal sequence of every snapshot in dir, in
+// ascending order.
+func snapshotSequences(dir string) ([]uint64, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "snapshot-*.bin"))
+	if err != nil {
+		return nil, err
+	}
+
+	sequences := make([]uint64, 0, len(paths))
+	for _, path := range paths {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "snapshot-"), ".bin")
+		sequence, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		sequences = append(sequences, sequence)
+	}
+	sort.Slice(sequences, func(i, j int) bool {
+		return sequences[i] < sequences[j]
+	})
+
+	return sequences, nil
+}
+
+func snapshotPath(dir string, sequence uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("snapshot-%020d.bin", sequence))
+}
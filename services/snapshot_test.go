@@ -0,0 +1,155 @@
+package services
+
+import (
+	"encoding/binary"
+	"os"
+	"order-matching/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotter(t *testing.T) {
+	t.Parallel()
+
+	// newJournaledMarkets opens a journal in dir and lists BTC-USD in a book
+	// manager that journals to it.
+	newJournaledMarkets := func(t *testing.T, dir string) (*BookManager, *Journal) {
+		journal, entries, err := OpenJournal(dir, 0)
+		assert.Nil(t, err)
+		bm := NewBookManager(0)
+		assert.Nil(t, bm.Recover(journal, entries))
+		bm.CreateInstrument(models.Instrument{Symbol: "BTC-USD", BaseAsset: "BTC", QuoteAsset: "USD", TickSize: decimal("0.01"), LotSize: decimal("0.01")})
+
+		return bm, journal
+	}
+
+	// recoverMarkets rebuilds a book manager the way the service does on startup.
+	recoverMarkets := func(t *testing.T, dir string) (*BookManager, *Journal, *Snapshot) {
+		snapshot, err := LoadSnapshot(dir)
+		assert.Nil(t, err)
+		bm := NewBookManager(0)
+		if snapshot != nil {
+			assert.Nil(t, bm.Restore(snapshot))
+		}
+
+		journal, entries, err := OpenJournal(dir, 0)
+		assert.Nil(t, err)
+		assert.Nil(t, bm.Recover(journal, entries))
+
+		return bm, journal, snapshot
+	}
+
+	assertSameMarket := func(t *testing.T, expected *Market, actual *Market) {
+		assert.Equal(t, expected.Instrument, actual.Instrument)
+		assert.Equal(t, expected.OrderBook.Snapshot(), actual.OrderBook.Snapshot())
+		assert.Equal(t, expected.TradeHistory.all(), actual.TradeHistory.all())
+	}
+
+	t.Run("It restores the snapshot and replays only the later entries", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		bm, journal := newJournaledMarkets(t, dir)
+		market, _ := bm.Market("BTC-USD")
+
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440000", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("5.0"), DisplayAmount: decimal("2.0")})
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Action: models.Sell, Price: decimal("100.0"), Amount: decimal("1.0")})
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440002", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("1.0")})
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440003", Action: models.Buy, Price: decimal("98.0"), Amount: decimal("2.0"), TimeInForce: models.GoodTillDate, ExpireAt: time.Now().UTC().Add(time.Hour)})
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440004", Action: models.Sell, Kind: models.Stop, StopPrice: decimal("95.0"), Amount: decimal("1.0")})
+
+		snapshotter := NewSnapshotter(startSequencer(t, bm), journal, dir, 0, 0)
+		assert.Nil(t, snapshotter.Snapshot())
+
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440005", Action: models.Buy, Price: decimal("100.0"), Amount: decimal("2.0")})
+		bm.CancelOrder(market, "550e8400-e29b-41d4-a716-446655440003")
+		journal.Close()
+
+		recovered, recoveredJournal, snapshot := recoverMarkets(t, dir)
+		defer recoveredJournal.Close()
+
+		assert.Equal(t, uint64(6), snapshot.Sequence)
+		recoveredMarket, _ := recovered.Market("BTC-USD")
+		assertSameMarket(t, market, recoveredMarket)
+		assert.Equal(t, uint64(8), recoveredJournal.Sequence())
+
+		_, err := recovered.PlaceOrder(recoveredMarket, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440001", Action: models.Buy, Price: decimal("90.0"), Amount: decimal("1.0")})
+		assert.ErrorIs(t, err, ErrDuplicateOrder)
+
+		order := &models.Order{ID: "550e8400-e29b-41d4-a716-446655440006", Action: models.Buy, Price: decimal("90.0"), Amount: decimal("1.0")}
+		recovered.PlaceOrder(recoveredMarket, order)
+		assert.Equal(t, market.OrderBook.sequence+1, order.Sequence)
+	})
+
+	t.Run("It keeps two snapshots and compacts the journal behind the older one", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		bm, journal := newJournaledMarkets(t, dir)
+		market, _ := bm.Market("BTC-USD")
+		snapshotter := NewSnapshotter(startSequencer(t, bm), journal, dir, 0, 0)
+
+		ids := []string{"550e8400-e29b-41d4-a716-446655440010", "550e8400-e29b-41d4-a716-446655440011", "550e8400-e29b-41d4-a716-446655440012"}
+		for _, id := range ids {
+			bm.PlaceOrder(market, &models.Order{ID: id, Action: models.Buy, Price: decimal("90.0"), Amount: decimal("1.0")})
+			assert.Nil(t, snapshotter.Snapshot())
+		}
+		journal.Close()
+
+		sequences, _ := snapshotSequences(dir)
+		assert.Equal(t, []uint64{3, 4}, sequences)
+		starts, _ := journalSegments(dir)
+		assert.Equal(t, []uint64{4, 5}, starts)
+
+		// a damaged latest snapshot falls back to the one before it
+		data, _ := os.ReadFile(snapshotPath(dir, 4))
+		data[len(data)-1] ^= 0xff
+		os.WriteFile(snapshotPath(dir, 4), data, 0o644)
+
+		recovered, recoveredJournal, snapshot := recoverMarkets(t, dir)
+		defer recoveredJournal.Close()
+
+		assert.Equal(t, uint64(3), snapshot.Sequence)
+		recoveredMarket, _ := recovered.Market("BTC-USD")
+		assertSameMarket(t, market, recoveredMarket)
+	})
+
+	t.Run("It refuses a snapshot written in an older version", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		bm, journal := newJournaledMarkets(t, dir)
+		market, _ := bm.Market("BTC-USD")
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440010", Action: models.Buy, Price: decimal("90.0"), Amount: decimal("1.0")})
+		journal.Close()
+
+		// version 1 had no accounts, market states or event sequences; gob
+		// would decode it into a snapshot with those left empty
+		assert.Nil(t, WriteSnapshot(dir, bm.Snapshot()))
+		data, _ := os.ReadFile(snapshotPath(dir, 2))
+		binary.BigEndian.PutUint16(data[6:8], 1)
+		os.WriteFile(snapshotPath(dir, 2), data, 0o644)
+
+		_, err := readSnapshot(snapshotPath(dir, 2))
+		assert.ErrorIs(t, err, ErrSnapshotInvalid)
+
+		recovered, recoveredJournal, snapshot := recoverMarkets(t, dir)
+		defer recoveredJournal.Close()
+
+		assert.Nil(t, snapshot, "the journal is replayed from its start instead")
+		recoveredMarket, _ := recovered.Market("BTC-USD")
+		assertSameMarket(t, market, recoveredMarket)
+	})
+
+	t.Run("It is due after the given number of commands", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		bm, journal := newJournaledMarkets(t, dir)
+		defer journal.Close()
+		market, _ := bm.Market("BTC-USD")
+		snapshotter := NewSnapshotter(startSequencer(t, bm), journal, dir, 0, 2)
+
+		assert.False(t, snapshotter.due(time.Now()))
+		bm.PlaceOrder(market, &models.Order{ID: "550e8400-e29b-41d4-a716-446655440020", Action: models.Buy, Price: decimal("90.0"), Amount: decimal("1.0")})
+		assert.True(t, snapshotter.due(time.Now()))
+	})
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// snapshotCheckInterval is how often the snapshotter checks whether a
+// snapshot is due.
+const snapshotCheckInterval = time.Second
+
+// Snapshotter periodically writes a snapshot of every market next to the
+// journal and compacts the journal behind it. A snapshot is due once the
+// interval has passed or the given number of commands has been journaled
+// since the last one, whichever comes first; a zero interval or count turns
+// that trigger off.
+type Snapshotter struct {
+	sequencer *Sequencer // captures the markets between two commands
+	markets *BookManager
+	journal *Journal
+	dir string
+	interval time.Duration
+	every uint64
+
+	lastSequence uint64 // journal sequence covered by the last snapshot
+	lastTime time.Time
+}
+
+func NewSnapshotter(sequencer *Sequencer, journal *Journal, dir string, interval time.Duration, every uint64) *Snapshotter {
+	markets := sequencer.Markets()
+	return &Snapshotter{
+		sequencer: sequencer,
+		markets: markets,
+		journal: journal,
+		dir: dir,
+		interval: interval,
+		every: every,
+		lastSequence: markets.restored,
+		lastTime: time.Now(),
+	}
+}
+
+// Run writes snapshots whenever one is due until ctx is done.
+func (s *Snapshotter) Run(ctx context.Context) {
+	if s.interval == 0 && s.every == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(min(snapshotCheckInterval, max(s.interval, time.Millisecond)))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if !s.due(now) {
+				continue
+			}
+			if err := s.Snapshot(); err != nil {
+				fmt.Println(err.Error())
+			}
+		}
+	}
+}
+
+func (s *Snapshotter) due(now time.Time) bool {
+	commands := s.journal.Sequence() - s.lastSequence
+	if commands == 0 {
+		return false
+	}
+
+	return (s.every > 0 && commands >= s.every) || (s.interval > 0 && now.Sub(s.lastTime) >= s.interval)
+}
+
+// Snapshot captures every market, writes the snapshot to disk and deletes the
+// journal segments and older snapshots it makes redundant. Only capturing the
+// state holds up the sequencer; encoding and writing happen after it has moved
+// on to the next command.
+func (s *Snapshotter) Snapshot() error {
+	var snapshot *Snapshot
+	var err error
+	if executeErr := s.sequencer.Execute(func() {
+		snapshot = s.markets.Snapshot()
+		err = s.journal.Rotate()
+	}); executeErr != nil {
+		return executeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := WriteSnapshot(s.dir, snapshot); err != nil {
+		return err
+	}
+	s.lastSequence = snapshot.Sequence
+	s.lastTime = time.Now()
+
+	covered, err := PruneSnapshots(s.dir)
+	if err != nil {
+		return err
+	}
+
+	return s.journal.Compact(covered)
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"order-matching/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TradeHistory keeps every trade produced by the order book in the order they
+// happened. It is safe for concurrent use.
+type TradeHistory struct {
+	mutex   sync.RWMutex
+	trades  []models.Trade
+	byOrder map[string][]int // positions in trades of every trade an order took part in
+}
+
+func NewTradeHistory() *TradeHistory {
+	return &TradeHistory{
+		byOrder: make(map[string][]int),
+	}
+}
+
+// Record appends trades to the history. Trades must be recorded in the order
+// the order book produced them.
+func (th *TradeHistory) Record(trades ...models.Trade) {
+	th.mutex.Lock()
+	defer th.mutex.Unlock()
+
+	for _, trade := range trades {
+		th.byOrder[trade.MakerOrderID] = append(th.byOrder[trade.MakerOrderID], len(th.trades))
+		th.byOrder[trade.TakerOrderID] = append(th.byOrder[trade.TakerOrderID], len(th.trades))
+		th.trades = append(th.trades, trade)
+	}
+}
+
+// GetOrderTrades returns every trade the order took part in, as maker or
+// taker, oldest first.
+func (th *TradeHistory) GetOrderTrades(orderID string) []models.Trade {
+	th.mutex.RLock()
+	defer th.mutex.RUnlock()
+
+	result := make([]models.Trade, 0, len(th.byOrder[orderID]))
+	for _, position := range th.byOrder[orderID] {
+		result = append(result, th.trades[position])
+	}
+
+	return result
+}
+
+// all returns a copy of every trade in the history.
+func (th *TradeHistory) all() []models.Trade {
+	th.mutex.RLock()
+	defer th.mutex.RUnlock()
+
+	return append([]models.Trade{}, th.trades...)
+}
+
+// GetTradeList returns a page of the trades executed in [from, to), oldest
+// first. A zero from or to leaves that side of the range open.
+func (th *TradeHistory) GetTradeList(from time.Time, to time.Time, page int, pageSize int) []models.Trade {
+	th.mutex.RLock()
+	defer th.mutex.RUnlock()
+
+	// trades are appended in time order, so the range can be found by binary search
+	startIndex := 0
+	if !from.IsZero() {
+		startIndex = sort.Search(len(th.trades), func(i int) bool {
+			return !th.trades[i].Timestamp.Before(from)
+		})
+	}
+
+	endIndex := len(th.trades)
+	if !to.IsZero() {
+		endIndex = sort.Search(len(th.trades), func(i int) bool {
+			return !th.trades[i].Timestamp.Before(to)
+		})
+	}
+
+	start := startIndex + (page-1)*pageSize
+	if start >= endIndex {
+		return []models.Trade{}
+	}
+
+	end := start + pageSize
+	if end > endIndex {
+		end = endIndex
+	}
+
+	result := make([]models.Trade, end-start)
+	copy(result, th.trades[start:end])
+
+	return result
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"order-matching/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTradeList(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	th := NewTradeHistory()
+	for i := 1; i <= 5; i++ {
+		th.Record(models.Trade{
+			ID:        uint64(i),
+			Price:     decimal("100.0"),
+			Amount:    decimal("1.0"),
+			Timestamp: start.Add(time.Duration(i) * time.Minute),
+		})
+	}
+
+	tradeIDs := func(trades []models.Trade) []uint64 {
+		ids := []uint64{}
+		for _, trade := range trades {
+			ids = append(ids, trade.ID)
+		}
+		return ids
+	}
+
+	t.Run("It pages through all trades oldest first", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, []uint64{1, 2}, tradeIDs(th.GetTradeList(time.Time{}, time.Time{}, 1, 2)))
+		assert.Equal(t, []uint64{5}, tradeIDs(th.GetTradeList(time.Time{}, time.Time{}, 3, 2)))
+		assert.Equal(t, []uint64{}, tradeIDs(th.GetTradeList(time.Time{}, time.Time{}, 4, 2)))
+	})
+
+	t.Run("It restricts trades to the time range", func(t *testing.T) {
+		t.Parallel()
+		from := start.Add(2 * time.Minute)
+		to := start.Add(4 * time.Minute)
+
+		assert.Equal(t, []uint64{2, 3}, tradeIDs(th.GetTradeList(from, to, 1, 10)))
+		assert.Equal(t, []uint64{3}, tradeIDs(th.GetTradeList(from, to, 2, 1)))
+		assert.Equal(t, []uint64{2, 3, 4, 5}, tradeIDs(th.GetTradeList(from, time.Time{}, 1, 10)))
+		assert.Equal(t, []uint64{}, tradeIDs(th.GetTradeList(to, from, 1, 10)))
+	})
+}
+
+func TestGetOrderTrades(t *testing.T) {
+	t.Parallel()
+	th := NewTradeHistory()
+	th.Record(
+		models.Trade{ID: 1, MakerOrderID: "maker-1", TakerOrderID: "taker-1"},
+		models.Trade{ID: 2, MakerOrderID: "maker-2", TakerOrderID: "taker-1"},
+	)
+	th.Record(models.Trade{ID: 3, MakerOrderID: "maker-2", TakerOrderID: "taker-2"})
+
+	assert.Equal(t, 2, len(th.GetOrderTrades("taker-1")))
+	assert.Equal(t, uint64(2), th.GetOrderTrades("maker-2")[0].ID)
+	assert.Equal(t, uint64(3), th.GetOrderTrades("maker-2")[1].ID)
+	assert.Equal(t, []models.Trade{}, th.GetOrderTrades("unknown"))
+}